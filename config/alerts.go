@@ -0,0 +1,115 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Alert metrics
+const (
+	AlertMetricQueueLength = "queue_length"  // Tickets waiting for the lock
+	AlertMetricWaitP95     = "wait_p95_ms"   // p95 wait time of the locks granted in the last minute
+	AlertMetricHoldP95     = "hold_p95_ms"   // p95 hold time of the locks ended in the last minute
+	AlertMetricExpiries    = "lock_expiries" // Held locks expired in the last minute
+	AlertMetricToolOffline = "tool_offline"  // 1 for each tool offline by heartbeat timeout, alerted per tool
+)
+
+// AlertMetrics lists the metrics a rule can watch
+var AlertMetrics = []string{AlertMetricQueueLength, AlertMetricWaitP95, AlertMetricHoldP95, AlertMetricExpiries, AlertMetricToolOffline}
+
+// Alert severities
+const (
+	AlertSeverityWarning  = "warning"
+	AlertSeverityCritical = "critical"
+)
+
+// alertOps lists the comparisons a rule can use
+var alertOps = []string{">", ">=", "<", "<="}
+
+// AlertRule fires an alert when Metric compared to Threshold has held for
+// For seconds, and resolves it when the comparison no longer holds
+type AlertRule struct {
+	Name      string  `yaml:"name" json:"name"`
+	Metric    string  `yaml:"metric" json:"metric"`
+	Op        string  `yaml:"op,omitempty" json:"op,omitempty"` // >, >=, <, <=; default >
+	Threshold float64 `yaml:"threshold" json:"threshold"`
+	For       int     `yaml:"for,omitempty" json:"for,omitempty"`           // Seconds the condition must hold before firing, 0 = at once
+	Severity  string  `yaml:"severity,omitempty" json:"severity,omitempty"` // warning (default) or critical
+	Match     string  `yaml:"match,omitempty" json:"match,omitempty"`       // tool_offline: glob on the tool ID, empty = all tools
+}
+
+// Compare reports whether value satisfies the rule's condition
+func (r AlertRule) Compare(value float64) bool {
+	switch r.Op {
+	case ">=":
+		return value >= r.Threshold
+	case "<":
+		return value < r.Threshold
+	case "<=":
+		return value <= r.Threshold
+	default:
+		return value > r.Threshold
+	}
+}
+
+// MatchesTool reports whether a tool_offline rule covers toolID
+func (r AlertRule) MatchesTool(toolID string) bool {
+	matched, _ := path.Match(r.Match, toolID)
+	return r.Match == "" || matched
+}
+
+// defaultAlertRules are the rules used without an alert_rules setting
+func defaultAlertRules() []AlertRule {
+	return []AlertRule{
+		{Name: "queue_backlog", Metric: AlertMetricQueueLength, Threshold: 20, For: 60},
+		{Name: "slow_wait", Metric: AlertMetricWaitP95, Threshold: 10000},
+		{Name: "lock_expiries", Metric: AlertMetricExpiries, Threshold: 5, Severity: AlertSeverityCritical},
+		{Name: "tool_offline", Metric: AlertMetricToolOffline, Threshold: 0},
+	}
+}
+
+// validateAlerts checks every alert rule and the webhook URL
+func (c *Config) validateAlerts() error {
+	names := make(map[string]bool, len(c.AlertRules))
+	for i, rule := range c.AlertRules {
+		if rule.Name == "" {
+			return fmt.Errorf("alert_rules[%d]: name is required", i)
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("alert_rules[%d]: duplicate name %q", i, rule.Name)
+		}
+		names[rule.Name] = true
+
+		if !slices.Contains(AlertMetrics, rule.Metric) {
+			return fmt.Errorf("alert_rules[%d]: metric must be one of: %s (got: %s)", i, strings.Join(AlertMetrics, ", "), rule.Metric)
+		}
+		if rule.Op != "" && !slices.Contains(alertOps, rule.Op) {
+			return fmt.Errorf("alert_rules[%d]: op must be one of: %s (got: %s)", i, strings.Join(alertOps, ", "), rule.Op)
+		}
+		if rule.For < 0 {
+			return fmt.Errorf("alert_rules[%d]: for must be non-negative", i)
+		}
+		if rule.Severity != "" && rule.Severity != AlertSeverityWarning && rule.Severity != AlertSeverityCritical {
+			return fmt.Errorf("alert_rules[%d]: severity must be one of: %s, %s (got: %s)", i, AlertSeverityWarning, AlertSeverityCritical, rule.Severity)
+		}
+		if rule.Match != "" {
+			if rule.Metric != AlertMetricToolOffline {
+				return fmt.Errorf("alert_rules[%d]: match is only supported for metric %s", i, AlertMetricToolOffline)
+			}
+			if _, err := path.Match(rule.Match, ""); err != nil {
+				return fmt.Errorf("alert_rules[%d]: invalid match pattern %q", i, rule.Match)
+			}
+		}
+	}
+
+	if c.AlertWebhookURL != "" {
+		u, err := url.Parse(c.AlertWebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("alert_webhook_url must be an http or https URL (got: %s)", c.AlertWebhookURL)
+		}
+	}
+	return nil
+}
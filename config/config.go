@@ -1,16 +1,9 @@
 package config
 
-import (
-	"os"
-	"sync"
-
-	"gopkg.in/yaml.v3"
-)
-
-// Config holds all configuration for the clipboard controller
+// Config holds all configuration for the clipboard controller.
+// A Config published through a Store is an immutable snapshot: never modify
+// it in place, use Store.Update to derive and swap in a new one.
 type Config struct {
-	mu sync.RWMutex
-
 	// Server
 	Port int `yaml:"port" json:"port"`
 
@@ -18,6 +11,12 @@ type Config struct {
 	HeartbeatTimeout  int `yaml:"heartbeat_timeout" json:"heartbeat_timeout"`
 	HeartbeatInterval int `yaml:"heartbeat_interval" json:"heartbeat_interval"`
 
+	// Session-checked lock calls count as a heartbeat
+	LockRefreshesHeartbeat bool `yaml:"lock_refreshes_heartbeat" json:"lock_refreshes_heartbeat"`
+
+	// Offline tools are removed from the registry after this many seconds (0 = keep forever)
+	OfflineToolRetention int `yaml:"offline_tool_retention" json:"offline_tool_retention"`
+
 	// Polling
 	PollInterval int `yaml:"poll_interval" json:"poll_interval"`
 
@@ -31,6 +30,9 @@ type Config struct {
 	LockExtendMax   int  `yaml:"lock_extend_max" json:"lock_extend_max"`
 	LockGracePeriod int  `yaml:"lock_grace_period" json:"lock_grace_period"`
 
+	// Per-tool overrides of the lock settings above
+	Tools []ToolProfile `yaml:"tools" json:"tools"`
+
 	// Priority (for future use)
 	PriorityEnabled bool `yaml:"priority_enabled" json:"priority_enabled"`
 
@@ -44,115 +46,331 @@ type Config struct {
 	LogSummary       bool   `yaml:"log_summary" json:"log_summary"`
 	LogHeartbeats    bool   `yaml:"log_heartbeats" json:"log_heartbeats"` // Log heartbeat events (noisy, default false)
 
+	// Per-stream sampling of request and event logs
+	LogSampling []LogSampleRule `yaml:"log_sampling" json:"log_sampling"`
+
+	// Extra destinations for log entries (stdout, syslog, HTTP collector)
+	LogSinks []LogSinkConfig `yaml:"log_sinks" json:"log_sinks"`
+
+	// Log writer queue
+	LogQueueSize      int    `yaml:"log_queue_size" json:"log_queue_size"`           // Entries buffered before the overflow policy applies
+	LogOverflowPolicy string `yaml:"log_overflow_policy" json:"log_overflow_policy"` // block or drop_oldest
+
+	// Log file size limits
+	LogMaxFileSizeMB  int  `yaml:"log_max_file_size_mb" json:"log_max_file_size_mb"`   // Start a new segment above this size, 0 = no limit
+	LogMaxTotalSizeMB int  `yaml:"log_max_total_size_mb" json:"log_max_total_size_mb"` // Delete oldest log files above this total, 0 = no limit
+	LogCompress       bool `yaml:"log_compress" json:"log_compress"`                   // Gzip closed days and segments
+
+	// Percentiles reported for wait time, hold time, queue length and extend counts
+	MetricsPercentiles []float64 `yaml:"metrics_percentiles" json:"metrics_percentiles"`
+
+	// Alerting on live metrics, evaluated by the logging background jobs
+	AlertRules      []AlertRule `yaml:"alert_rules" json:"alert_rules"`
+	AlertWebhookURL string      `yaml:"alert_webhook_url" json:"alert_webhook_url"` // POST alert state changes here, empty = off
+	AlertTrayNotify bool        `yaml:"alert_tray_notify" json:"alert_tray_notify"` // Show a tray notification on state changes
+
+	// Outgoing webhooks for lock and tool events
+	Webhooks []WebhookConfig `yaml:"webhooks" json:"webhooks"`
+
+	// Admin endpoints (/admin/*). Empty = only allowed from localhost
+	AdminToken string `yaml:"admin_token" json:"admin_token"`
+
 	// Client Retry (suggestions for client)
 	ClientRetryMax     int `yaml:"client_retry_max" json:"client_retry_max"`
 	ClientRetryDelayMs int `yaml:"client_retry_delay_ms" json:"client_retry_delay_ms"`
+
+	// Config file reload
+	ConfigWatch         bool `yaml:"config_watch" json:"config_watch"`                   // Reload config file when it changes on disk
+	ConfigWatchInterval int  `yaml:"config_watch_interval" json:"config_watch_interval"` // Seconds between file change checks
+	ConfigWriteBack     bool `yaml:"config_write_back" json:"config_write_back"`         // Persist PATCH /config changes to the file
 }
 
-// Default returns a Config with default values
-func Default() *Config {
-	return &Config{
-		Port:               8899,
-		HeartbeatTimeout:   300,
-		HeartbeatInterval:  120,
-		PollInterval:       200,
-		TicketTTL:          120,
-		TicketTTLOnPoll:    true,
-		LockMaxDuration:    20,
-		LockExtendable:     true,
-		LockExtendMax:      2,
-		LockGracePeriod:    5,
-		PriorityEnabled:    false,
-		LogDir:             "./logs",
-		LogRetentionDays:   30,
-		LogLevel:           "info",
-		ClientRetryMax:     3,
-		ClientRetryDelayMs: 1000,
-	}
+// RuntimeKeys lists config keys that can be changed without a restart
+var RuntimeKeys = []string{
+	"heartbeat_timeout",
+	"heartbeat_interval",
+	"lock_refreshes_heartbeat",
+	"offline_tool_retention",
+	"poll_interval",
+	"ticket_ttl",
+	"ticket_ttl_on_poll",
+	"lock_max_duration",
+	"lock_extendable",
+	"lock_extend_max",
+	"lock_grace_period",
+	"tools",
+	"log_requests",
+	"log_events",
+	"log_metrics",
+	"log_summary",
+	"log_heartbeats",
+	"log_sampling",
+	"metrics_percentiles",
+	"alert_rules",
+	"alert_webhook_url",
+	"alert_tray_notify",
+	"webhooks",
+	"client_retry_max",
+	"client_retry_delay_ms",
+}
+
+// SecretKeys lists the string config keys whose values are masked when
+// displayed
+var SecretKeys = map[string]bool{
+	"admin_token":       true,
+	"alert_webhook_url": true,
 }
 
-// Load loads config from a YAML file, applying defaults for missing values
-func Load(path string) (*Config, error) {
-	cfg := Default()
+// secretMask replaces a secret value when displayed
+const secretMask = "********"
 
-	data, err := os.ReadFile(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			// File doesn't exist, use defaults
-			return cfg, nil
+// maskSecret returns the value to display for key: secret strings, sink
+// headers and webhook secrets are masked
+func maskSecret(key string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if SecretKeys[key] && v != "" {
+			return secretMask
 		}
-		return nil, err
+	case []LogSinkConfig:
+		return maskedSinks(v)
+	case []WebhookConfig:
+		return MaskedWebhooks(v)
 	}
+	return value
+}
 
-	if err := yaml.Unmarshal(data, cfg); err != nil {
-		return nil, err
+// Default returns a Config with default values
+func Default() *Config {
+	return &Config{
+		Port:                   8899,
+		HeartbeatTimeout:       300,
+		HeartbeatInterval:      120,
+		LockRefreshesHeartbeat: true,
+		OfflineToolRetention:   86400,
+		PollInterval:           200,
+		TicketTTL:              120,
+		TicketTTLOnPoll:        true,
+		LockMaxDuration:        20,
+		LockExtendable:         true,
+		LockExtendMax:          2,
+		LockGracePeriod:        5,
+		PriorityEnabled:        false,
+		LogDir:                 "./logs",
+		LogRetentionDays:       30,
+		LogLevel:               "info",
+		LogRequests:            true,
+		LogEvents:              true,
+		LogMetrics:             true,
+		LogSummary:             true,
+		LogQueueSize:           10000,
+		LogOverflowPolicy:      "block",
+		LogMaxFileSizeMB:       100,
+		LogCompress:            true,
+		MetricsPercentiles:     []float64{50, 90, 99},
+		AlertRules:             defaultAlertRules(),
+		AlertTrayNotify:        true,
+		ClientRetryMax:         3,
+		ClientRetryDelayMs:     1000,
+		ConfigWatch:            true,
+		ConfigWatchInterval:    2,
+		ConfigWriteBack:        false,
 	}
-
-	return cfg, nil
 }
 
-// GetClientConfig returns config values relevant to clients
-func (c *Config) GetClientConfig() map[string]interface{} {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+// GetClientConfig returns config values relevant to a client, with the
+// tool's profile overrides applied
+func (c *Config) GetClientConfig(toolID string) map[string]interface{} {
+	settings := c.ForTool(toolID)
 
-	return map[string]interface{}{
+	result := map[string]interface{}{
 		"heartbeat_interval":    c.HeartbeatInterval,
 		"heartbeat_timeout":     c.HeartbeatTimeout,
 		"poll_interval":         c.PollInterval,
-		"ticket_ttl":            c.TicketTTL,
-		"lock_max_duration":     c.LockMaxDuration,
+		"ticket_ttl":            settings.TicketTTL,
+		"lock_max_duration":     settings.LockMaxDuration,
+		"lock_extend_max":       settings.LockExtendMax,
+		"lock_grace_period":     settings.LockGracePeriod,
 		"client_retry_max":      c.ClientRetryMax,
 		"client_retry_delay_ms": c.ClientRetryDelayMs,
 	}
+	if settings.Profile != "" {
+		result["profile"] = settings.Profile
+	}
+
+	return result
 }
 
-// Update updates config values at runtime (only safe values)
-func (c *Config) Update(updates map[string]interface{}) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+// PersistValues returns the values of the runtime keys in updates as applied
+// to c, for writing back to the config file. Masked secrets sent back by a
+// client are resolved to the secret in use.
+func (c *Config) PersistValues(updates map[string]interface{}) map[string]interface{} {
+	values := c.values()
+	persist := make(map[string]interface{})
+	for key := range RuntimeSubset(updates) {
+		persist[key] = values[key]
+	}
+	return persist
+}
 
+// RuntimeSubset returns the entries of updates whose keys are in RuntimeKeys
+func RuntimeSubset(updates map[string]interface{}) map[string]interface{} {
+	subset := make(map[string]interface{})
+	for _, key := range RuntimeKeys {
+		if v, ok := updates[key]; ok {
+			subset[key] = v
+		}
+	}
+	return subset
+}
+
+// Clone returns a copy of the config that can be modified independently
+func (c *Config) Clone() *Config {
+	clone := *c
+	clone.Tools = append([]ToolProfile(nil), c.Tools...)
+	clone.LogSampling = append([]LogSampleRule(nil), c.LogSampling...)
+	clone.LogSinks = append([]LogSinkConfig(nil), c.LogSinks...)
+	clone.MetricsPercentiles = append([]float64(nil), c.MetricsPercentiles...)
+	clone.AlertRules = append([]AlertRule(nil), c.AlertRules...)
+	clone.Webhooks = append([]WebhookConfig(nil), c.Webhooks...)
+	return &clone
+}
+
+// With returns a copy of the config with the runtime-safe updates applied.
+// The receiver is not modified.
+func (c *Config) With(updates map[string]interface{}) *Config {
+	next := c.Clone()
+
+	if v, ok := updates["heartbeat_timeout"].(int); ok {
+		next.HeartbeatTimeout = v
+	}
+	if v, ok := updates["heartbeat_interval"].(int); ok {
+		next.HeartbeatInterval = v
+	}
+	if v, ok := updates["lock_refreshes_heartbeat"].(bool); ok {
+		next.LockRefreshesHeartbeat = v
+	}
+	if v, ok := updates["offline_tool_retention"].(int); ok {
+		next.OfflineToolRetention = v
+	}
 	if v, ok := updates["poll_interval"].(int); ok {
-		c.PollInterval = v
+		next.PollInterval = v
 	}
 	if v, ok := updates["ticket_ttl"].(int); ok {
-		c.TicketTTL = v
+		next.TicketTTL = v
+	}
+	if v, ok := updates["ticket_ttl_on_poll"].(bool); ok {
+		next.TicketTTLOnPoll = v
 	}
 	if v, ok := updates["lock_max_duration"].(int); ok {
-		c.LockMaxDuration = v
+		next.LockMaxDuration = v
 	}
 	if v, ok := updates["lock_grace_period"].(int); ok {
-		c.LockGracePeriod = v
+		next.LockGracePeriod = v
 	}
 	if v, ok := updates["lock_extendable"].(bool); ok {
-		c.LockExtendable = v
+		next.LockExtendable = v
 	}
 	if v, ok := updates["lock_extend_max"].(int); ok {
-		c.LockExtendMax = v
+		next.LockExtendMax = v
+	}
+	if v, ok := updates["tools"].([]ToolProfile); ok {
+		next.Tools = append([]ToolProfile(nil), v...)
+	}
+	if v, ok := updates["log_requests"].(bool); ok {
+		next.LogRequests = v
+	}
+	if v, ok := updates["log_events"].(bool); ok {
+		next.LogEvents = v
+	}
+	if v, ok := updates["log_metrics"].(bool); ok {
+		next.LogMetrics = v
+	}
+	if v, ok := updates["log_summary"].(bool); ok {
+		next.LogSummary = v
+	}
+	if v, ok := updates["log_heartbeats"].(bool); ok {
+		next.LogHeartbeats = v
 	}
+	if v, ok := updates["log_sampling"].([]LogSampleRule); ok {
+		next.LogSampling = append([]LogSampleRule(nil), v...)
+	}
+	if v, ok := updates["metrics_percentiles"].([]float64); ok {
+		next.MetricsPercentiles = append([]float64(nil), v...)
+	}
+	if v, ok := updates["alert_rules"].([]AlertRule); ok {
+		next.AlertRules = append([]AlertRule(nil), v...)
+	}
+	if v, ok := updates["alert_webhook_url"].(string); ok && v != secretMask {
+		next.AlertWebhookURL = v
+	}
+	if v, ok := updates["alert_tray_notify"].(bool); ok {
+		next.AlertTrayNotify = v
+	}
+	if v, ok := updates["webhooks"].([]WebhookConfig); ok {
+		next.Webhooks = keepWebhookSecrets(v, c.Webhooks)
+	}
+	if v, ok := updates["client_retry_max"].(int); ok {
+		next.ClientRetryMax = v
+	}
+	if v, ok := updates["client_retry_delay_ms"].(int); ok {
+		next.ClientRetryDelayMs = v
+	}
+
+	return next
 }
 
-// ToMap returns all config as a map
+// ToMap returns all config as a map, with secrets masked for display
 func (c *Config) ToMap() map[string]interface{} {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+	values := c.values()
+	for key, value := range values {
+		values[key] = maskSecret(key, value)
+	}
+	return values
+}
 
+// values returns all config as a map, secrets included
+func (c *Config) values() map[string]interface{} {
 	return map[string]interface{}{
-		"port":                  c.Port,
-		"heartbeat_timeout":     c.HeartbeatTimeout,
-		"heartbeat_interval":    c.HeartbeatInterval,
-		"poll_interval":         c.PollInterval,
-		"ticket_ttl":            c.TicketTTL,
-		"ticket_ttl_on_poll":    c.TicketTTLOnPoll,
-		"lock_max_duration":     c.LockMaxDuration,
-		"lock_extendable":       c.LockExtendable,
-		"lock_extend_max":       c.LockExtendMax,
-		"lock_grace_period":     c.LockGracePeriod,
-		"priority_enabled":      c.PriorityEnabled,
-		"log_dir":               c.LogDir,
-		"log_retention_days":    c.LogRetentionDays,
-		"log_level":             c.LogLevel,
-		"client_retry_max":      c.ClientRetryMax,
-		"client_retry_delay_ms": c.ClientRetryDelayMs,
+		"port":                     c.Port,
+		"heartbeat_timeout":        c.HeartbeatTimeout,
+		"heartbeat_interval":       c.HeartbeatInterval,
+		"lock_refreshes_heartbeat": c.LockRefreshesHeartbeat,
+		"offline_tool_retention":   c.OfflineToolRetention,
+		"poll_interval":            c.PollInterval,
+		"ticket_ttl":               c.TicketTTL,
+		"ticket_ttl_on_poll":       c.TicketTTLOnPoll,
+		"lock_max_duration":        c.LockMaxDuration,
+		"lock_extendable":          c.LockExtendable,
+		"lock_extend_max":          c.LockExtendMax,
+		"lock_grace_period":        c.LockGracePeriod,
+		"tools":                    c.Tools,
+		"priority_enabled":         c.PriorityEnabled,
+		"log_dir":                  c.LogDir,
+		"log_retention_days":       c.LogRetentionDays,
+		"log_level":                c.LogLevel,
+		"log_requests":             c.LogRequests,
+		"log_events":               c.LogEvents,
+		"log_metrics":              c.LogMetrics,
+		"log_summary":              c.LogSummary,
+		"log_heartbeats":           c.LogHeartbeats,
+		"log_sampling":             c.LogSampling,
+		"log_sinks":                c.LogSinks,
+		"log_queue_size":           c.LogQueueSize,
+		"log_overflow_policy":      c.LogOverflowPolicy,
+		"log_max_file_size_mb":     c.LogMaxFileSizeMB,
+		"log_max_total_size_mb":    c.LogMaxTotalSizeMB,
+		"log_compress":             c.LogCompress,
+		"metrics_percentiles":      c.MetricsPercentiles,
+		"alert_rules":              c.AlertRules,
+		"alert_webhook_url":        c.AlertWebhookURL,
+		"alert_tray_notify":        c.AlertTrayNotify,
+		"webhooks":                 c.Webhooks,
+		"admin_token":              c.AdminToken,
+		"client_retry_max":         c.ClientRetryMax,
+		"client_retry_delay_ms":    c.ClientRetryDelayMs,
+		"config_watch":             c.ConfigWatch,
+		"config_watch_interval":    c.ConfigWatchInterval,
+		"config_write_back":        c.ConfigWriteBack,
 	}
 }
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"fmt"
+	"path"
+)
+
+// Log streams that can be sampled
+const (
+	LogStreamRequests   = "requests"
+	LogStreamLockEvents = "lock_events"
+	LogStreamToolEvents = "tool_events"
+)
+
+// LogSampleRule keeps only a fraction of the entries of a log stream.
+// Match is a glob on the request path (requests) or the event type
+// (lock_events, tool_events); empty matches every entry of the stream.
+type LogSampleRule struct {
+	Stream string  `yaml:"stream" json:"stream"`
+	Match  string  `yaml:"match,omitempty" json:"match,omitempty"`
+	Rate   float64 `yaml:"rate" json:"rate"` // Fraction kept, 0 (none) to 1 (all)
+}
+
+// LogSampleRate returns the fraction of entries of stream identified by key
+// (request path or event type) to write. The first matching rule in config
+// order wins; without one every entry is written.
+func (c *Config) LogSampleRate(stream, key string) float64 {
+	for _, rule := range c.LogSampling {
+		if rule.Stream != stream {
+			continue
+		}
+		if matched, _ := path.Match(rule.Match, key); rule.Match == "" || matched {
+			return rule.Rate
+		}
+	}
+	return 1
+}
+
+// validateLogSampling checks every sampling rule
+func (c *Config) validateLogSampling() error {
+	for i, rule := range c.LogSampling {
+		switch rule.Stream {
+		case LogStreamRequests, LogStreamLockEvents, LogStreamToolEvents:
+		default:
+			return fmt.Errorf("log_sampling[%d]: stream must be one of: %s, %s, %s (got: %s)",
+				i, LogStreamRequests, LogStreamLockEvents, LogStreamToolEvents, rule.Stream)
+		}
+		if _, err := path.Match(rule.Match, ""); err != nil {
+			return fmt.Errorf("log_sampling[%d]: invalid match pattern %q", i, rule.Match)
+		}
+		if rule.Rate < 0 || rule.Rate > 1 {
+			return fmt.Errorf("log_sampling[%d]: rate must be between 0 and 1 (got: %g)", i, rule.Rate)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Log sink types
+const (
+	LogSinkStdout = "stdout"
+	LogSinkSyslog = "syslog"
+	LogSinkHTTP   = "http"
+)
+
+// Streams that are not sampled: per-minute metrics and the audit log
+const (
+	LogStreamMetrics = "metrics"
+	LogStreamAudit   = "audit"
+)
+
+// LogStreams lists the streams a sink can receive
+var LogStreams = []string{LogStreamRequests, LogStreamLockEvents, LogStreamToolEvents, LogStreamMetrics, LogStreamAudit}
+
+// LogSinkConfig sends log entries to a destination besides the local JSONL
+// files. Remote sinks (syslog, http) deliver in batches, retry, and spool to
+// <log_dir>/spool/<name>.jsonl while the destination is unavailable.
+type LogSinkConfig struct {
+	Name            string            `yaml:"name" json:"name"`
+	Type            string            `yaml:"type" json:"type"`                                               // stdout, syslog or http
+	Streams         []string          `yaml:"streams,omitempty" json:"streams,omitempty"`                     // Empty = all streams
+	Address         string            `yaml:"address,omitempty" json:"address,omitempty"`                     // syslog: udp://host:514, tcp://host:514 or unix:///dev/log
+	URL             string            `yaml:"url,omitempty" json:"url,omitempty"`                             // http: collector endpoint
+	Headers         map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`                     // http: extra request headers
+	BatchSize       int               `yaml:"batch_size,omitempty" json:"batch_size,omitempty"`               // Entries per delivery, default 100
+	FlushIntervalMs int               `yaml:"flush_interval_ms,omitempty" json:"flush_interval_ms,omitempty"` // Max delay before a partial batch is sent, default 1000
+	SpoolMaxMB      int               `yaml:"spool_max_mb,omitempty" json:"spool_max_mb,omitempty"`           // Spool file limit, default 100
+}
+
+// WantsStream reports whether the sink receives stream
+func (s LogSinkConfig) WantsStream(stream string) bool {
+	return len(s.Streams) == 0 || slices.Contains(s.Streams, stream)
+}
+
+// withDefaults fills unset batching and spool limits
+func (s LogSinkConfig) withDefaults() LogSinkConfig {
+	if s.BatchSize == 0 {
+		s.BatchSize = 100
+	}
+	if s.FlushIntervalMs == 0 {
+		s.FlushIntervalMs = 1000
+	}
+	if s.SpoolMaxMB == 0 {
+		s.SpoolMaxMB = 100
+	}
+	return s
+}
+
+// Sinks returns the configured sinks with defaults applied
+func (c *Config) Sinks() []LogSinkConfig {
+	sinks := make([]LogSinkConfig, len(c.LogSinks))
+	for i, sink := range c.LogSinks {
+		sinks[i] = sink.withDefaults()
+	}
+	return sinks
+}
+
+// maskedSinks returns the sinks with header values masked for display
+func maskedSinks(sinks []LogSinkConfig) []LogSinkConfig {
+	if sinks == nil {
+		return nil
+	}
+	masked := make([]LogSinkConfig, len(sinks))
+	for i, sink := range sinks {
+		masked[i] = sink
+		if len(sink.Headers) > 0 {
+			masked[i].Headers = make(map[string]string, len(sink.Headers))
+			for name := range sink.Headers {
+				masked[i].Headers[name] = secretMask
+			}
+		}
+	}
+	return masked
+}
+
+// validateLogSinks checks every sink
+func (c *Config) validateLogSinks() error {
+	names := make(map[string]bool, len(c.LogSinks))
+	for i, sink := range c.LogSinks {
+		if sink.Name == "" {
+			return fmt.Errorf("log_sinks[%d]: name is required", i)
+		}
+		if names[sink.Name] {
+			return fmt.Errorf("log_sinks[%d]: duplicate name %q", i, sink.Name)
+		}
+		names[sink.Name] = true
+
+		for _, stream := range sink.Streams {
+			if !slices.Contains(LogStreams, stream) {
+				return fmt.Errorf("log_sinks[%d]: stream must be one of: %s (got: %s)", i, strings.Join(LogStreams, ", "), stream)
+			}
+		}
+		if sink.BatchSize < 0 || sink.FlushIntervalMs < 0 || sink.SpoolMaxMB < 0 {
+			return fmt.Errorf("log_sinks[%d]: batch_size, flush_interval_ms and spool_max_mb must be non-negative", i)
+		}
+
+		switch sink.Type {
+		case LogSinkStdout:
+		case LogSinkSyslog:
+			u, err := url.Parse(sink.Address)
+			if err != nil || (u.Scheme != "udp" && u.Scheme != "tcp" && u.Scheme != "unix") {
+				return fmt.Errorf("log_sinks[%d]: address must be udp://host:port, tcp://host:port or unix:///path (got: %s)", i, sink.Address)
+			}
+		case LogSinkHTTP:
+			u, err := url.Parse(sink.URL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("log_sinks[%d]: url must be an http or https URL (got: %s)", i, sink.URL)
+			}
+		default:
+			return fmt.Errorf("log_sinks[%d]: type must be one of: %s, %s, %s (got: %s)", i, LogSinkStdout, LogSinkSyslog, LogSinkHTTP, sink.Type)
+		}
+	}
+	return nil
+}
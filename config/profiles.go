@@ -0,0 +1,124 @@
+package config
+
+import (
+	"fmt"
+	"path"
+)
+
+// ToolProfile overrides lock settings for the tools it matches.
+// Match is either an exact tool_id or a glob pattern (e.g. "bas_*").
+// Nil fields fall back to the global value.
+type ToolProfile struct {
+	Match           string `yaml:"match" json:"match"`
+	LockMaxDuration *int   `yaml:"lock_max_duration,omitempty" json:"lock_max_duration,omitempty"`
+	LockExtendMax   *int   `yaml:"lock_extend_max,omitempty" json:"lock_extend_max,omitempty"`
+	TicketTTL       *int   `yaml:"ticket_ttl,omitempty" json:"ticket_ttl,omitempty"`
+	LockGracePeriod *int   `yaml:"lock_grace_period,omitempty" json:"lock_grace_period,omitempty"`
+}
+
+// ToolSettings holds the effective per-tool lock settings
+type ToolSettings struct {
+	Profile         string `json:"profile,omitempty"` // Match of the applied profile, empty if none
+	LockMaxDuration int    `json:"lock_max_duration"`
+	LockExtendMax   int    `json:"lock_extend_max"`
+	TicketTTL       int    `json:"ticket_ttl"`
+	LockGracePeriod int    `json:"lock_grace_period"`
+}
+
+// ForTool returns the effective lock settings for a tool.
+// An exact tool_id match wins over glob patterns; among patterns the first
+// matching profile in config order is used.
+func (c *Config) ForTool(toolID string) ToolSettings {
+	settings := ToolSettings{
+		LockMaxDuration: c.LockMaxDuration,
+		LockExtendMax:   c.LockExtendMax,
+		TicketTTL:       c.TicketTTL,
+		LockGracePeriod: c.LockGracePeriod,
+	}
+
+	profile := c.matchProfile(toolID)
+	if profile == nil {
+		return settings
+	}
+
+	settings.Profile = profile.Match
+	if profile.LockMaxDuration != nil {
+		settings.LockMaxDuration = *profile.LockMaxDuration
+	}
+	if profile.LockExtendMax != nil {
+		settings.LockExtendMax = *profile.LockExtendMax
+	}
+	if profile.TicketTTL != nil {
+		settings.TicketTTL = *profile.TicketTTL
+	}
+	if profile.LockGracePeriod != nil {
+		settings.LockGracePeriod = *profile.LockGracePeriod
+	}
+
+	return settings
+}
+
+func (c *Config) matchProfile(toolID string) *ToolProfile {
+	if toolID == "" {
+		return nil
+	}
+
+	for i := range c.Tools {
+		if c.Tools[i].Match == toolID {
+			return &c.Tools[i]
+		}
+	}
+
+	for i := range c.Tools {
+		if matched, _ := path.Match(c.Tools[i].Match, toolID); matched {
+			return &c.Tools[i]
+		}
+	}
+
+	return nil
+}
+
+// validateProfiles checks every tool profile against the same rules as the global settings
+func (c *Config) validateProfiles() error {
+	for i, profile := range c.Tools {
+		if profile.Match == "" {
+			return fmt.Errorf("tools[%d]: match is required", i)
+		}
+		if _, err := path.Match(profile.Match, ""); err != nil {
+			return fmt.Errorf("tools[%d]: invalid match pattern %q", i, profile.Match)
+		}
+
+		positive := map[string]*int{
+			"lock_max_duration": profile.LockMaxDuration,
+			"ticket_ttl":        profile.TicketTTL,
+			"lock_grace_period": profile.LockGracePeriod,
+		}
+		for name, v := range positive {
+			if v != nil && *v <= 0 {
+				return fmt.Errorf("tools[%d] (%s): %s must be positive", i, profile.Match, name)
+			}
+		}
+		if profile.LockExtendMax != nil && *profile.LockExtendMax < 0 {
+			return fmt.Errorf("tools[%d] (%s): lock_extend_max must be non-negative", i, profile.Match)
+		}
+
+		// Validate the profile's own values layered on the globals
+		settings := (&Config{
+			LockMaxDuration: c.LockMaxDuration,
+			LockExtendMax:   c.LockExtendMax,
+			TicketTTL:       c.TicketTTL,
+			LockGracePeriod: c.LockGracePeriod,
+			Tools:           []ToolProfile{profile},
+		}).ForTool(profile.Match)
+		if settings.LockGracePeriod >= settings.LockMaxDuration {
+			return fmt.Errorf("tools[%d] (%s): lock_grace_period (%ds) must be less than lock_max_duration (%ds)",
+				i, profile.Match, settings.LockGracePeriod, settings.LockMaxDuration)
+		}
+		if c.PollInterval >= settings.TicketTTL*1000 {
+			return fmt.Errorf("tools[%d] (%s): poll_interval (%dms) must be less than ticket_ttl (%ds = %dms)",
+				i, profile.Match, c.PollInterval, settings.TicketTTL, settings.TicketTTL*1000)
+		}
+	}
+
+	return nil
+}
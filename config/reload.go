@@ -0,0 +1,324 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+// Change describes a single config value that differs between two configs
+type Change struct {
+	Key     string      `json:"key"`
+	Old     interface{} `json:"old"`
+	New     interface{} `json:"new"`
+	Applied bool        `json:"applied"` // false if the key needs a restart to take effect
+}
+
+// Reloader keeps the live config in sync with its YAML file
+type Reloader struct {
+	path       string
+	store      *Store
+	flagValues map[string]string // Command line values, re-applied on every load
+
+	mu       sync.Mutex
+	lastMod  time.Time
+	lastSize int64
+
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+
+	onWatchReload func([]Change)
+}
+
+// NewReloader creates a reloader for the config file at path.
+// flagValues are layered on top of every reload (see Load) so that
+// environment variables and command line flags keep precedence over the file.
+func NewReloader(path string, store *Store, flagValues map[string]string) *Reloader {
+	r := &Reloader{
+		path:       path,
+		store:      store,
+		flagValues: flagValues,
+		stopChan:   make(chan struct{}),
+	}
+	r.lastMod, r.lastSize = r.stat()
+	return r
+}
+
+// Path returns the config file path
+func (r *Reloader) Path() string {
+	return r.path
+}
+
+// OnWatchReload sets a function called with the changes of every successful
+// reload started by the file watcher. It must be called before Start.
+func (r *Reloader) OnWatchReload(fn func([]Change)) {
+	r.onWatchReload = fn
+}
+
+// Start starts watching the config file for changes
+func (r *Reloader) Start() {
+	cfg := r.store.Get()
+	interval := time.Duration(cfg.ConfigWatchInterval) * time.Second
+
+	if !cfg.ConfigWatch {
+		log.Info().Str("path", r.path).Msg("Config file watching disabled")
+		return
+	}
+
+	r.wg.Add(1)
+	go r.watchLoop(interval)
+
+	log.Info().
+		Str("path", r.path).
+		Dur("interval", interval).
+		Msg("Watching config file for changes")
+}
+
+// Stop stops watching the config file
+func (r *Reloader) Stop() {
+	close(r.stopChan)
+	r.wg.Wait()
+}
+
+// watchLoop polls the config file for modifications
+func (r *Reloader) watchLoop(interval time.Duration) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stopChan:
+			return
+		case <-ticker.C:
+			if !r.fileChanged() {
+				continue
+			}
+			log.Info().Str("path", r.path).Msg("Config file changed, reloading")
+			changes, err := r.Reload()
+			if err != nil {
+				log.Error().Err(err).Str("path", r.path).Msg("Config reload failed, keeping current config")
+				continue
+			}
+			if r.onWatchReload != nil {
+				r.onWatchReload(changes)
+			}
+		}
+	}
+}
+
+// fileChanged reports whether the file's mod time or size changed since the last check
+func (r *Reloader) fileChanged() bool {
+	modTime, size := r.stat()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if modTime.Equal(r.lastMod) && size == r.lastSize {
+		return false
+	}
+	r.lastMod, r.lastSize = modTime, size
+	return true
+}
+
+func (r *Reloader) stat() (time.Time, int64) {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return time.Time{}, 0
+	}
+	return info.ModTime(), info.Size()
+}
+
+// Reload re-reads the config file, validates it and applies the runtime-safe
+// subset to the live config. Keys that need a restart are reported with
+// Applied=false. The live config is left untouched if the file is invalid.
+func (r *Reloader) Reload() ([]Change, error) {
+	newCfg, newSources, err := Load(r.path, r.flagValues)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := newCfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	// Diff and apply the real values; ToMap masks secrets, so a changed
+	// secret would look unchanged and a masked one would be applied
+	changes, err := r.store.reload(newCfg, newSources)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	maskChanges(changes)
+	logChanges(changes)
+
+	return changes, nil
+}
+
+// Persist writes the given values back to the config file, keeping the
+// existing layout and comments of the file where possible
+func (r *Reloader) Persist(values map[string]interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var doc yaml.Node
+	data, err := os.ReadFile(r.path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	if len(data) > 0 {
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("failed to parse config file: %w", err)
+		}
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("config file root is not a mapping")
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		var valueNode yaml.Node
+		if err := valueNode.Encode(values[key]); err != nil {
+			return fmt.Errorf("failed to encode %s: %w", key, err)
+		}
+		setMappingValue(root, key, &valueNode)
+	}
+
+	out, err := yaml.Marshal(&doc)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	// Write atomically so the watcher never sees a half-written file. The
+	// target of a symlinked config is replaced, with its mode kept.
+	path, err := filepath.EvalSymlinks(r.path)
+	if os.IsNotExist(err) {
+		path = r.path
+	} else if err != nil {
+		return fmt.Errorf("failed to resolve config file: %w", err)
+	}
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".config-*.yaml")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to set temp file mode: %w", err)
+	}
+	if _, err := tmp.Write(out); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to replace config file: %w", err)
+	}
+
+	// Our own write should not trigger a reload
+	r.lastMod, r.lastSize = r.stat()
+
+	log.Info().
+		Str("path", r.path).
+		Strs("keys", keys).
+		Msg("Config changes written to file")
+
+	return nil
+}
+
+// setMappingValue sets key to value in a YAML mapping node, appending the key if missing
+func setMappingValue(mapping *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			// Keep the trailing comment of the original value
+			value.LineComment = mapping.Content[i+1].LineComment
+			mapping.Content[i+1] = value
+			return
+		}
+	}
+	mapping.Content = append(mapping.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+		value,
+	)
+}
+
+// Diff returns the keys whose values differ between two config maps, sorted by key
+func Diff(oldValues, newValues map[string]interface{}) []Change {
+	changes := make([]Change, 0)
+	for key, newValue := range newValues {
+		oldValue := oldValues[key]
+		if !reflect.DeepEqual(oldValue, newValue) {
+			changes = append(changes, Change{Key: key, Old: oldValue, New: newValue})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Key < changes[j].Key
+	})
+
+	return changes
+}
+
+// DiffConfig returns the keys whose values differ between two configs, with
+// secrets masked. A changed secret is reported with both values masked.
+func DiffConfig(before, after *Config) []Change {
+	changes := Diff(before.values(), after.values())
+	maskChanges(changes)
+	return changes
+}
+
+// maskChanges masks the secrets in changes for logging and auditing
+func maskChanges(changes []Change) {
+	for i := range changes {
+		changes[i].Old = maskSecret(changes[i].Key, changes[i].Old)
+		changes[i].New = maskSecret(changes[i].Key, changes[i].New)
+	}
+}
+
+func logChanges(changes []Change) {
+	if len(changes) == 0 {
+		log.Info().Msg("Config reloaded, no changes")
+		return
+	}
+
+	for _, change := range changes {
+		event := log.Info()
+		msg := "Config value changed"
+		if !change.Applied {
+			event = log.Warn()
+			msg = "Config value changed, restart required to apply"
+		}
+		event.
+			Str("key", change.Key).
+			Interface("old", change.Old).
+			Interface("new", change.New).
+			Msg(msg)
+	}
+}
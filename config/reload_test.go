@@ -0,0 +1,191 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// writeConfig writes a config file in a temporary directory
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// Runtime keys changed in the file are applied; the others are reported as
+// needing a restart
+func TestReloadAppliesRuntimeKeys(t *testing.T) {
+	store := NewStore(Default(), Sources{})
+	path := writeConfig(t, "poll_interval: 300\nport: 9000\n")
+	r := NewReloader(path, store, nil)
+
+	changes, err := r.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applied := make(map[string]bool)
+	for _, change := range changes {
+		applied[change.Key] = change.Applied
+	}
+	if len(changes) != 2 || !applied["poll_interval"] || applied["port"] {
+		t.Errorf("changes = %+v, want poll_interval applied and port not", changes)
+	}
+
+	cfg, sources := store.Sources()
+	if cfg.PollInterval != 300 {
+		t.Errorf("poll_interval = %d, want 300", cfg.PollInterval)
+	}
+	if cfg.Port != Default().Port {
+		t.Errorf("port = %d, applied without a restart", cfg.Port)
+	}
+	if sources["poll_interval"] != SourceFile {
+		t.Errorf("poll_interval source = %s, want %s", sources["poll_interval"], SourceFile)
+	}
+}
+
+// An invalid file leaves the live config untouched
+func TestReloadRejectsInvalidFile(t *testing.T) {
+	store := NewStore(Default(), Sources{})
+	before := store.Get()
+	path := writeConfig(t, "poll_interval: 300\nlock_grace_period: 100000\n")
+
+	if _, err := NewReloader(path, store, nil).Reload(); err == nil {
+		t.Fatal("invalid config reloaded")
+	}
+	if store.Get() != before {
+		t.Error("snapshot replaced by an invalid reload")
+	}
+}
+
+// A changed secret is applied with its real value and reported masked
+func TestReloadAppliesSecrets(t *testing.T) {
+	store := NewStore(Default(), Sources{})
+	path := writeConfig(t, "alert_webhook_url: http://alerts.example/hook\n")
+
+	changes, err := NewReloader(path, store, nil).Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := store.Get().AlertWebhookURL; got != "http://alerts.example/hook" {
+		t.Errorf("alert_webhook_url = %q, want the file value", got)
+	}
+	if len(changes) != 1 || changes[0].New != secretMask {
+		t.Errorf("changes = %+v, want alert_webhook_url masked", changes)
+	}
+}
+
+// Run with go test -race: reloads and updates share the store's write lock,
+// and the file value wins once updates stop
+func TestReloadConcurrentWithUpdates(t *testing.T) {
+	store := NewStore(Default(), Sources{})
+	path := writeConfig(t, "poll_interval: 300\n")
+	r := NewReloader(path, store, nil)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			if _, err := store.Update(map[string]interface{}{"poll_interval": 400 + i}); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			if _, err := r.Reload(); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+
+	if _, err := r.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := store.Get().PollInterval; got != 300 {
+		t.Errorf("poll_interval = %d after a final reload, want 300", got)
+	}
+}
+
+// Persist keeps comments, unrelated keys, the file mode and a symlink
+func TestPersistKeepsFile(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "shared", "config.yaml")
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := "# Clipboard controller\nport: 8899 # HTTP port\npoll_interval: 200 # ms\n"
+	if err := os.WriteFile(target, []byte(content), 0640); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "config.yaml")
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	r := NewReloader(link, NewStore(Default(), Sources{}), nil)
+	if err := r.Persist(map[string]interface{}{"poll_interval": 500, "lock_max_duration": 30}); err != nil {
+		t.Fatal(err)
+	}
+
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("config symlink replaced: %v", err)
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0640 {
+		t.Errorf("mode = %v, want 0640", info.Mode().Perm())
+	}
+
+	data, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"# Clipboard controller", "port: 8899 # HTTP port", "poll_interval: 500 # ms", "lock_max_duration: 30"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("written file lacks %q:\n%s", want, data)
+		}
+	}
+
+	// The write does not look like an outside change to the watcher
+	if r.fileChanged() {
+		t.Error("own write seen as a file change")
+	}
+}
+
+// Persist creates a missing config file readable like any other
+func TestPersistCreatesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	r := NewReloader(path, NewStore(Default(), Sources{}), nil)
+	if err := r.Persist(map[string]interface{}{"poll_interval": 500}); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0644 {
+		t.Errorf("mode = %v, want 0644", info.Mode().Perm())
+	}
+	cfg, _, err := Load(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.PollInterval != 500 {
+		t.Errorf("poll_interval = %d, want 500", cfg.PollInterval)
+	}
+}
@@ -0,0 +1,266 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Source names the configuration layer that supplied a value
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+	SourceRuntime Source = "runtime" // PATCH /config
+)
+
+// EnvPrefix is prepended to the upper-cased config key to form the environment variable name
+const EnvPrefix = "CLIPBOARD_"
+
+// Sources maps config keys to the layer that supplied their effective value
+type Sources map[string]Source
+
+// Clone returns a copy of the sources map
+func (s Sources) Clone() Sources {
+	clone := make(Sources, len(s))
+	for k, v := range s {
+		clone[k] = v
+	}
+	return clone
+}
+
+// Keys returns every config key (the yaml tags of Config), in declaration order
+func Keys() []string {
+	t := reflect.TypeOf(Config{})
+	keys := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		if key := yamlKey(t.Field(i)); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// EnvName returns the environment variable that sets the given config key
+func EnvName(key string) string {
+	return EnvPrefix + strings.ToUpper(key)
+}
+
+// FlagName returns the command line flag that sets the given config key
+func FlagName(key string) string {
+	return strings.ReplaceAll(key, "_", "-")
+}
+
+// Load builds the config by layering, in order of increasing precedence:
+// defaults, the YAML file at path, CLIPBOARD_* environment variables and
+// flagValues (config key -> raw value, only for flags set on the command line).
+// It returns the config together with the layer that supplied each value.
+func Load(path string, flagValues map[string]string) (*Config, Sources, error) {
+	cfg := Default()
+	sources := make(Sources)
+	for _, key := range Keys() {
+		sources[key] = SourceDefault
+	}
+
+	// YAML file
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, nil, err
+	}
+	if len(data) > 0 {
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			return nil, nil, err
+		}
+
+		var present map[string]interface{}
+		if err := yaml.Unmarshal(data, &present); err != nil {
+			return nil, nil, err
+		}
+		for key := range present {
+			if _, ok := sources[key]; ok {
+				sources[key] = SourceFile
+			}
+		}
+	}
+
+	// Environment variables
+	for _, key := range Keys() {
+		value, ok := os.LookupEnv(EnvName(key))
+		if !ok {
+			continue
+		}
+		if err := cfg.setString(key, value); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", EnvName(key), err)
+		}
+		sources[key] = SourceEnv
+	}
+
+	// Command line flags
+	for key, value := range flagValues {
+		if err := cfg.setString(key, value); err != nil {
+			return nil, nil, fmt.Errorf("--%s: %w", FlagName(key), err)
+		}
+		sources[key] = SourceFlag
+	}
+
+	return cfg, sources, nil
+}
+
+// setString parses value according to the type of the field for key and assigns it.
+// Non-scalar fields accept YAML or JSON.
+func (c *Config) setString(key, value string) error {
+	field, ok := c.field(key)
+	if !ok {
+		return fmt.Errorf("unknown config key: %s", key)
+	}
+
+	switch field.Kind() {
+	case reflect.Int:
+		v, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("%s must be an integer (got %q)", key, value)
+		}
+		field.SetInt(int64(v))
+	case reflect.Bool:
+		v, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("%s must be a boolean (got %q)", key, value)
+		}
+		field.SetBool(v)
+	case reflect.String:
+		field.SetString(value)
+	default:
+		if err := yaml.Unmarshal([]byte(value), field.Addr().Interface()); err != nil {
+			return fmt.Errorf("%s: invalid value: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+// field returns the addressable struct field for a config key
+func (c *Config) field(key string) (reflect.Value, bool) {
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if yamlKey(t.Field(i)) == key {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// Value returns the current value for a config key
+func (c *Config) Value(key string) (interface{}, bool) {
+	field, ok := c.field(key)
+	if !ok {
+		return nil, false
+	}
+	return field.Interface(), true
+}
+
+func yamlKey(f reflect.StructField) string {
+	tag := f.Tag.Get("yaml")
+	if tag == "" || tag == "-" {
+		return ""
+	}
+	return strings.Split(tag, ",")[0]
+}
+
+// FlagSet registers one command line flag per config key and records which
+// ones were set explicitly
+type FlagSet struct {
+	values map[string]string
+}
+
+// RegisterFlags defines a flag for every config key on fs (e.g. --lock-max-duration)
+func RegisterFlags(fs *flag.FlagSet) *FlagSet {
+	f := &FlagSet{values: make(map[string]string)}
+	defaults := Default()
+
+	for _, key := range Keys() {
+		field, _ := defaults.field(key)
+		usage := fmt.Sprintf("Override %s (env %s)", key, EnvName(key))
+		fs.Var(&configFlag{key: key, values: f.values, isBool: field.Kind() == reflect.Bool}, FlagName(key), usage)
+	}
+
+	return f
+}
+
+// Values returns the config key -> raw value of every flag set on the command line
+func (f *FlagSet) Values() map[string]string {
+	values := make(map[string]string, len(f.values))
+	for k, v := range f.values {
+		values[k] = v
+	}
+	return values
+}
+
+// configFlag is a flag.Value that records the raw string for later layering
+type configFlag struct {
+	key    string
+	values map[string]string
+	isBool bool
+}
+
+func (cf *configFlag) String() string {
+	if cf == nil || cf.values == nil {
+		return ""
+	}
+	return cf.values[cf.key]
+}
+
+func (cf *configFlag) Set(value string) error {
+	// Check the value parses before accepting it
+	if err := Default().setString(cf.key, value); err != nil {
+		return err
+	}
+	cf.values[cf.key] = value
+	return nil
+}
+
+func (cf *configFlag) IsBoolFlag() bool {
+	return cf.isBool
+}
+
+// SourceInfo describes where an effective config value came from
+type SourceInfo struct {
+	Key    string      `json:"key"`
+	Value  interface{} `json:"value"`
+	Source Source      `json:"source"`
+	Env    string      `json:"env"`
+	Flag   string      `json:"flag"`
+}
+
+// DescribeSources lists every config key with its effective value and source, sorted by key
+func DescribeSources(cfg *Config, sources Sources) []SourceInfo {
+	keys := Keys()
+	sort.Strings(keys)
+
+	result := make([]SourceInfo, 0, len(keys))
+	for _, key := range keys {
+		value, _ := cfg.Value(key)
+		value = maskSecret(key, value)
+		source := sources[key]
+		if source == "" {
+			source = SourceDefault
+		}
+		result = append(result, SourceInfo{
+			Key:    key,
+			Value:  value,
+			Source: source,
+			Env:    EnvName(key),
+			Flag:   "--" + FlagName(key),
+		})
+	}
+	return result
+}
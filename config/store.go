@@ -0,0 +1,107 @@
+package config
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Store holds the live configuration as an immutable snapshot.
+// Readers call Get once per operation and use the returned Config without
+// locking. Writers derive a new snapshot, validate it and swap it in
+// atomically, so an invalid config is never visible to readers.
+type Store struct {
+	writeMu sync.Mutex // Serializes writers so concurrent updates don't lose each other
+	current atomic.Pointer[snapshot]
+}
+
+// snapshot pairs a config with the layer that supplied each of its values
+type snapshot struct {
+	cfg     *Config
+	sources Sources
+}
+
+// NewStore creates a store with cfg as the initial snapshot
+func NewStore(cfg *Config, sources Sources) *Store {
+	s := &Store{}
+	s.current.Store(&snapshot{cfg: cfg, sources: sources.Clone()})
+	return s
+}
+
+// Get returns the current config snapshot. The result must not be modified.
+func (s *Store) Get() *Config {
+	return s.current.Load().cfg
+}
+
+// Sources returns the current config snapshot together with the source of each value
+func (s *Store) Sources() (*Config, Sources) {
+	snap := s.current.Load()
+	return snap.cfg, snap.sources.Clone()
+}
+
+// Update applies runtime-safe updates to a copy of the current snapshot,
+// validates it and publishes it. On validation failure the current snapshot
+// is kept and the error is returned.
+func (s *Store) Update(updates map[string]interface{}) (*Config, error) {
+	return s.update(updates, nil)
+}
+
+// update is Update with the source of each updated key taken from sources
+// (keys missing from sources are recorded as SourceRuntime)
+func (s *Store) update(updates map[string]interface{}, sources Sources) (*Config, error) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	return s.apply(updates, sources)
+}
+
+// reload diffs loaded, a config read from the file, against the current
+// snapshot and applies the changed runtime keys, both under the write lock
+// so that an update in between is neither lost nor reverted. Keys that need
+// a restart are reported with Applied=false. Secrets are not masked.
+func (s *Store) reload(loaded *Config, sources Sources) ([]Change, error) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	changes := Diff(s.current.Load().cfg.values(), loaded.values())
+
+	runtime := make(map[string]bool, len(RuntimeKeys))
+	for _, key := range RuntimeKeys {
+		runtime[key] = true
+	}
+
+	updates := make(map[string]interface{})
+	for i, change := range changes {
+		if runtime[change.Key] {
+			updates[change.Key] = change.New
+			changes[i].Applied = true
+		}
+	}
+
+	if len(updates) > 0 {
+		if _, err := s.apply(updates, sources); err != nil {
+			return nil, err
+		}
+	}
+	return changes, nil
+}
+
+// apply publishes the current snapshot with updates. Callers hold writeMu.
+func (s *Store) apply(updates map[string]interface{}, sources Sources) (*Config, error) {
+	current := s.current.Load()
+	next := current.cfg.With(updates)
+	if err := next.Validate(); err != nil {
+		return nil, err
+	}
+
+	nextSources := current.sources.Clone()
+	for key := range RuntimeSubset(updates) {
+		source, ok := sources[key]
+		if !ok {
+			source = SourceRuntime
+		}
+		nextSources[key] = source
+	}
+
+	s.current.Store(&snapshot{cfg: next, sources: nextSources})
+	return next, nil
+}
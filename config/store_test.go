@@ -0,0 +1,88 @@
+package config
+
+import (
+	"sync"
+	"testing"
+)
+
+// Run with go test -race: readers never see a snapshot half-updated or
+// failing validation while writers apply valid and invalid updates.
+func TestStoreConcurrentUpdates(t *testing.T) {
+	// Every valid snapshot has lock_grace_period = lock_max_duration - 5
+	cfg := Default()
+	cfg.LockGracePeriod = cfg.LockMaxDuration - 5
+	store := NewStore(cfg, Sources{})
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				duration := 10 + (w+i)%20
+				if _, err := store.Update(map[string]interface{}{"lock_max_duration": duration, "lock_grace_period": duration - 5}); err != nil {
+					t.Errorf("valid update rejected: %v", err)
+					return
+				}
+				if _, err := store.Update(map[string]interface{}{"lock_max_duration": 5, "lock_grace_period": 9}); err == nil {
+					t.Errorf("invalid update accepted")
+					return
+				}
+			}
+		}(w)
+	}
+
+	var readers sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				cfg := store.Get()
+				if cfg.LockGracePeriod != cfg.LockMaxDuration-5 {
+					t.Errorf("inconsistent snapshot: lock_max_duration %d, lock_grace_period %d", cfg.LockMaxDuration, cfg.LockGracePeriod)
+					return
+				}
+				if err := cfg.Validate(); err != nil {
+					t.Errorf("invalid snapshot published: %v", err)
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(done)
+	readers.Wait()
+}
+
+// A rejected update keeps the current snapshot
+func TestStoreUpdateValidatesBeforeCommit(t *testing.T) {
+	store := NewStore(Default(), Sources{})
+	before := store.Get()
+
+	if _, err := store.Update(map[string]interface{}{"poll_interval": 500, "lock_grace_period": before.LockMaxDuration}); err == nil {
+		t.Fatal("invalid update accepted")
+	}
+	if store.Get() != before {
+		t.Error("snapshot replaced by a rejected update")
+	}
+
+	updated, err := store.Update(map[string]interface{}{"poll_interval": 500})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.PollInterval != 500 || store.Get() != updated {
+		t.Errorf("valid update not published")
+	}
+	if before.PollInterval == 500 {
+		t.Error("previous snapshot modified in place")
+	}
+}
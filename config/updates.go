@@ -0,0 +1,100 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// UpdateError describes why a single key of a runtime update was rejected
+type UpdateError struct {
+	Key    string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+func (e UpdateError) Error() string {
+	return e.Key + ": " + e.Reason
+}
+
+// ParseUpdates strictly converts a JSON object of runtime updates into the
+// typed values expected by Config.With. Unknown keys, keys that need a
+// restart and values of the wrong type are all rejected; nothing is returned
+// unless every key is valid.
+func ParseUpdates(raw map[string]json.RawMessage) (map[string]interface{}, []UpdateError) {
+	runtime := make(map[string]bool, len(RuntimeKeys))
+	for _, key := range RuntimeKeys {
+		runtime[key] = true
+	}
+
+	keys := make([]string, 0, len(raw))
+	for key := range raw {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	defaults := Default()
+	updates := make(map[string]interface{}, len(raw))
+	var errs []UpdateError
+
+	for _, key := range keys {
+		field, ok := defaults.field(key)
+		if !ok {
+			errs = append(errs, UpdateError{Key: key, Reason: "unknown config key"})
+			continue
+		}
+		if !runtime[key] {
+			errs = append(errs, UpdateError{Key: key, Reason: "cannot be changed at runtime, edit the config file and restart"})
+			continue
+		}
+
+		value, err := decodeValue(raw[key], field.Type())
+		if err != nil {
+			errs = append(errs, UpdateError{Key: key, Reason: err.Error()})
+			continue
+		}
+		updates[key] = value
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return updates, nil
+}
+
+// decodeValue decodes a JSON value into a value of type t without coercion
+func decodeValue(data json.RawMessage, t reflect.Type) (interface{}, error) {
+	switch t.Kind() {
+	case reflect.Int:
+		var f float64
+		if err := json.Unmarshal(data, &f); err != nil {
+			return nil, fmt.Errorf("must be an integer")
+		}
+		if f != math.Trunc(f) || f > math.MaxInt32 || f < math.MinInt32 {
+			return nil, fmt.Errorf("must be an integer (got %s)", string(data))
+		}
+		return int(f), nil
+	case reflect.Bool:
+		var b bool
+		if err := json.Unmarshal(data, &b); err != nil {
+			return nil, fmt.Errorf("must be a boolean")
+		}
+		return b, nil
+	case reflect.String:
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return nil, fmt.Errorf("must be a string")
+		}
+		return s, nil
+	default:
+		ptr := reflect.New(t)
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(ptr.Interface()); err != nil {
+			return nil, fmt.Errorf("invalid value: %v", err)
+		}
+		return ptr.Elem().Interface(), nil
+	}
+}
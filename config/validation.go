@@ -7,9 +7,6 @@ import (
 
 // Validate checks if the config values are valid
 func (c *Config) Validate() error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
 	// Port must be valid
 	if c.Port < 1 || c.Port > 65535 {
 		return errors.New("port must be between 1 and 65535")
@@ -49,6 +46,22 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("log_level must be one of: debug, info, warn, error (got: %s)", c.LogLevel)
 	}
 
+	// Log writer queue
+	if c.LogQueueSize <= 0 {
+		return errors.New("log_queue_size must be positive")
+	}
+	if c.LogOverflowPolicy != "block" && c.LogOverflowPolicy != "drop_oldest" {
+		return fmt.Errorf("log_overflow_policy must be one of: block, drop_oldest (got: %s)", c.LogOverflowPolicy)
+	}
+
+	// Log file size limits
+	if c.LogMaxFileSizeMB < 0 {
+		return errors.New("log_max_file_size_mb must be non-negative")
+	}
+	if c.LogMaxTotalSizeMB < 0 {
+		return errors.New("log_max_total_size_mb must be non-negative")
+	}
+
 	// Positive values check
 	if c.HeartbeatTimeout <= 0 {
 		return errors.New("heartbeat_timeout must be positive")
@@ -56,6 +69,9 @@ func (c *Config) Validate() error {
 	if c.HeartbeatInterval <= 0 {
 		return errors.New("heartbeat_interval must be positive")
 	}
+	if c.OfflineToolRetention < 0 {
+		return errors.New("offline_tool_retention must be non-negative")
+	}
 	if c.PollInterval <= 0 {
 		return errors.New("poll_interval must be positive")
 	}
@@ -71,6 +87,40 @@ func (c *Config) Validate() error {
 	if c.LockExtendMax < 0 {
 		return errors.New("lock_extend_max must be non-negative")
 	}
+	if c.ConfigWatch && c.ConfigWatchInterval <= 0 {
+		return errors.New("config_watch_interval must be positive when config_watch is enabled")
+	}
+
+	for _, p := range c.MetricsPercentiles {
+		if p <= 0 || p > 100 {
+			return fmt.Errorf("metrics_percentiles values must be greater than 0 and at most 100 (got: %v)", p)
+		}
+	}
+
+	// Per-tool profiles
+	if err := c.validateProfiles(); err != nil {
+		return err
+	}
+
+	// Log sampling rules
+	if err := c.validateLogSampling(); err != nil {
+		return err
+	}
+
+	// Log sinks
+	if err := c.validateLogSinks(); err != nil {
+		return err
+	}
+
+	// Alert rules
+	if err := c.validateAlerts(); err != nil {
+		return err
+	}
+
+	// Webhooks
+	if err := c.validateWebhooks(); err != nil {
+		return err
+	}
 
 	return nil
 }
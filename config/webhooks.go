@@ -0,0 +1,141 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"clipboard-controller/model"
+)
+
+// WebhookEvents lists the event types a webhook can subscribe to
+var WebhookEvents = []string{
+	model.LockEventRequested,
+	model.LockEventGranted,
+	model.LockEventReleased,
+	model.LockEventExpired,
+	model.LockEventExtended,
+	model.ToolEventRegistered,
+	model.ToolEventOffline,
+	model.ToolEventUnregistered,
+	model.ToolEventRemoved,
+}
+
+// WebhookConfig subscribes a URL to lock and tool events. Deliveries are
+// signed with Secret, retried with backoff, and written to the dead-letter
+// file after MaxAttempts failures.
+type WebhookConfig struct {
+	Name        string   `yaml:"name" json:"name"`
+	URL         string   `yaml:"url" json:"url"`
+	Secret      string   `yaml:"secret,omitempty" json:"secret,omitempty"`             // HMAC-SHA256 key, empty = unsigned
+	Events      []string `yaml:"events,omitempty" json:"events,omitempty"`             // Event types or globs (lock_*), empty = all
+	MaxAttempts int      `yaml:"max_attempts,omitempty" json:"max_attempts,omitempty"` // Default 5
+	Disabled    bool     `yaml:"disabled,omitempty" json:"disabled,omitempty"`
+}
+
+// WantsEvent reports whether the webhook receives eventType
+func (w WebhookConfig) WantsEvent(eventType string) bool {
+	if w.Disabled {
+		return false
+	}
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, pattern := range w.Events {
+		if matched, _ := path.Match(pattern, eventType); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Attempts returns the number of delivery attempts before dead-lettering
+func (w WebhookConfig) Attempts() int {
+	if w.MaxAttempts == 0 {
+		return 5
+	}
+	return w.MaxAttempts
+}
+
+// Webhook returns the webhook called name
+func (c *Config) Webhook(name string) (WebhookConfig, bool) {
+	for _, webhook := range c.Webhooks {
+		if webhook.Name == name {
+			return webhook, true
+		}
+	}
+	return WebhookConfig{}, false
+}
+
+// MaskedWebhooks returns the webhooks with their secrets masked for display
+func MaskedWebhooks(webhooks []WebhookConfig) []WebhookConfig {
+	if webhooks == nil {
+		return nil
+	}
+	masked := make([]WebhookConfig, len(webhooks))
+	for i, webhook := range webhooks {
+		masked[i] = webhook
+		if webhook.Secret != "" {
+			masked[i].Secret = secretMask
+		}
+	}
+	return masked
+}
+
+// keepWebhookSecrets copies webhooks, replacing a masked secret, as shown by
+// GET /config, with the current secret of the webhook of the same name
+func keepWebhookSecrets(webhooks, current []WebhookConfig) []WebhookConfig {
+	out := append([]WebhookConfig(nil), webhooks...)
+	for i, webhook := range out {
+		if webhook.Secret != secretMask {
+			continue
+		}
+		out[i].Secret = ""
+		for _, existing := range current {
+			if existing.Name == webhook.Name {
+				out[i].Secret = existing.Secret
+			}
+		}
+	}
+	return out
+}
+
+// validateWebhooks checks every webhook
+func (c *Config) validateWebhooks() error {
+	names := make(map[string]bool, len(c.Webhooks))
+	for i, webhook := range c.Webhooks {
+		if webhook.Name == "" {
+			return fmt.Errorf("webhooks[%d]: name is required", i)
+		}
+		if names[webhook.Name] {
+			return fmt.Errorf("webhooks[%d]: duplicate name %q", i, webhook.Name)
+		}
+		names[webhook.Name] = true
+
+		u, err := url.Parse(webhook.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("webhooks[%d]: url must be an http or https URL (got: %s)", i, webhook.URL)
+		}
+		if webhook.MaxAttempts < 0 || webhook.MaxAttempts > 20 {
+			return fmt.Errorf("webhooks[%d]: max_attempts must be between 0 and 20 (got: %d)", i, webhook.MaxAttempts)
+		}
+
+		for _, pattern := range webhook.Events {
+			if !matchesAny(pattern, WebhookEvents) {
+				return fmt.Errorf("webhooks[%d]: event %q matches none of: %s", i, pattern, strings.Join(WebhookEvents, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// matchesAny reports whether the glob pattern matches one of values
+func matchesAny(pattern string, values []string) bool {
+	for _, value := range values {
+		if matched, _ := path.Match(pattern, value); matched {
+			return true
+		}
+	}
+	return false
+}
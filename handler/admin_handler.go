@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"clipboard-controller/config"
+	"clipboard-controller/logger"
+	"clipboard-controller/model"
+	"clipboard-controller/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterAdminHandler registers operator actions used by the dashboard
+func RegisterAdminHandler(router *gin.Engine, lm *service.LockManager, tl *service.ToolLifecycle, cfg *config.Store, el *logger.EventLogger) {
+	admin := router.Group("/admin", adminAuth(cfg, el))
+	{
+		admin.POST("/lock/release", forceReleaseLock(lm, el))
+		admin.POST("/tickets/:id/cancel", cancelTicket(lm, el))
+		admin.POST("/tools/:id/unregister", forceUnregisterTool(tl, el))
+	}
+}
+
+// adminAuth requires the admin token as "Authorization: Bearer <token>" or
+// X-Admin-Token. Without a configured token only loopback clients are allowed.
+// Rejected calls are recorded in the audit log.
+func adminAuth(cfg *config.Store, el *logger.EventLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := cfg.Get().AdminToken
+
+		if token == "" {
+			ip := net.ParseIP(c.RemoteIP())
+			if ip == nil || !ip.IsLoopback() {
+				denyAdmin(c, el, "no admin token configured, only loopback clients are allowed")
+				return
+			}
+			c.Set("actor", model.AuditActorLocalhost)
+			c.Next()
+			return
+		}
+
+		supplied := c.GetHeader("X-Admin-Token")
+		if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+			supplied = strings.TrimPrefix(auth, "Bearer ")
+		}
+		if supplied == "" {
+			denyAdmin(c, el, "missing admin token")
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(supplied), []byte(token)) != 1 {
+			denyAdmin(c, el, "invalid admin token")
+			return
+		}
+
+		c.Set("actor", model.AuditActorAdminToken)
+		c.Next()
+	}
+}
+
+// denyAdmin rejects an admin call and records the failure, aggregated by
+// client IP (see EventLogger.LogAuthFailure)
+func denyAdmin(c *gin.Context, el *logger.EventLogger, reason string) {
+	entry := auditEntry(c, model.AuditLog{
+		Action: model.AuditAuthFailure,
+		Target: c.Request.Method + " " + c.Request.URL.Path,
+		Result: model.AuditResultDenied,
+		Reason: reason,
+	})
+	el.LogAuthFailure(&entry)
+	respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, nil)
+}
+
+func forceReleaseLock(lm *service.LockManager, el *logger.EventLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ticket := lm.ExpireCurrentLock(service.ReasonAdminRelease)
+		if ticket == nil {
+			respondError(c, http.StatusNotFound, ErrCodeNoActiveLock, nil)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"status":    "released",
+			"ticket_id": ticket.TicketID,
+			"tool_id":   ticket.ToolID,
+			"thread_id": ticket.ThreadID,
+		})
+
+		audit(c, el, model.AuditLog{Action: model.AuditLockRelease, Target: ticket.TicketID})
+
+		// Set context for logging
+		c.Set("ticket_id", ticket.TicketID)
+		c.Set("tool_id", ticket.ToolID)
+		c.Set("thread_id", ticket.ThreadID)
+	}
+}
+
+func cancelTicket(lm *service.LockManager, el *logger.EventLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ticketID := c.Param("id")
+		if !validateIDParam(c, "id", ticketID) {
+			return
+		}
+
+		ticket, err := lm.CancelTicket(ticketID, service.ReasonAdminCancel)
+		if err != nil {
+			if errors.Is(err, service.ErrTicketNotFound) {
+				respondError(c, http.StatusNotFound, ErrCodeTicketNotFound, nil)
+				return
+			}
+			respondInternalError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"status":    "cancelled",
+			"ticket_id": ticket.TicketID,
+		})
+
+		audit(c, el, model.AuditLog{Action: model.AuditTicketCancel, Target: ticket.TicketID})
+
+		// Set context for logging
+		c.Set("ticket_id", ticket.TicketID)
+		c.Set("tool_id", ticket.ToolID)
+		c.Set("thread_id", ticket.ThreadID)
+	}
+}
+
+func forceUnregisterTool(tl *service.ToolLifecycle, el *logger.EventLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		toolID := c.Param("id")
+		if !validateIDParam(c, "id", toolID) {
+			return
+		}
+
+		tool, released, err := tl.ForceUnregister(toolID)
+		if err != nil {
+			respondToolError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"status":           "unregistered",
+			"released_tickets": released,
+		})
+
+		audit(c, el, model.AuditLog{Action: model.AuditToolUnregister, Target: tool.ToolID})
+
+		// Set context for logging
+		c.Set("tool_id", tool.ToolID)
+	}
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"net/http"
+
+	"clipboard-controller/logger"
+	"clipboard-controller/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterAlertHandler registers the alert listing endpoint
+func RegisterAlertHandler(router *gin.Engine, am *logger.AlertManager) {
+	router.GET("/alerts", listAlerts(am))
+}
+
+// listAlerts returns the firing and pending alerts and the recently resolved
+// ones
+// GET /alerts?state=firing
+func listAlerts(am *logger.AlertManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		state := c.Query("state")
+		switch state {
+		case "", model.AlertStatePending, model.AlertStateFiring, model.AlertStateResolved:
+		default:
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, []FieldError{{Field: "state", Reason: "must be pending, firing or resolved"}})
+			return
+		}
+
+		alerts := make([]model.Alert, 0)
+		firing := 0
+		for _, alert := range am.Alerts() {
+			if alert.State == model.AlertStateFiring {
+				firing++
+			}
+			if state == "" || alert.State == state {
+				alerts = append(alerts, alert)
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"count":  len(alerts),
+			"firing": firing,
+			"alerts": alerts,
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"clipboard-controller/logger"
+	"clipboard-controller/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// audit records an action of the caller in the audit log. The actor defaults
+// to the one set by adminAuth, or anonymous.
+func audit(c *gin.Context, el *logger.EventLogger, entry model.AuditLog) {
+	entry = auditEntry(c, entry)
+	el.LogAudit(&entry)
+}
+
+// auditEntry fills in the caller of entry: actor, client IP and request ID
+func auditEntry(c *gin.Context, entry model.AuditLog) model.AuditLog {
+	if entry.Actor == "" {
+		entry.Actor = c.GetString("actor")
+	}
+	if entry.Actor == "" {
+		entry.Actor = model.AuditActorAnonymous
+	}
+	entry.ClientIP = c.ClientIP()
+	entry.RequestID = c.GetString("request_id")
+	return entry
+}
@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Run with go test -race: config updates, lock traffic and status reads
+// share the config snapshot, the registry and the lock manager. The JSON
+// encoder's sync.Pool orders some accesses for the race detector, so the
+// registry copies are also tested directly in the service package.
+func TestConcurrentConfigLockAndToolStatus(t *testing.T) {
+	s := newTestServer(t)
+
+	const tools, threads, rounds = 3, 2, 15
+	sessions := make(map[string]string, tools)
+	for i := 0; i < tools; i++ {
+		toolID := fmt.Sprintf("tool_%d", i)
+		w := s.do("POST", "/tool/register", map[string]interface{}{"tool_id": toolID, "labels": map[string]string{"team": "qa"}})
+		if w.Code != http.StatusOK {
+			t.Fatalf("register %s: %d %s", toolID, w.Code, w.Body.String())
+		}
+		sessions[toolID] = decode(t, w)["session_id"].(string)
+	}
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+
+	// Lock clients: request, poll until granted, release
+	var clients sync.WaitGroup
+	for toolID, sessionID := range sessions {
+		for thread := 0; thread < threads; thread++ {
+			clients.Add(1)
+			go func(toolID, sessionID, threadID string) {
+				defer clients.Done()
+				for round := 0; round < rounds; round++ {
+					if !acquireAndRelease(t, s, toolID, sessionID, threadID) {
+						return
+					}
+				}
+			}(toolID, sessionID, fmt.Sprintf("thread_%d", thread))
+		}
+	}
+
+	// Config writer: valid updates apply, invalid ones are rejected whole
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			w := s.do("PATCH", "/config", map[string]interface{}{
+				"lock_max_duration": 20 + i%10,
+				"poll_interval":     100 + i%50,
+				"heartbeat_timeout": 300 + i%5,
+			})
+			if w.Code != http.StatusOK {
+				t.Errorf("PATCH /config: %d %s", w.Code, w.Body.String())
+				return
+			}
+
+			w = s.do("PATCH", "/config", map[string]interface{}{"lock_max_duration": 60, "lock_grace_period": 90})
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("invalid PATCH /config: got %d, want 400", w.Code)
+				return
+			}
+		}
+	}()
+
+	// Status readers, and heartbeats in goroutines of their own so the
+	// registry lock they take does not order the readers' accesses
+	for toolID, sessionID := range sessions {
+		wg.Add(2)
+		go func(toolID string) {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				for _, path := range []string{"/tool/status?tool_id=" + toolID, "/tool/list", "/tools", "/lock/status", "/ui/state", "/config"} {
+					if w := s.do("GET", path, nil); w.Code != http.StatusOK {
+						t.Errorf("GET %s: %d %s", path, w.Code, w.Body.String())
+						return
+					}
+				}
+			}
+		}(toolID)
+		go func(toolID, sessionID string) {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				if w := s.do("POST", "/tool/heartbeat", map[string]string{"tool_id": toolID, "session_id": sessionID}); w.Code != http.StatusOK {
+					t.Errorf("heartbeat %s: %d %s", toolID, w.Code, w.Body.String())
+					return
+				}
+			}
+		}(toolID, sessionID)
+	}
+
+	clients.Wait()
+	close(done)
+	wg.Wait()
+
+	if got := s.config.Get().LockGracePeriod; got == 90 {
+		t.Errorf("rejected lock_grace_period was applied")
+	}
+}
+
+// acquireAndRelease takes the lock for one thread and releases it
+func acquireAndRelease(t *testing.T, s *testServer, toolID, sessionID, threadID string) bool {
+	w := s.do("POST", "/lock/request", map[string]string{"tool_id": toolID, "thread_id": threadID, "session_id": sessionID})
+	if w.Code != http.StatusOK {
+		t.Errorf("lock request %s/%s: %d %s", toolID, threadID, w.Code, w.Body.String())
+		return false
+	}
+	ticketID := decode(t, w)["ticket_id"].(string)
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		w = s.do("GET", "/lock/check?ticket_id="+ticketID+"&session_id="+sessionID, nil)
+		if w.Code != http.StatusOK {
+			t.Errorf("lock check %s: %d %s", ticketID, w.Code, w.Body.String())
+			return false
+		}
+		status := decode(t, w)["status"]
+		if status == "granted" {
+			break
+		}
+		if status != "waiting" {
+			t.Errorf("lock check %s: status %v", ticketID, status)
+			return false
+		}
+		if time.Now().After(deadline) {
+			t.Errorf("lock check %s: not granted after 10s", ticketID)
+			return false
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	w = s.do("POST", "/lock/release", map[string]string{"ticket_id": ticketID, "session_id": sessionID})
+	if w.Code != http.StatusOK {
+		t.Errorf("lock release %s: %d %s", ticketID, w.Code, w.Body.String())
+		return false
+	}
+	return true
+}
+
+// A rejected update leaves the whole previous snapshot live
+func TestInvalidConfigUpdateIsNotApplied(t *testing.T) {
+	s := newTestServer(t)
+	before := s.config.Get()
+
+	w := s.do("PATCH", "/config", map[string]interface{}{"poll_interval": 500, "lock_grace_period": before.LockMaxDuration})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("got %d, want 400: %s", w.Code, w.Body.String())
+	}
+	if body := decode(t, w); body["error"] != ErrCodeInvalidConfig {
+		t.Errorf("error = %v, want %s", body["error"], ErrCodeInvalidConfig)
+	}
+
+	after := s.config.Get()
+	if after != before {
+		t.Errorf("config snapshot replaced by a rejected update")
+	}
+	if after.PollInterval != before.PollInterval {
+		t.Errorf("poll_interval = %d, want %d", after.PollInterval, before.PollInterval)
+	}
+}
@@ -1,58 +1,104 @@
 package handler
 
 import (
+	"encoding/json"
 	"net/http"
 
 	"clipboard-controller/config"
+	"clipboard-controller/logger"
+	"clipboard-controller/model"
 
 	"github.com/gin-gonic/gin"
 )
 
-// RegisterConfigHandler registers config endpoints
-func RegisterConfigHandler(router *gin.Engine, cfg *config.Config) {
+// RegisterConfigHandler registers config endpoints. Changing or reloading
+// the config requires admin authentication, as /admin and /webhooks do;
+// changes are recorded in the audit log.
+func RegisterConfigHandler(router *gin.Engine, cfg *config.Store, reloader *config.Reloader, el *logger.EventLogger) {
 	router.GET("/config", getConfig(cfg))
-	router.PATCH("/config", updateConfig(cfg))
+	router.PATCH("/config", adminAuth(cfg, el), updateConfig(cfg, reloader, el))
+	router.GET("/config/sources", getConfigSources(cfg))
+	router.POST("/config/reload", adminAuth(cfg, el), reloadConfig(reloader, el))
 }
 
-func getConfig(cfg *config.Config) gin.HandlerFunc {
+func getConfig(cfg *config.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.JSON(http.StatusOK, cfg.ToMap())
+		c.JSON(http.StatusOK, cfg.Get().ToMap())
 	}
 }
 
-func updateConfig(cfg *config.Config) gin.HandlerFunc {
+func getConfigSources(cfg *config.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var updates map[string]interface{}
-		if err := c.ShouldBindJSON(&updates); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error":   "invalid_request",
-				"message": "Invalid JSON body",
-			})
+		snapshot, sources := cfg.Sources()
+		c.JSON(http.StatusOK, gin.H{
+			"sources": config.DescribeSources(snapshot, sources),
+		})
+	}
+}
+
+func updateConfig(cfg *config.Store, reloader *config.Reloader, el *logger.EventLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var raw map[string]json.RawMessage
+		if !bindJSON(c, &raw) {
+			return
+		}
+
+		// Reject unknown, restart-only and mistyped keys
+		updates, errs := config.ParseUpdates(raw)
+		if len(errs) > 0 {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidConfig, errs)
+			return
+		}
+
+		// Validate and apply atomically; nothing changes if invalid
+		before := cfg.Get()
+		updated, err := cfg.Update(updates)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidConfig, []config.UpdateError{{Key: "config", Reason: err.Error()}})
 			return
 		}
 
-		// Convert float64 to int for numeric values (JSON unmarshals numbers as float64)
-		for key, value := range updates {
-			if floatVal, ok := value.(float64); ok {
-				updates[key] = int(floatVal)
+		changes := config.DiffConfig(before, updated)
+		for i := range changes {
+			changes[i].Applied = true
+		}
+		if len(changes) > 0 {
+			audit(c, el, model.AuditLog{Action: model.AuditConfigUpdate, Changes: logger.AuditChanges(changes)})
+		}
+
+		response := gin.H{
+			"status": "updated",
+			"config": updated.ToMap(),
+		}
+
+		// Persist to config file so changes survive restarts
+		if updated.ConfigWriteBack {
+			if err := reloader.Persist(updated.PersistValues(updates)); err != nil {
+				response["persisted"] = false
+				response["persist_error"] = err.Error()
+			} else {
+				response["persisted"] = true
 			}
 		}
 
-		// Update config
-		cfg.Update(updates)
+		c.JSON(http.StatusOK, response)
+	}
+}
 
-		// Validate after update
-		if err := cfg.Validate(); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error":   "invalid_config",
-				"message": err.Error(),
-			})
+func reloadConfig(reloader *config.Reloader, el *logger.EventLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		changes, err := reloader.Reload()
+		if err != nil {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidConfig, []config.UpdateError{{Key: "config", Reason: err.Error()}})
 			return
 		}
 
+		audit(c, el, model.AuditLog{Action: model.AuditConfigReload, Target: reloader.Path(), Changes: logger.AuditChanges(changes)})
+
 		c.JSON(http.StatusOK, gin.H{
-			"status": "updated",
-			"config": cfg.ToMap(),
+			"status":  "reloaded",
+			"path":    reloader.Path(),
+			"changes": changes,
 		})
 	}
 }
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"clipboard-controller/config"
+	"clipboard-controller/model"
+)
+
+// Changing or reloading the config requires admin authentication
+func TestConfigChangesRequireAdmin(t *testing.T) {
+	const token = "s3cret"
+	s := newTestServer(t, func(cfg *config.Config) { cfg.AdminToken = token })
+	webhooks := map[string]interface{}{
+		"webhooks": []map[string]interface{}{{"name": "attacker", "url": "http://attacker.example/hook"}},
+	}
+
+	for _, tc := range []struct {
+		name, token string
+	}{
+		{"no token", ""},
+		{"wrong token", "guess"},
+	} {
+		if w := s.doFrom("192.168.1.20:50000", tc.token, "PATCH", "/config", webhooks); w.Code != http.StatusUnauthorized {
+			t.Errorf("PATCH /config with %s: got %d, want 401", tc.name, w.Code)
+		}
+		if w := s.doFrom("192.168.1.20:50000", tc.token, "POST", "/config/reload", nil); w.Code != http.StatusUnauthorized {
+			t.Errorf("POST /config/reload with %s: got %d, want 401", tc.name, w.Code)
+		}
+	}
+	if got := s.config.Get().Webhooks; len(got) != 0 {
+		t.Fatalf("unauthenticated PATCH applied webhooks: %v", got)
+	}
+
+	w := s.doFrom("192.168.1.20:50000", token, "PATCH", "/config", webhooks)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PATCH /config with the admin token: %d %s", w.Code, w.Body.String())
+	}
+	if got := s.config.Get().Webhooks; len(got) != 1 || got[0].URL != "http://attacker.example/hook" {
+		t.Errorf("webhooks = %v, want the patched one", got)
+	}
+}
+
+// Without an admin token only loopback clients may change the config
+func TestConfigChangesLoopbackOnlyWithoutToken(t *testing.T) {
+	s := newTestServer(t)
+
+	if w := s.doFrom("192.168.1.20:50000", "", "PATCH", "/config", map[string]interface{}{"poll_interval": 500}); w.Code != http.StatusUnauthorized {
+		t.Errorf("remote PATCH /config: got %d, want 401", w.Code)
+	}
+	if w := s.do("PATCH", "/config", map[string]interface{}{"poll_interval": 500}); w.Code != http.StatusOK {
+		t.Errorf("loopback PATCH /config: %d %s", w.Code, w.Body.String())
+	}
+}
+
+// Config changes are audited with the caller authenticated by adminAuth
+func TestConfigChangesAuditedWithCaller(t *testing.T) {
+	const token = "s3cret"
+	s := newTestServer(t, func(cfg *config.Config) { cfg.AdminToken = token })
+
+	if w := s.doFrom("192.168.1.20:50000", token, "PATCH", "/config", map[string]interface{}{"poll_interval": 500}); w.Code != http.StatusOK {
+		t.Fatalf("PATCH /config: %d %s", w.Code, w.Body.String())
+	}
+
+	entry := s.waitAudit(t, model.AuditConfigUpdate)
+	if entry.Actor != model.AuditActorAdminToken {
+		t.Errorf("actor = %q, want %q", entry.Actor, model.AuditActorAdminToken)
+	}
+	if entry.ClientIP != "192.168.1.20" {
+		t.Errorf("client_ip = %q, want 192.168.1.20", entry.ClientIP)
+	}
+}
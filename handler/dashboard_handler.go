@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"embed"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"clipboard-controller/config"
+	"clipboard-controller/logger"
+	"clipboard-controller/model"
+	"clipboard-controller/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed ui/index.html
+var uiFS embed.FS
+
+// Dashboard push settings
+const (
+	dashboardStateInterval = 1 * time.Second
+	dashboardRecentEvents  = 50
+	dashboardEventBuffer   = 64
+)
+
+// DashboardState is the snapshot pushed to the dashboard
+type DashboardState struct {
+	Time               time.Time              `json:"time"`
+	Lock               map[string]interface{} `json:"lock"` // Current lock and queue, as GET /lock/status
+	Tools              []*ToolSummary         `json:"tools"`
+	RecentEvents       []model.LockEventLog   `json:"recent_events"`
+	Summary            model.DailySummaryLog  `json:"summary"` // Today so far
+	AdminTokenRequired bool                   `json:"admin_token_required"`
+}
+
+// DashboardHandler serves the web dashboard and its live update stream
+type DashboardHandler struct {
+	toolRegistry *service.ToolRegistry
+	lockManager  *service.LockManager
+	eventLogger  *logger.EventLogger
+	config       *config.Store
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// RegisterDashboardHandler registers the dashboard endpoints. Call Close on
+// server shutdown to end open event streams.
+func RegisterDashboardHandler(router *gin.Engine, tr *service.ToolRegistry, lm *service.LockManager, el *logger.EventLogger, cfg *config.Store) *DashboardHandler {
+	h := &DashboardHandler{
+		toolRegistry: tr,
+		lockManager:  lm,
+		eventLogger:  el,
+		config:       cfg,
+		closed:       make(chan struct{}),
+	}
+
+	ui := router.Group("/ui")
+	{
+		ui.GET("", h.GetPage)
+		ui.GET("/state", h.GetState)
+		ui.GET("/events", h.StreamEvents)
+	}
+
+	return h
+}
+
+// Close ends all open event streams
+func (h *DashboardHandler) Close() {
+	h.closeOnce.Do(func() {
+		close(h.closed)
+	})
+}
+
+// GetPage serves the single-page dashboard
+// GET /ui
+func (h *DashboardHandler) GetPage(c *gin.Context) {
+	page, _ := uiFS.ReadFile("ui/index.html")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", page)
+}
+
+// GetState returns the current dashboard snapshot
+// GET /ui/state
+func (h *DashboardHandler) GetState(c *gin.Context) {
+	c.JSON(http.StatusOK, h.snapshot())
+}
+
+// StreamEvents pushes the dashboard snapshot every second ("state") and each
+// lock event as it happens ("event") over Server-Sent Events
+// GET /ui/events
+func (h *DashboardHandler) StreamEvents(c *gin.Context) {
+	events, unsubscribe := h.eventLogger.Subscribe(dashboardEventBuffer)
+	defer unsubscribe()
+
+	ticker := time.NewTicker(dashboardStateInterval)
+	defer ticker.Stop()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.SSEvent("state", h.snapshot())
+	c.Writer.Flush()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-h.closed:
+			return false
+		case event := <-events:
+			c.SSEvent("event", event)
+		case <-ticker.C:
+			c.SSEvent("state", h.snapshot())
+		}
+		return true
+	})
+}
+
+// snapshot collects the current dashboard state
+func (h *DashboardHandler) snapshot() DashboardState {
+	tickets := h.lockManager.TicketsByTool()
+	tools := h.toolRegistry.ListTools(nil)
+	summaries := make([]*ToolSummary, 0, len(tools))
+	for _, tool := range tools {
+		summaries = append(summaries, newToolSummary(tool, tickets[tool.ToolID], h.eventLogger.GetToolUsage(tool.ToolID)))
+	}
+
+	return DashboardState{
+		Time:               time.Now(),
+		Lock:               h.lockManager.GetQueueStatus(),
+		Tools:              summaries,
+		RecentEvents:       h.eventLogger.GetRecentEvents(dashboardRecentEvents),
+		Summary:            h.eventLogger.PeekDailySummary(),
+		AdminTokenRequired: h.config.Get().AdminToken != "",
+	}
+}
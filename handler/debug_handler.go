@@ -1,31 +1,48 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"clipboard-controller/logger"
+	"clipboard-controller/model"
+	"clipboard-controller/service"
 
 	"github.com/gin-gonic/gin"
 )
 
+// Paging limits for GET /debug/logs/query
+const (
+	defaultLogQueryLimit = 100
+	maxLogQueryLimit     = 1000
+)
+
 // DebugHandler handles debug and logging endpoints
 type DebugHandler struct {
 	eventLogger    *logger.EventLogger
 	logFileManager *logger.LogFileManager
+	lockManager    *service.LockManager
 }
 
 // RegisterDebugHandler registers debug endpoints
-func RegisterDebugHandler(router *gin.Engine, el *logger.EventLogger, lfm *logger.LogFileManager) {
+func RegisterDebugHandler(router *gin.Engine, el *logger.EventLogger, lfm *logger.LogFileManager, lm *service.LockManager) {
 	h := &DebugHandler{
 		eventLogger:    el,
 		logFileManager: lfm,
+		lockManager:    lm,
 	}
 
 	debug := router.Group("/debug")
 	{
 		debug.GET("/logs/recent", h.GetRecentLogs)
 		debug.GET("/logs/stats", h.GetLogStats)
+		debug.GET("/logs/query", h.QueryLogs)
+		debug.GET("/tickets/:id/timeline", h.GetTicketTimeline)
+		debug.GET("/metrics/distributions", h.GetDistributions)
 	}
 }
 
@@ -53,3 +70,147 @@ func (h *DebugHandler) GetLogStats(c *gin.Context) {
 	stats := h.logFileManager.GetStats()
 	c.JSON(http.StatusOK, stats)
 }
+
+// GetDistributions returns the percentiles of wait time, hold time, queue
+// length and extend counts for the current minute, today and each tool
+// GET /debug/metrics/distributions?tool_id=...
+func (h *DebugHandler) GetDistributions(c *gin.Context) {
+	snapshot := h.eventLogger.PeekDistributions()
+	if toolID := c.Query("tool_id"); toolID != "" {
+		tools := make(map[string]*model.Distributions, 1)
+		if d, ok := snapshot.Tools[toolID]; ok {
+			tools[toolID] = d
+		}
+		snapshot.Tools = tools
+	}
+	c.JSON(http.StatusOK, snapshot)
+}
+
+// QueryLogs searches the daily log files
+// GET /debug/logs/query?type=lock_events&from=2024-01-15T00:00:00Z&to=...&tool_id=...&limit=100&cursor=...
+func (h *DebugHandler) QueryLogs(c *gin.Context) {
+	logType := c.DefaultQuery("type", "lock_events")
+	if !slices.Contains(logger.QueryableLogTypes, logType) {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, []FieldError{{Field: "type", Reason: "must be one of " + strings.Join(logger.QueryableLogTypes, ", ")}})
+		return
+	}
+
+	now := time.Now()
+	from, ok := timeQuery(c, "from", time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()))
+	if !ok {
+		return
+	}
+	to, ok := timeQuery(c, "to", now)
+	if !ok {
+		return
+	}
+	if !from.Before(to) {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, []FieldError{{Field: "from", Reason: "must be before to"}})
+		return
+	}
+
+	statusCode, ok := intQuery(c, "status_code", 0, 100, 599)
+	if !ok {
+		return
+	}
+	limit, ok := intQuery(c, "limit", defaultLogQueryLimit, 1, maxLogQueryLimit)
+	if !ok {
+		return
+	}
+
+	for _, name := range []string{"tool_id", "thread_id", "ticket_id"} {
+		if value := c.Query(name); value != "" && !validateIDParam(c, name, value) {
+			return
+		}
+	}
+
+	result, err := h.logFileManager.Query(c.Request.Context(), logger.LogQuery{
+		LogType:    logType,
+		From:       from,
+		To:         to,
+		ToolID:     c.Query("tool_id"),
+		ThreadID:   c.Query("thread_id"),
+		TicketID:   c.Query("ticket_id"),
+		EventType:  c.Query("event_type"),
+		StatusCode: statusCode,
+		Cursor:     c.Query("cursor"),
+		Limit:      limit,
+	})
+	if err != nil {
+		if errors.Is(err, logger.ErrInvalidCursor) {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, []FieldError{{Field: "cursor", Reason: "invalid cursor"}})
+			return
+		}
+		respondInternalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count":         len(result.Entries),
+		"entries":       result.Entries,
+		"next_cursor":   result.NextCursor,
+		"scanned_lines": result.ScannedLines,
+	})
+}
+
+// GetTicketTimeline returns the logged history of one ticket and, if it is
+// still live, its current state
+// GET /debug/tickets/:id/timeline?from=2024-01-15&to=...
+func (h *DebugHandler) GetTicketTimeline(c *gin.Context) {
+	ticketID := c.Param("id")
+	if !validateIDParam(c, "id", ticketID) {
+		return
+	}
+
+	// Tickets live for minutes, so by default look at yesterday and today
+	now := time.Now()
+	from, ok := timeQuery(c, "from", time.Date(now.Year(), now.Month(), now.Day()-1, 0, 0, 0, 0, now.Location()))
+	if !ok {
+		return
+	}
+	to, ok := timeQuery(c, "to", now.Add(time.Second))
+	if !ok {
+		return
+	}
+	if !from.Before(to) {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, []FieldError{{Field: "from", Reason: "must be before to"}})
+		return
+	}
+
+	live := h.lockManager.GetTicket(ticketID)
+	timeline, err := h.logFileManager.TicketTimeline(c.Request.Context(), ticketID, from, to)
+	if err != nil {
+		respondInternalError(c, err)
+		return
+	}
+
+	if live == nil && len(timeline.Entries) == 0 {
+		respondError(c, http.StatusNotFound, ErrCodeTicketNotFound, nil)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"timeline": timeline,
+		"live":     live,
+	})
+}
+
+// timeQuery parses an optional RFC 3339 timestamp or YYYY-MM-DD date (local
+// midnight) query parameter. On failure it writes an invalid_request response
+// and returns false.
+func timeQuery(c *gin.Context, name string, def time.Time) (time.Time, bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return def, true
+	}
+
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, true
+	}
+	if t, err := time.ParseInLocation("2006-01-02", raw, time.Local); err == nil {
+		return t, true
+	}
+
+	respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, []FieldError{{Field: name, Reason: "must be an RFC 3339 time or YYYY-MM-DD date"}})
+	return time.Time{}, false
+}
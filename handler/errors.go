@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIError is the error body returned by every endpoint
+type APIError struct {
+	Code      string      `json:"error"`   // Machine-readable error code
+	Message   string      `json:"message"` // Human-readable message in the caller's language
+	Details   interface{} `json:"details,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+}
+
+// Error codes
+const (
+	ErrCodeInvalidRequest        = "invalid_request"
+	ErrCodeInvalidConfig         = "invalid_config"
+	ErrCodeToolAlreadyRegistered = "tool_already_registered"
+	ErrCodeToolNotFound          = "tool_not_found"
+	ErrCodeToolOffline           = "tool_offline"
+	ErrCodeSessionMismatch       = "session_mismatch"
+	ErrCodeTicketNotFound        = "ticket_not_found"
+	ErrCodeNotLockHolder         = "not_lock_holder"
+	ErrCodeExtendDisabled        = "extend_disabled"
+	ErrCodeMaxExtendReached      = "max_extend_reached"
+	ErrCodeNoActiveLock          = "no_active_lock"
+	ErrCodeUnauthorized          = "unauthorized"
+	ErrCodeNotFound              = "not_found"
+	ErrCodeMethodNotAllowed      = "method_not_allowed"
+	ErrCodeWebhookNotFound       = "webhook_not_found"
+	ErrCodeWebhookExists         = "webhook_already_exists"
+	ErrCodeInternal              = "internal_error"
+)
+
+// Supported message languages
+const (
+	langVI = "vi"
+	langEN = "en"
+)
+
+// errorMessages holds the localized message for every error code.
+// Vietnamese is the default language.
+var errorMessages = map[string]map[string]string{
+	ErrCodeInvalidRequest: {
+		langVI: "Request không hợp lệ",
+		langEN: "Invalid request",
+	},
+	ErrCodeInvalidConfig: {
+		langVI: "Config không hợp lệ",
+		langEN: "Invalid config",
+	},
+	ErrCodeToolAlreadyRegistered: {
+		langVI: "Tool ID đã được đăng ký và đang online",
+		langEN: "Tool ID is already registered and online",
+	},
+	ErrCodeToolNotFound: {
+		langVI: "Tool chưa được đăng ký",
+		langEN: "Tool is not registered",
+	},
+	ErrCodeToolOffline: {
+		langVI: "Tool không online, cần register hoặc heartbeat",
+		langEN: "Tool is offline, register or send a heartbeat first",
+	},
+	ErrCodeSessionMismatch: {
+		langVI: "Session không khớp, tool đã được đăng ký lại bởi instance khác",
+		langEN: "Session does not match, the tool was registered again by another instance",
+	},
+	ErrCodeTicketNotFound: {
+		langVI: "Ticket không tồn tại hoặc đã bị xóa",
+		langEN: "Ticket does not exist or has been removed",
+	},
+	ErrCodeNotLockHolder: {
+		langVI: "Ticket này không đang giữ lock",
+		langEN: "Ticket is not holding the lock",
+	},
+	ErrCodeExtendDisabled: {
+		langVI: "Lock extend không được bật trong config",
+		langEN: "Lock extend is disabled in config",
+	},
+	ErrCodeMaxExtendReached: {
+		langVI: "Đã extend tối đa số lần cho phép",
+		langEN: "Maximum number of extends reached",
+	},
+	ErrCodeNoActiveLock: {
+		langVI: "Hiện không có lock nào đang được giữ",
+		langEN: "No lock is currently held",
+	},
+	ErrCodeUnauthorized: {
+		langVI: "Cần admin token (hoặc gọi từ localhost nếu chưa cấu hình admin_token)",
+		langEN: "Admin token required (or call from localhost when admin_token is not set)",
+	},
+	ErrCodeNotFound: {
+		langVI: "Endpoint không tồn tại",
+		langEN: "Endpoint not found",
+	},
+	ErrCodeMethodNotAllowed: {
+		langVI: "Method không được hỗ trợ cho endpoint này",
+		langEN: "Method not allowed for this endpoint",
+	},
+	ErrCodeWebhookNotFound: {
+		langVI: "Webhook không tồn tại",
+		langEN: "Webhook does not exist",
+	},
+	ErrCodeWebhookExists: {
+		langVI: "Đã có webhook với tên này",
+		langEN: "A webhook with this name already exists",
+	},
+	ErrCodeInternal: {
+		langVI: "Lỗi hệ thống",
+		langEN: "Internal server error",
+	},
+}
+
+// respondError writes a structured error response and aborts the request
+func respondError(c *gin.Context, status int, code string, details interface{}) {
+	c.AbortWithStatusJSON(status, APIError{
+		Code:      code,
+		Message:   localizedMessage(code, requestLanguage(c)),
+		Details:   details,
+		RequestID: c.GetString("request_id"),
+	})
+}
+
+// respondInternalError writes a 500 response and records err for the request log
+func respondInternalError(c *gin.Context, err error) {
+	_ = c.Error(err)
+	respondError(c, http.StatusInternalServerError, ErrCodeInternal, gin.H{"cause": err.Error()})
+}
+
+// localizedMessage returns the message for code in lang, falling back to Vietnamese
+func localizedMessage(code, lang string) string {
+	messages, ok := errorMessages[code]
+	if !ok {
+		return code
+	}
+	if msg, ok := messages[lang]; ok {
+		return msg
+	}
+	return messages[langVI]
+}
+
+// requestLanguage picks the first supported language from Accept-Language
+func requestLanguage(c *gin.Context) string {
+	for _, part := range strings.Split(c.GetHeader("Accept-Language"), ",") {
+		tag := strings.ToLower(strings.TrimSpace(strings.SplitN(part, ";", 2)[0]))
+		switch {
+		case strings.HasPrefix(tag, langEN):
+			return langEN
+		case strings.HasPrefix(tag, langVI):
+			return langVI
+		}
+	}
+	return langVI
+}
+
+// RegisterErrorHandlers makes unknown routes and methods return structured errors
+func RegisterErrorHandlers(router *gin.Engine) {
+	router.HandleMethodNotAllowed = true
+	router.NoRoute(func(c *gin.Context) {
+		respondError(c, http.StatusNotFound, ErrCodeNotFound, gin.H{"path": c.Request.URL.Path})
+	})
+	router.NoMethod(func(c *gin.Context) {
+		respondError(c, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, gin.H{"method": c.Request.Method})
+	})
+}
+
+// Recovery returns a panic recovery middleware that responds with a structured error
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecovery(func(c *gin.Context, recovered interface{}) {
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, nil)
+	})
+}
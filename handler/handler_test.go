@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"clipboard-controller/config"
+	"clipboard-controller/logger"
+	"clipboard-controller/model"
+	"clipboard-controller/service"
+	"clipboard-controller/webhook"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	os.Exit(m.Run())
+}
+
+// testServer is the full router wired as in main, on a temporary log
+// directory and config file
+type testServer struct {
+	router *gin.Engine
+	config *config.Store
+	logDir string
+}
+
+// newTestServer builds the server; configure, if given, adjusts the
+// default config first
+func newTestServer(t *testing.T, configure ...func(*config.Config)) *testServer {
+	t.Helper()
+
+	dir := t.TempDir()
+	cfg := config.Default()
+	for _, fn := range configure {
+		fn(cfg)
+	}
+	cfg.LogDir = filepath.Join(dir, "logs")
+	configPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(configPath, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	store := config.NewStore(cfg, config.Sources{})
+	lfm, err := logger.NewLogFileManager(cfg.LogDir, cfg.LogRetentionDays, cfg.LogQueueSize, cfg.LogOverflowPolicy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	el := logger.NewEventLogger(lfm, store)
+
+	toolRegistry := service.NewToolRegistry(store)
+	lockManager := service.NewLockManager(store, toolRegistry)
+	toolLifecycle := service.NewToolLifecycle(toolRegistry, lockManager)
+	toolRegistry.SetEventLogger(el)
+	lockManager.SetEventLogger(el)
+	bgJobs := service.NewBackgroundJobs(store, toolRegistry, toolLifecycle, lockManager)
+	bgJobs.Start()
+
+	reloader := config.NewReloader(configPath, store, nil)
+	alertManager := logger.NewAlertManager(el, store, logger.AlertSources{
+		QueueLength:  lockManager.QueueLength,
+		OfflineTools: func() []string { return nil },
+	})
+	dispatcher, err := webhook.NewDispatcher(store, webhook.DeadLetterPath(cfg.LogDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lockEvents, unsubscribeLocks := el.Subscribe(64)
+	toolEvents, unsubscribeTools := el.SubscribeTools(64)
+	dispatcher.Start(lockEvents, toolEvents)
+
+	router := gin.New()
+	router.Use(Recovery())
+	RegisterErrorHandlers(router)
+	startTime := time.Now()
+	RegisterHealthHandler(router, "test", &startTime)
+	RegisterToolHandler(router, toolRegistry, toolLifecycle, store, el)
+	RegisterToolsHandler(router, toolRegistry, lockManager, el)
+	RegisterLockHandler(router, lockManager, store)
+	RegisterConfigHandler(router, store, reloader, el)
+	RegisterDebugHandler(router, el, lfm, lockManager)
+	RegisterReportHandler(router, lfm, store)
+	RegisterAlertHandler(router, alertManager)
+	RegisterWebhookHandler(router, dispatcher, store, reloader, el)
+	RegisterAdminHandler(router, lockManager, toolLifecycle, store, el)
+	dashboard := RegisterDashboardHandler(router, toolRegistry, lockManager, el, store)
+	RegisterOpenAPIHandler(router, "test")
+
+	t.Cleanup(func() {
+		dashboard.Close()
+		unsubscribeLocks()
+		unsubscribeTools()
+		dispatcher.Stop()
+		alertManager.Close()
+		bgJobs.Stop()
+		lfm.Close()
+	})
+
+	return &testServer{router: router, config: store, logDir: cfg.LogDir}
+}
+
+// do sends a request from a loopback client and returns the response
+func (s *testServer) do(method, path string, body interface{}) *httptest.ResponseRecorder {
+	return s.doFrom("127.0.0.1:40000", "", method, path, body)
+}
+
+// doFrom sends a request from remoteAddr with the admin token, if not
+// empty, and returns the response
+func (s *testServer) doFrom(remoteAddr, token, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var data []byte
+	if body != nil {
+		data, _ = json.Marshal(body)
+	}
+
+	req := httptest.NewRequest(method, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	req.RemoteAddr = remoteAddr
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+// waitAudit waits for an audit entry with action to be written and returns it
+func (s *testServer) waitAudit(t *testing.T, action string) model.AuditLog {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		files, _ := filepath.Glob(filepath.Join(s.logDir, logger.AuditLogType, "*.jsonl"))
+		for _, path := range files {
+			data, _ := os.ReadFile(path)
+			for _, line := range bytes.Split(data, []byte("\n")) {
+				var entry model.AuditLog
+				if json.Unmarshal(line, &entry) == nil && entry.Action == action {
+					return entry
+				}
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("no %s audit entry written", action)
+	return model.AuditLog{}
+}
+
+// decode unmarshals a JSON response body
+func decode(t *testing.T, w *httptest.ResponseRecorder) map[string]interface{} {
+	t.Helper()
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Errorf("invalid JSON response %q: %v", w.Body.String(), err)
+	}
+	return body
+}
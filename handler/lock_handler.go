@@ -12,7 +12,7 @@ import (
 )
 
 // RegisterLockHandler registers lock management endpoints
-func RegisterLockHandler(router *gin.Engine, lm *service.LockManager, cfg *config.Config) {
+func RegisterLockHandler(router *gin.Engine, lm *service.LockManager, cfg *config.Store) {
 	lock := router.Group("/lock")
 	{
 		lock.POST("/request", requestLock(lm, cfg))
@@ -25,54 +25,45 @@ func RegisterLockHandler(router *gin.Engine, lm *service.LockManager, cfg *confi
 
 // LockRequest represents the request body for lock request
 type LockRequest struct {
-	ToolID   string `json:"tool_id" binding:"required"`
-	ThreadID string `json:"thread_id" binding:"required"`
+	ToolID    string `json:"tool_id" binding:"required,max=64,id"`
+	ThreadID  string `json:"thread_id" binding:"required,max=64,id"`
+	SessionID string `json:"session_id" binding:"required,max=64,id"`
 }
 
 // ReleaseRequest represents the request body for lock release
 type ReleaseRequest struct {
-	TicketID string `json:"ticket_id" binding:"required"`
+	TicketID  string `json:"ticket_id" binding:"required,max=64,id"`
+	SessionID string `json:"session_id" binding:"required,max=64,id"`
 }
 
 // ExtendRequest represents the request body for lock extend
 type ExtendRequest struct {
-	TicketID string `json:"ticket_id" binding:"required"`
+	TicketID  string `json:"ticket_id" binding:"required,max=64,id"`
+	SessionID string `json:"session_id" binding:"required,max=64,id"`
 }
 
-func requestLock(lm *service.LockManager, cfg *config.Config) gin.HandlerFunc {
+func requestLock(lm *service.LockManager, cfg *config.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req LockRequest
-		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error":   "invalid_request",
-				"message": "tool_id and thread_id are required",
-			})
+		if !bindJSON(c, &req) {
 			return
 		}
 
-		ticket, position, err := lm.RequestLock(req.ToolID, req.ThreadID)
+		ticket, position, err := lm.RequestLock(c.Request.Context(), req.ToolID, req.ThreadID, req.SessionID)
 		if err != nil {
-			if errors.Is(err, service.ErrToolOffline) {
-				c.JSON(http.StatusBadRequest, gin.H{
-					"error":   "tool_offline",
-					"message": "Tool không online, cần register hoặc heartbeat",
-				})
-				return
-			}
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error":   "internal_error",
-				"message": err.Error(),
-			})
+			respondToolError(c, err)
 			return
 		}
 
 		// Calculate ticket expiry
-		ticketExpiresAt := ticket.RequestedAt.Add(time.Duration(cfg.TicketTTL) * time.Second)
+		snapshot := cfg.Get()
+		settings := snapshot.ForTool(req.ToolID)
+		ticketExpiresAt := ticket.RequestedAt.Add(time.Duration(settings.TicketTTL) * time.Second)
 
 		response := gin.H{
 			"ticket_id":         ticket.TicketID,
 			"position":          position,
-			"poll_interval":     cfg.PollInterval,
+			"poll_interval":     snapshot.PollInterval,
 			"ticket_expires_at": ticketExpiresAt.Format(time.RFC3339),
 		}
 
@@ -92,30 +83,28 @@ func requestLock(lm *service.LockManager, cfg *config.Config) gin.HandlerFunc {
 	}
 }
 
-func checkLock(lm *service.LockManager, cfg *config.Config) gin.HandlerFunc {
+func checkLock(lm *service.LockManager, cfg *config.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ticketID := c.Query("ticket_id")
-		if ticketID == "" {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error":   "invalid_request",
-				"message": "ticket_id query parameter is required",
-			})
+		if !validateIDParam(c, "ticket_id", ticketID) {
+			return
+		}
+		sessionID := c.Query("session_id")
+		if !validateIDParam(c, "session_id", sessionID) {
 			return
 		}
 
-		ticket, position, err := lm.CheckLock(ticketID)
+		ticket, position, err := lm.CheckLock(c.Request.Context(), ticketID, sessionID)
 		if err != nil {
 			if errors.Is(err, service.ErrTicketNotFound) {
-				c.JSON(http.StatusNotFound, gin.H{
-					"error":   "ticket_not_found",
-					"message": "Ticket không tồn tại hoặc đã bị xóa",
-				})
+				respondError(c, http.StatusNotFound, ErrCodeTicketNotFound, nil)
+				return
+			}
+			if errors.Is(err, service.ErrSessionMismatch) {
+				respondError(c, http.StatusConflict, ErrCodeSessionMismatch, nil)
 				return
 			}
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error":   "internal_error",
-				"message": err.Error(),
-			})
+			respondInternalError(c, err)
 			return
 		}
 
@@ -148,34 +137,25 @@ func checkLock(lm *service.LockManager, cfg *config.Config) gin.HandlerFunc {
 func releaseLock(lm *service.LockManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req ReleaseRequest
-		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error":   "invalid_request",
-				"message": "ticket_id is required",
-			})
+		if !bindJSON(c, &req) {
 			return
 		}
 
-		ticket, err := lm.ReleaseLock(req.TicketID)
+		ticket, err := lm.ReleaseLock(c.Request.Context(), req.TicketID, req.SessionID)
 		if err != nil {
 			if errors.Is(err, service.ErrTicketNotFound) {
-				c.JSON(http.StatusNotFound, gin.H{
-					"error":   "ticket_not_found",
-					"message": "Ticket không tồn tại hoặc đã bị xóa",
-				})
+				respondError(c, http.StatusNotFound, ErrCodeTicketNotFound, nil)
+				return
+			}
+			if errors.Is(err, service.ErrSessionMismatch) {
+				respondError(c, http.StatusConflict, ErrCodeSessionMismatch, nil)
 				return
 			}
 			if errors.Is(err, service.ErrNotLockHolder) {
-				c.JSON(http.StatusBadRequest, gin.H{
-					"error":   "not_lock_holder",
-					"message": "Ticket này không đang giữ lock",
-				})
+				respondError(c, http.StatusBadRequest, ErrCodeNotLockHolder, nil)
 				return
 			}
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error":   "internal_error",
-				"message": err.Error(),
-			})
+			respondInternalError(c, err)
 			return
 		}
 
@@ -191,51 +171,36 @@ func releaseLock(lm *service.LockManager) gin.HandlerFunc {
 	}
 }
 
-func extendLock(lm *service.LockManager, cfg *config.Config) gin.HandlerFunc {
+func extendLock(lm *service.LockManager, cfg *config.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req ExtendRequest
-		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error":   "invalid_request",
-				"message": "ticket_id is required",
-			})
+		if !bindJSON(c, &req) {
 			return
 		}
 
-		ticket, err := lm.ExtendLock(req.TicketID)
+		ticket, err := lm.ExtendLock(c.Request.Context(), req.TicketID, req.SessionID)
 		if err != nil {
 			if errors.Is(err, service.ErrExtendDisabled) {
-				c.JSON(http.StatusBadRequest, gin.H{
-					"error":   "extend_disabled",
-					"message": "Lock extend không được bật trong config",
-				})
+				respondError(c, http.StatusBadRequest, ErrCodeExtendDisabled, nil)
 				return
 			}
 			if errors.Is(err, service.ErrTicketNotFound) {
-				c.JSON(http.StatusNotFound, gin.H{
-					"error":   "ticket_not_found",
-					"message": "Ticket không tồn tại hoặc đã bị xóa",
-				})
+				respondError(c, http.StatusNotFound, ErrCodeTicketNotFound, nil)
+				return
+			}
+			if errors.Is(err, service.ErrSessionMismatch) {
+				respondError(c, http.StatusConflict, ErrCodeSessionMismatch, nil)
 				return
 			}
 			if errors.Is(err, service.ErrNotLockHolder) {
-				c.JSON(http.StatusBadRequest, gin.H{
-					"error":   "not_lock_holder",
-					"message": "Ticket này không đang giữ lock",
-				})
+				respondError(c, http.StatusBadRequest, ErrCodeNotLockHolder, nil)
 				return
 			}
 			if errors.Is(err, service.ErrMaxExtendReached) {
-				c.JSON(http.StatusBadRequest, gin.H{
-					"error":   "max_extend_reached",
-					"message": "Đã extend tối đa số lần cho phép",
-				})
+				respondError(c, http.StatusBadRequest, ErrCodeMaxExtendReached, nil)
 				return
 			}
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error":   "internal_error",
-				"message": err.Error(),
-			})
+			respondInternalError(c, err)
 			return
 		}
 
@@ -243,7 +208,7 @@ func extendLock(lm *service.LockManager, cfg *config.Config) gin.HandlerFunc {
 			"status":           "extended",
 			"new_expires_at":   ticket.ExpiresAt.Format(time.RFC3339),
 			"extend_count":     ticket.ExtendCount,
-			"extend_remaining": cfg.LockExtendMax - ticket.ExtendCount,
+			"extend_remaining": cfg.Get().ForTool(ticket.ToolID).LockExtendMax - ticket.ExtendCount,
 		})
 
 		// Set context for logging
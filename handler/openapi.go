@@ -0,0 +1,336 @@
+package handler
+
+import (
+	"embed"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed docs/index.html
+var docsFS embed.FS
+
+// schema is a JSON Schema object as used by OpenAPI 3
+type schema map[string]interface{}
+
+// paramDoc documents a query or path parameter
+type paramDoc struct {
+	Name        string
+	In          string // "query" or "path"
+	Required    bool
+	Description string
+	Schema      schema
+}
+
+// routeDoc documents one registered route
+type routeDoc struct {
+	Method      string
+	Path        string // gin syntax, e.g. /debug/tickets/:id/timeline
+	Tag         string
+	Summary     string
+	Params      []paramDoc
+	Request     interface{} // Go value whose type describes the JSON body, or a schema
+	Response    interface{} // Go value whose type describes the 200 body, or a schema
+	Errors      []string    // Error codes the route can return
+	ContentType string      // Response content type, defaults to application/json
+}
+
+// errorStatus maps every error code to its HTTP status
+var errorStatus = map[string]int{
+	ErrCodeInvalidRequest:        http.StatusBadRequest,
+	ErrCodeInvalidConfig:         http.StatusBadRequest,
+	ErrCodeToolAlreadyRegistered: http.StatusConflict,
+	ErrCodeToolNotFound:          http.StatusNotFound,
+	ErrCodeToolOffline:           http.StatusBadRequest,
+	ErrCodeSessionMismatch:       http.StatusConflict,
+	ErrCodeTicketNotFound:        http.StatusNotFound,
+	ErrCodeNotLockHolder:         http.StatusBadRequest,
+	ErrCodeExtendDisabled:        http.StatusBadRequest,
+	ErrCodeMaxExtendReached:      http.StatusBadRequest,
+	ErrCodeNoActiveLock:          http.StatusNotFound,
+	ErrCodeUnauthorized:          http.StatusUnauthorized,
+	ErrCodeNotFound:              http.StatusNotFound,
+	ErrCodeMethodNotAllowed:      http.StatusMethodNotAllowed,
+	ErrCodeWebhookNotFound:       http.StatusNotFound,
+	ErrCodeWebhookExists:         http.StatusConflict,
+	ErrCodeInternal:              http.StatusInternalServerError,
+}
+
+// RegisterOpenAPIHandler serves the OpenAPI document and the docs UI
+func RegisterOpenAPIHandler(router *gin.Engine, version string) {
+	router.GET("/openapi.json", func(c *gin.Context) {
+		c.JSON(http.StatusOK, buildOpenAPISpec(version))
+	})
+	router.GET("/docs", func(c *gin.Context) {
+		page, _ := docsFS.ReadFile("docs/index.html")
+		c.Data(http.StatusOK, "text/html; charset=utf-8", page)
+	})
+}
+
+// UndocumentedRoutes returns the registered routes that have no OpenAPI entry
+func UndocumentedRoutes(routes gin.RoutesInfo) []string {
+	documented := make(map[string]bool, len(apiRoutes))
+	for _, doc := range apiRoutes {
+		documented[doc.Method+" "+doc.Path] = true
+	}
+
+	var missing []string
+	for _, route := range routes {
+		if !documented[route.Method+" "+route.Path] {
+			missing = append(missing, route.Method+" "+route.Path)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// buildOpenAPISpec assembles the OpenAPI 3 document from apiRoutes
+func buildOpenAPISpec(version string) map[string]interface{} {
+	paths := make(map[string]map[string]interface{})
+
+	for _, doc := range apiRoutes {
+		path := openAPIPath(doc.Path)
+		if paths[path] == nil {
+			paths[path] = make(map[string]interface{})
+		}
+		paths[path][strings.ToLower(doc.Method)] = operation(doc)
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "Clipboard Controller API",
+			"version":     version,
+			"description": "HTTP API quản lý quyền truy cập clipboard cho các automation tool chạy đa luồng.",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": map[string]interface{}{
+				"APIError": schemaOf(APIError{}),
+			},
+		},
+	}
+}
+
+func operation(doc routeDoc) map[string]interface{} {
+	op := map[string]interface{}{
+		"summary":     doc.Summary,
+		"tags":        []string{doc.Tag},
+		"operationId": strings.ToLower(doc.Method) + strings.NewReplacer("/", "_", ":", "", ".", "_", "-", "_").Replace(doc.Path),
+	}
+
+	if len(doc.Params) > 0 {
+		params := make([]map[string]interface{}, 0, len(doc.Params))
+		for _, p := range doc.Params {
+			s := p.Schema
+			if s == nil {
+				s = schema{"type": "string"}
+			}
+			params = append(params, map[string]interface{}{
+				"name":        p.Name,
+				"in":          p.In,
+				"required":    p.Required || p.In == "path",
+				"description": p.Description,
+				"schema":      s,
+			})
+		}
+		op["parameters"] = params
+	}
+
+	if doc.Request != nil {
+		op["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": schemaOf(doc.Request)},
+			},
+		}
+	}
+
+	contentType := doc.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	okResponse := map[string]interface{}{"description": "OK"}
+	if doc.Response != nil {
+		okResponse["content"] = map[string]interface{}{
+			contentType: map[string]interface{}{"schema": schemaOf(doc.Response)},
+		}
+	}
+	responses := map[string]interface{}{"200": okResponse}
+
+	// Group error codes by status
+	codes := append([]string{}, doc.Errors...)
+	codes = append(codes, ErrCodeInternal)
+	byStatus := make(map[int][]string)
+	for _, code := range codes {
+		status := errorStatus[code]
+		byStatus[status] = append(byStatus[status], code)
+	}
+	for status, codes := range byStatus {
+		responses[strconv.Itoa(status)] = map[string]interface{}{
+			"description": strings.Join(codes, ", "),
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{
+					"schema": map[string]interface{}{"$ref": "#/components/schemas/APIError"},
+				},
+			},
+		}
+	}
+	op["responses"] = responses
+
+	return op
+}
+
+// openAPIPath converts gin path params (:id, *path) to OpenAPI syntax ({id})
+func openAPIPath(path string) string {
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") || strings.HasPrefix(part, "*") {
+			parts[i] = "{" + part[1:] + "}"
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// schemaOf returns v itself if it is already a schema, otherwise a schema
+// derived from its Go type
+func schemaOf(v interface{}) schema {
+	if s, ok := v.(schema); ok {
+		return s
+	}
+	return typeSchema(reflect.TypeOf(v))
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// typeSchema derives a JSON schema from a Go type using json and binding tags
+func typeSchema(t reflect.Type) schema {
+	if t == nil {
+		return schema{}
+	}
+	if t == timeType {
+		return schema{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return typeSchema(t.Elem())
+	case reflect.String:
+		return schema{"type": "string"}
+	case reflect.Bool:
+		return schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return schema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return schema{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return schema{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return schema{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Interface:
+		return schema{}
+	case reflect.Struct:
+		properties := make(map[string]interface{})
+		var required []string
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				continue
+			}
+			if name == "" && field.Anonymous {
+				// Embedded struct fields are flattened by encoding/json
+				embedded := typeSchema(field.Type)
+				if props, ok := embedded["properties"].(map[string]interface{}); ok {
+					for k, v := range props {
+						properties[k] = v
+					}
+					continue
+				}
+			}
+			if name == "" {
+				name = field.Name
+			}
+
+			fieldSchema := typeSchema(field.Type)
+		rules:
+			for _, rule := range strings.Split(field.Tag.Get("binding"), ",") {
+				switch {
+				case rule == "required":
+					required = append(required, name)
+				case rule == "dive":
+					// Rules after dive apply to the elements, not the field
+					break rules
+				case strings.HasPrefix(rule, "max="):
+					if n, err := strconv.Atoi(strings.TrimPrefix(rule, "max=")); err == nil {
+						fieldSchema[limitKeyword(field.Type, "max")] = n
+					}
+				case strings.HasPrefix(rule, "min="):
+					if n, err := strconv.Atoi(strings.TrimPrefix(rule, "min=")); err == nil {
+						fieldSchema[limitKeyword(field.Type, "min")] = n
+					}
+				case rule == "id":
+					fieldSchema["pattern"] = idPattern.String()
+				}
+			}
+			properties[name] = fieldSchema
+		}
+		s := schema{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			s["required"] = required
+		}
+		return s
+	}
+
+	return schema{}
+}
+
+// limitKeyword returns the JSON schema keyword for a min/max binding rule on
+// a field of type t (maxLength, maxItems, maxProperties or maximum)
+func limitKeyword(t reflect.Type, bound string) string {
+	switch t.Kind() {
+	case reflect.String:
+		return bound + "Length"
+	case reflect.Slice, reflect.Array:
+		return bound + "Items"
+	case reflect.Map:
+		return bound + "Properties"
+	}
+	if bound == "max" {
+		return "maximum"
+	}
+	return "minimum"
+}
+
+// Schema helpers for responses that are built as gin.H
+
+func objectSchema(properties map[string]schema) schema {
+	props := make(map[string]interface{}, len(properties))
+	for k, v := range properties {
+		props[k] = v
+	}
+	return schema{"type": "object", "properties": props}
+}
+
+func arraySchema(items schema) schema {
+	return schema{"type": "array", "items": items}
+}
+
+var (
+	stringSchema   = schema{"type": "string"}
+	integerSchema  = schema{"type": "integer"}
+	numberSchema   = schema{"type": "number"}
+	booleanSchema  = schema{"type": "boolean"}
+	dateTimeSchema = schema{"type": "string", "format": "date-time"}
+	anySchema      = schema{}
+	freeFormSchema = schema{"type": "object", "additionalProperties": true}
+)
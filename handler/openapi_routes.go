@@ -0,0 +1,524 @@
+package handler
+
+import (
+	"clipboard-controller/config"
+	"clipboard-controller/logger"
+	"clipboard-controller/model"
+	"clipboard-controller/webhook"
+)
+
+// Reusable parameter docs
+var (
+	toolIDQuery     = paramDoc{Name: "tool_id", In: "query", Required: true, Description: "Tool ID"}
+	ticketIDQuery   = paramDoc{Name: "ticket_id", In: "query", Required: true, Description: "Ticket ID trả về từ /lock/request"}
+	sessionIDQuery  = paramDoc{Name: "session_id", In: "query", Required: true, Description: "Session ID trả về từ /tool/register"}
+	webhookNamePath = paramDoc{Name: "name", In: "path", Description: "Tên webhook"}
+)
+
+// webhookUpdateResponse is returned by the webhook create, update and delete
+// endpoints
+var webhookUpdateResponse = objectSchema(map[string]schema{
+	"status":        stringSchema,
+	"webhooks":      schemaOf([]config.WebhookConfig{}),
+	"persisted":     booleanSchema,
+	"persist_error": stringSchema,
+})
+
+// apiRoutes documents every route registered by the Register*Handler
+// functions. A route registered without an entry here is reported at
+// startup (see UndocumentedRoutes).
+var apiRoutes = []routeDoc{
+	// Health
+	{
+		Method:  "GET",
+		Path:    "/health",
+		Tag:     "health",
+		Summary: "Kiểm tra server còn hoạt động",
+		Response: objectSchema(map[string]schema{
+			"status":         stringSchema,
+			"uptime_seconds": integerSchema,
+			"version":        stringSchema,
+		}),
+	},
+
+	// Tool management
+	{
+		Method:  "POST",
+		Path:    "/tool/register",
+		Tag:     "tool",
+		Summary: "Đăng ký tool",
+		Request: RegisterRequest{},
+		Response: objectSchema(map[string]schema{
+			"tool_id":          stringSchema,
+			"session_id":       stringSchema,
+			"status":           stringSchema,
+			"released_tickets": arraySchema(stringSchema),
+			"config":           freeFormSchema,
+		}),
+		Errors: []string{ErrCodeInvalidRequest, ErrCodeToolAlreadyRegistered},
+	},
+	{
+		Method:  "POST",
+		Path:    "/tool/heartbeat",
+		Tag:     "tool",
+		Summary: "Báo tool còn hoạt động",
+		Request: HeartbeatRequest{},
+		Response: objectSchema(map[string]schema{
+			"status":                stringSchema,
+			"next_heartbeat_before": dateTimeSchema,
+			"tickets": arraySchema(objectSchema(map[string]schema{
+				"ticket_id":    stringSchema,
+				"tool_id":      stringSchema,
+				"thread_id":    stringSchema,
+				"requested_at": dateTimeSchema,
+				"status":       stringSchema,
+				"position":     integerSchema,
+				"granted_at":   dateTimeSchema,
+				"expires_at":   dateTimeSchema,
+				"extend_count": integerSchema,
+			})),
+		}),
+		Errors: []string{ErrCodeInvalidRequest, ErrCodeToolNotFound, ErrCodeToolOffline, ErrCodeSessionMismatch},
+	},
+	{
+		Method:  "POST",
+		Path:    "/tool/unregister",
+		Tag:     "tool",
+		Summary: "Hủy đăng ký tool",
+		Request: UnregisterRequest{},
+		Response: objectSchema(map[string]schema{
+			"status":           stringSchema,
+			"released_tickets": arraySchema(stringSchema),
+		}),
+		Errors: []string{ErrCodeInvalidRequest, ErrCodeToolNotFound, ErrCodeSessionMismatch},
+	},
+	{
+		Method:  "GET",
+		Path:    "/tool/status",
+		Tag:     "tool",
+		Summary: "Xem trạng thái tool",
+		Params:  []paramDoc{toolIDQuery},
+		Response: objectSchema(map[string]schema{
+			"tool_id":                 stringSchema,
+			"status":                  stringSchema,
+			"registered_at":           dateTimeSchema,
+			"last_heartbeat":          dateTimeSchema,
+			"next_heartbeat_deadline": dateTimeSchema,
+			"hostname":                stringSchema,
+			"pid":                     integerSchema,
+			"version":                 stringSchema,
+			"machine":                 stringSchema,
+			"owner":                   stringSchema,
+			"tool_type":               stringSchema,
+			"thread_count":            integerSchema,
+			"labels":                  schema{"type": "object", "additionalProperties": stringSchema},
+		}),
+		Errors: []string{ErrCodeInvalidRequest, ErrCodeToolNotFound},
+	},
+	{
+		Method:  "GET",
+		Path:    "/tool/list",
+		Tag:     "tool",
+		Summary: "Danh sách tool, lọc theo label",
+		Params: []paramDoc{
+			{Name: "label", In: "query", Description: "Lọc theo label dạng key=value, lặp lại để lọc nhiều label (AND)", Schema: arraySchema(stringSchema)},
+		},
+		Response: objectSchema(map[string]schema{
+			"count": integerSchema,
+			"tools": schemaOf([]model.Tool{}),
+		}),
+		Errors: []string{ErrCodeInvalidRequest},
+	},
+
+	{
+		Method:  "GET",
+		Path:    "/tools",
+		Tag:     "tool",
+		Summary: "Danh sách tool kèm ticket đang chờ, lock đang giữ và thống kê trong ngày",
+		Params: []paramDoc{
+			{Name: "status", In: "query", Description: "online hoặc offline"},
+			{Name: "label", In: "query", Description: "Lọc theo label dạng key=value, lặp lại để lọc nhiều label (AND)", Schema: arraySchema(stringSchema)},
+			{Name: "sort", In: "query", Description: "tool_id, status, registered_at, last_heartbeat, tickets_waiting, locks_today, avg_wait_ms, avg_hold_ms; thêm - phía trước để sắp xếp giảm dần"},
+			{Name: "limit", In: "query", Description: "Số tool mỗi trang (mặc định 50, tối đa 500)", Schema: integerSchema},
+			{Name: "offset", In: "query", Description: "Bỏ qua bao nhiêu tool đầu", Schema: integerSchema},
+		},
+		Response: objectSchema(map[string]schema{
+			"total":  integerSchema,
+			"count":  integerSchema,
+			"offset": integerSchema,
+			"limit":  integerSchema,
+			"tools":  schemaOf([]ToolSummary{}),
+		}),
+		Errors: []string{ErrCodeInvalidRequest},
+	},
+
+	// Lock management
+	{
+		Method:  "POST",
+		Path:    "/lock/request",
+		Tag:     "lock",
+		Summary: "Yêu cầu lock, trả về ticket để poll",
+		Request: LockRequest{},
+		Response: objectSchema(map[string]schema{
+			"ticket_id":         stringSchema,
+			"position":          integerSchema,
+			"poll_interval":     integerSchema,
+			"ticket_expires_at": dateTimeSchema,
+			"status":            stringSchema,
+			"expires_at":        dateTimeSchema,
+			"lock_duration_ms":  integerSchema,
+		}),
+		Errors: []string{ErrCodeInvalidRequest, ErrCodeToolOffline, ErrCodeSessionMismatch},
+	},
+	{
+		Method:  "GET",
+		Path:    "/lock/check",
+		Tag:     "lock",
+		Summary: "Kiểm tra trạng thái ticket",
+		Params:  []paramDoc{ticketIDQuery, sessionIDQuery},
+		Response: objectSchema(map[string]schema{
+			"status":            stringSchema,
+			"position":          integerSchema,
+			"estimated_wait_ms": integerSchema,
+			"expires_at":        dateTimeSchema,
+			"lock_duration_ms":  integerSchema,
+			"reason":            stringSchema,
+		}),
+		Errors: []string{ErrCodeInvalidRequest, ErrCodeTicketNotFound, ErrCodeSessionMismatch},
+	},
+	{
+		Method:  "POST",
+		Path:    "/lock/release",
+		Tag:     "lock",
+		Summary: "Trả lock sau khi paste xong",
+		Request: ReleaseRequest{},
+		Response: objectSchema(map[string]schema{
+			"status":           stringSchema,
+			"held_duration_ms": integerSchema,
+		}),
+		Errors: []string{ErrCodeInvalidRequest, ErrCodeTicketNotFound, ErrCodeSessionMismatch, ErrCodeNotLockHolder},
+	},
+	{
+		Method:  "POST",
+		Path:    "/lock/extend",
+		Tag:     "lock",
+		Summary: "Gia hạn thời gian giữ lock",
+		Request: ExtendRequest{},
+		Response: objectSchema(map[string]schema{
+			"status":           stringSchema,
+			"new_expires_at":   dateTimeSchema,
+			"extend_count":     integerSchema,
+			"extend_remaining": integerSchema,
+		}),
+		Errors: []string{ErrCodeInvalidRequest, ErrCodeExtendDisabled, ErrCodeTicketNotFound, ErrCodeSessionMismatch, ErrCodeNotLockHolder, ErrCodeMaxExtendReached},
+	},
+	{
+		Method:   "GET",
+		Path:     "/lock/status",
+		Tag:      "lock",
+		Summary:  "Xem lock hiện tại và queue",
+		Response: freeFormSchema,
+	},
+
+	// Config
+	{
+		Method:   "GET",
+		Path:     "/config",
+		Tag:      "config",
+		Summary:  "Xem config hiện tại",
+		Response: freeFormSchema,
+	},
+	{
+		Method:  "PATCH",
+		Path:    "/config",
+		Tag:     "config",
+		Summary: "Cập nhật các key runtime của config (admin)",
+		Request: freeFormSchema,
+		Response: objectSchema(map[string]schema{
+			"status":        stringSchema,
+			"config":        freeFormSchema,
+			"persisted":     booleanSchema,
+			"persist_error": stringSchema,
+		}),
+		Errors: []string{ErrCodeInvalidRequest, ErrCodeInvalidConfig, ErrCodeUnauthorized},
+	},
+	{
+		Method:  "GET",
+		Path:    "/config/sources",
+		Tag:     "config",
+		Summary: "Xem nguồn (default/file/env/flag/runtime) của từng giá trị config",
+		Response: objectSchema(map[string]schema{
+			"sources": schemaOf([]config.SourceInfo{}),
+		}),
+	},
+	{
+		Method:  "POST",
+		Path:    "/config/reload",
+		Tag:     "config",
+		Summary: "Đọc lại file config (admin)",
+		Response: objectSchema(map[string]schema{
+			"status":  stringSchema,
+			"path":    stringSchema,
+			"changes": schemaOf([]config.Change{}),
+		}),
+		Errors: []string{ErrCodeInvalidConfig, ErrCodeUnauthorized},
+	},
+
+	// Debug
+	{
+		Method:  "GET",
+		Path:    "/debug/logs/recent",
+		Tag:     "debug",
+		Summary: "Các lock event gần đây (memory buffer)",
+		Params: []paramDoc{
+			{Name: "limit", In: "query", Description: "Số event tối đa (mặc định 50, tối đa 100)", Schema: integerSchema},
+		},
+		Response: objectSchema(map[string]schema{
+			"count":  integerSchema,
+			"events": schemaOf([]model.LockEventLog{}),
+		}),
+	},
+	{
+		Method:   "GET",
+		Path:     "/debug/logs/stats",
+		Tag:      "debug",
+		Summary:  "Thống kê log files",
+		Response: freeFormSchema,
+	},
+	{
+		Method:  "GET",
+		Path:    "/debug/logs/query",
+		Tag:     "debug",
+		Summary: "Tìm kiếm trong log files theo khoảng thời gian, có phân trang",
+		Params: []paramDoc{
+			{Name: "type", In: "query", Description: "requests, lock_events (mặc định), tool_events, metrics hoặc audit"},
+			{Name: "from", In: "query", Description: "Bắt đầu (RFC 3339 hoặc YYYY-MM-DD), mặc định đầu ngày hôm nay"},
+			{Name: "to", In: "query", Description: "Kết thúc, không bao gồm (RFC 3339 hoặc YYYY-MM-DD), mặc định bây giờ"},
+			{Name: "tool_id", In: "query", Description: "Lọc theo tool"},
+			{Name: "thread_id", In: "query", Description: "Lọc theo thread"},
+			{Name: "ticket_id", In: "query", Description: "Lọc theo ticket"},
+			{Name: "event_type", In: "query", Description: "Lọc theo loại event"},
+			{Name: "status_code", In: "query", Description: "Lọc theo HTTP status (type=requests)", Schema: integerSchema},
+			{Name: "limit", In: "query", Description: "Số entry tối đa mỗi trang (mặc định 100, tối đa 1000)", Schema: integerSchema},
+			{Name: "cursor", In: "query", Description: "next_cursor của trang trước"},
+		},
+		Response: objectSchema(map[string]schema{
+			"count":         integerSchema,
+			"entries":       arraySchema(freeFormSchema),
+			"next_cursor":   stringSchema,
+			"scanned_lines": integerSchema,
+		}),
+		Errors: []string{ErrCodeInvalidRequest},
+	},
+	{
+		Method:  "GET",
+		Path:    "/debug/tickets/:id/timeline",
+		Tag:     "debug",
+		Summary: "Dựng lại lịch sử của một ticket từ request log và lock event",
+		Params: []paramDoc{
+			{Name: "id", In: "path", Description: "Ticket ID"},
+			{Name: "from", In: "query", Description: "Bắt đầu (RFC 3339 hoặc YYYY-MM-DD), mặc định đầu ngày hôm qua"},
+			{Name: "to", In: "query", Description: "Kết thúc, không bao gồm, mặc định bây giờ"},
+		},
+		Response: objectSchema(map[string]schema{
+			"timeline": schemaOf(logger.TicketTimeline{}),
+			"live":     freeFormSchema,
+		}),
+		Errors: []string{ErrCodeInvalidRequest, ErrCodeTicketNotFound},
+	},
+	{
+		Method:  "GET",
+		Path:    "/debug/metrics/distributions",
+		Tag:     "debug",
+		Summary: "Percentile của wait time, hold time, queue length và số lần extend (phút hiện tại, hôm nay, theo tool)",
+		Params: []paramDoc{
+			{Name: "tool_id", In: "query", Description: "Chỉ trả về tool này trong tools"},
+		},
+		Response: schemaOf(logger.DistributionsSnapshot{}),
+	},
+
+	// Reports
+	{
+		Method:  "GET",
+		Path:    "/reports",
+		Tag:     "reports",
+		Summary: "Báo cáo sử dụng lock theo tool hoặc thread, gộp từ lock event log (JSON hoặc CSV)",
+		Params: []paramDoc{
+			{Name: "from", In: "query", Description: "Bắt đầu (RFC 3339 hoặc YYYY-MM-DD), mặc định đầu ngày 6 ngày trước"},
+			{Name: "to", In: "query", Description: "Kết thúc, không bao gồm, mặc định bây giờ. Tối đa 366 ngày, 31 ngày với bucket=hour"},
+			{Name: "group_by", In: "query", Description: "tool (mặc định) hoặc thread"},
+			{Name: "bucket", In: "query", Description: "hour, day hoặc week (tuần bắt đầu thứ Hai); bỏ trống để gộp cả khoảng"},
+			{Name: "format", In: "query", Description: "json (mặc định) hoặc csv"},
+		},
+		Response: schemaOf(logger.UsageReport{}),
+		Errors:   []string{ErrCodeInvalidRequest},
+	},
+
+	// Alerts
+	{
+		Method:  "GET",
+		Path:    "/alerts",
+		Tag:     "alerts",
+		Summary: "Các alert đang firing/pending và các alert đã resolved gần đây",
+		Params: []paramDoc{
+			{Name: "state", In: "query", Description: "Chỉ trả về alert ở trạng thái này: pending, firing hoặc resolved"},
+		},
+		Response: objectSchema(map[string]schema{
+			"count":  integerSchema,
+			"firing": integerSchema,
+			"alerts": arraySchema(schemaOf(model.Alert{})),
+		}),
+		Errors: []string{ErrCodeInvalidRequest},
+	},
+
+	// Webhooks
+	{
+		Method:  "GET",
+		Path:    "/webhooks",
+		Tag:     "webhooks",
+		Summary: "Danh sách webhook (secret bị ẩn) và thống kê gửi của từng webhook (admin)",
+		Response: objectSchema(map[string]schema{
+			"count":    integerSchema,
+			"events":   arraySchema(stringSchema),
+			"webhooks": schemaOf([]webhookStatus{}),
+		}),
+		Errors: []string{ErrCodeUnauthorized},
+	},
+	{
+		Method:   "POST",
+		Path:     "/webhooks",
+		Tag:      "webhooks",
+		Summary:  "Thêm webhook (admin)",
+		Request:  config.WebhookConfig{},
+		Response: webhookUpdateResponse,
+		Errors:   []string{ErrCodeInvalidRequest, ErrCodeInvalidConfig, ErrCodeUnauthorized, ErrCodeWebhookExists},
+	},
+	{
+		Method:  "GET",
+		Path:    "/webhooks/dead-letter",
+		Tag:     "webhooks",
+		Summary: "Các lần gửi đã bỏ cuộc, mới nhất trước (admin)",
+		Params: []paramDoc{
+			{Name: "limit", In: "query", Description: "Số entry tối đa (mặc định 50, tối đa 1000)", Schema: integerSchema},
+		},
+		Response: objectSchema(map[string]schema{
+			"count":   integerSchema,
+			"total":   integerSchema,
+			"entries": schemaOf([]webhook.DeadLetter{}),
+		}),
+		Errors: []string{ErrCodeInvalidRequest, ErrCodeUnauthorized},
+	},
+	{
+		Method:   "PUT",
+		Path:     "/webhooks/:name",
+		Tag:      "webhooks",
+		Summary:  "Thay thế webhook; secret \"********\" giữ nguyên secret hiện tại (admin)",
+		Params:   []paramDoc{webhookNamePath},
+		Request:  config.WebhookConfig{},
+		Response: webhookUpdateResponse,
+		Errors:   []string{ErrCodeInvalidRequest, ErrCodeInvalidConfig, ErrCodeUnauthorized, ErrCodeWebhookNotFound},
+	},
+	{
+		Method:   "DELETE",
+		Path:     "/webhooks/:name",
+		Tag:      "webhooks",
+		Summary:  "Xoá webhook (admin)",
+		Params:   []paramDoc{webhookNamePath},
+		Response: webhookUpdateResponse,
+		Errors:   []string{ErrCodeInvalidRequest, ErrCodeUnauthorized, ErrCodeWebhookNotFound},
+	},
+	{
+		Method:   "POST",
+		Path:     "/webhooks/:name/test",
+		Tag:      "webhooks",
+		Summary:  "Gửi thử một event webhook_test, không retry (admin)",
+		Params:   []paramDoc{webhookNamePath},
+		Response: schemaOf(webhook.TestResult{}),
+		Errors:   []string{ErrCodeInvalidRequest, ErrCodeUnauthorized, ErrCodeWebhookNotFound},
+	},
+
+	// Admin
+	{
+		Method:  "POST",
+		Path:    "/admin/lock/release",
+		Tag:     "admin",
+		Summary: "Giải phóng lock hiện tại (admin)",
+		Response: objectSchema(map[string]schema{
+			"status":    stringSchema,
+			"ticket_id": stringSchema,
+			"tool_id":   stringSchema,
+			"thread_id": stringSchema,
+		}),
+		Errors: []string{ErrCodeUnauthorized, ErrCodeNoActiveLock},
+	},
+	{
+		Method:  "POST",
+		Path:    "/admin/tickets/:id/cancel",
+		Tag:     "admin",
+		Summary: "Huỷ một ticket đang chờ hoặc đang giữ lock (admin)",
+		Params: []paramDoc{
+			{Name: "id", In: "path", Description: "Ticket ID"},
+		},
+		Response: objectSchema(map[string]schema{
+			"status":    stringSchema,
+			"ticket_id": stringSchema,
+		}),
+		Errors: []string{ErrCodeInvalidRequest, ErrCodeUnauthorized, ErrCodeTicketNotFound},
+	},
+	{
+		Method:  "POST",
+		Path:    "/admin/tools/:id/unregister",
+		Tag:     "admin",
+		Summary: "Huỷ đăng ký tool và giải phóng các ticket của nó (admin)",
+		Params: []paramDoc{
+			{Name: "id", In: "path", Description: "Tool ID"},
+		},
+		Response: objectSchema(map[string]schema{
+			"status":           stringSchema,
+			"released_tickets": arraySchema(stringSchema),
+		}),
+		Errors: []string{ErrCodeInvalidRequest, ErrCodeUnauthorized, ErrCodeToolNotFound},
+	},
+
+	// Dashboard
+	{
+		Method:      "GET",
+		Path:        "/ui",
+		Tag:         "dashboard",
+		Summary:     "Web dashboard",
+		Response:    stringSchema,
+		ContentType: "text/html",
+	},
+	{
+		Method:   "GET",
+		Path:     "/ui/state",
+		Tag:      "dashboard",
+		Summary:  "Snapshot trạng thái cho dashboard",
+		Response: schemaOf(DashboardState{}),
+	},
+	{
+		Method:      "GET",
+		Path:        "/ui/events",
+		Tag:         "dashboard",
+		Summary:     "Server-Sent Events: \"state\" mỗi giây và \"event\" cho từng lock event",
+		Response:    stringSchema,
+		ContentType: "text/event-stream",
+	},
+
+	// Documentation
+	{
+		Method:   "GET",
+		Path:     "/openapi.json",
+		Tag:      "docs",
+		Summary:  "OpenAPI document",
+		Response: freeFormSchema,
+	},
+	{
+		Method:      "GET",
+		Path:        "/docs",
+		Tag:         "docs",
+		Summary:     "Trang tài liệu API",
+		Response:    stringSchema,
+		ContentType: "text/html",
+	},
+}
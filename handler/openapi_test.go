@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+// Every route registered by the Register*Handler functions must have an
+// entry in apiRoutes
+func TestAllRoutesDocumented(t *testing.T) {
+	s := newTestServer(t)
+
+	if missing := UndocumentedRoutes(s.router.Routes()); len(missing) > 0 {
+		t.Errorf("routes missing from apiRoutes: %v", missing)
+	}
+}
+
+// Every documented route exists, and the document lists each of them
+func TestOpenAPISpecMatchesRoutes(t *testing.T) {
+	s := newTestServer(t)
+
+	registered := make(map[string]bool)
+	for _, route := range s.router.Routes() {
+		registered[route.Method+" "+route.Path] = true
+	}
+	for _, doc := range apiRoutes {
+		if !registered[doc.Method+" "+doc.Path] {
+			t.Errorf("apiRoutes documents %s %s, which is not registered", doc.Method, doc.Path)
+		}
+	}
+
+	w := s.do("GET", "/openapi.json", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /openapi.json: %d", w.Code)
+	}
+	var spec struct {
+		Paths map[string]map[string]interface{} `json:"paths"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &spec); err != nil {
+		t.Fatal(err)
+	}
+	operations := 0
+	for _, methods := range spec.Paths {
+		operations += len(methods)
+	}
+	if operations != len(apiRoutes) {
+		t.Errorf("spec has %d operations, apiRoutes has %d", operations, len(apiRoutes))
+	}
+}
@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"encoding/csv"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"clipboard-controller/config"
+	"clipboard-controller/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Longest report ranges; hourly reports produce a row per hour and group
+const (
+	maxReportRange       = 366 * 24 * time.Hour
+	maxHourlyReportRange = 31 * 24 * time.Hour
+)
+
+// reportCSVColumns are the fixed CSV columns, followed by one
+// expired_<reason> column per expiry reason in the report
+var reportCSVColumns = []string{
+	"period_start", "period_end", "tool_id", "thread_id",
+	"locks_granted", "locks_released", "expired", "extends", "extend_rate",
+	"wait_total_ms", "wait_avg_ms", "wait_p95_ms",
+	"hold_total_ms", "hold_avg_ms", "hold_p95_ms", "hold_max_ms", "hold_share",
+	"hog", "hog_reasons",
+}
+
+// RegisterReportHandler registers the usage report endpoint
+func RegisterReportHandler(router *gin.Engine, lfm *logger.LogFileManager, cfg *config.Store) {
+	router.GET("/reports", getReport(lfm, cfg))
+}
+
+// getReport aggregates lock usage from the event logs
+// GET /reports?from=2024-01-08&to=2024-01-15&group_by=tool&bucket=day&format=csv
+func getReport(lfm *logger.LogFileManager, cfg *config.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		groupBy := c.DefaultQuery("group_by", logger.ReportGroupTool)
+		if groupBy != logger.ReportGroupTool && groupBy != logger.ReportGroupThread {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, []FieldError{{Field: "group_by", Reason: "must be tool or thread"}})
+			return
+		}
+		bucket := c.Query("bucket")
+		if bucket != "" && !slices.Contains([]string{logger.ReportBucketHour, logger.ReportBucketDay, logger.ReportBucketWeek}, bucket) {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, []FieldError{{Field: "bucket", Reason: "must be hour, day or week"}})
+			return
+		}
+		format := c.DefaultQuery("format", "json")
+		if format != "json" && format != "csv" {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, []FieldError{{Field: "format", Reason: "must be json or csv"}})
+			return
+		}
+
+		// By default the last 7 days up to now
+		now := time.Now()
+		from, ok := timeQuery(c, "from", time.Date(now.Year(), now.Month(), now.Day()-6, 0, 0, 0, 0, now.Location()))
+		if !ok {
+			return
+		}
+		to, ok := timeQuery(c, "to", now)
+		if !ok {
+			return
+		}
+		if !from.Before(to) {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, []FieldError{{Field: "from", Reason: "must be before to"}})
+			return
+		}
+		limit := maxReportRange
+		if bucket == logger.ReportBucketHour {
+			limit = maxHourlyReportRange
+		}
+		if to.Sub(from) > limit {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, []FieldError{{Field: "from", Reason: "range must be at most " + strconv.Itoa(int(limit.Hours()/24)) + " days"}})
+			return
+		}
+
+		report, err := lfm.Report(c.Request.Context(), logger.ReportQuery{From: from, To: to, GroupBy: groupBy, Bucket: bucket}, cfg.Get())
+		if err != nil {
+			respondInternalError(c, err)
+			return
+		}
+
+		if format == "csv" {
+			writeReportCSV(c, report)
+			return
+		}
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+// writeReportCSV writes the report rows as a CSV attachment
+func writeReportCSV(c *gin.Context, report *logger.UsageReport) {
+	reasonSet := make(map[string]bool)
+	for _, row := range report.Rows {
+		for reason := range row.ExpiriesByReason {
+			reasonSet[reason] = true
+		}
+	}
+	reasons := make([]string, 0, len(reasonSet))
+	for reason := range reasonSet {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+
+	header := slices.Clone(reportCSVColumns)
+	for _, reason := range reasons {
+		header = append(header, "expired_"+reason)
+	}
+
+	filename := "report-" + report.From.Local().Format("20060102") + "-" + report.To.Local().Format("20060102") + ".csv"
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	w.Write(header)
+	for _, row := range report.Rows {
+		record := []string{
+			row.PeriodStart.Format(time.RFC3339), row.PeriodEnd.Format(time.RFC3339), row.ToolID, row.ThreadID,
+			itoa(row.LocksGranted), itoa(row.LocksReleased), itoa(row.Expired), itoa(row.Extends), ftoa(row.ExtendRate),
+			itoa(row.WaitTotalMs), itoa(row.WaitAvgMs), itoa(row.WaitP95Ms),
+			itoa(row.HoldTotalMs), itoa(row.HoldAvgMs), itoa(row.HoldP95Ms), itoa(row.HoldMaxMs), ftoa(row.HoldShare),
+			strconv.FormatBool(row.Hog), strings.Join(row.HogReasons, ";"),
+		}
+		for _, reason := range reasons {
+			record = append(record, itoa(row.ExpiriesByReason[reason]))
+		}
+		w.Write(record)
+	}
+	w.Flush()
+}
+
+func itoa(v int64) string { return strconv.FormatInt(v, 10) }
+
+func ftoa(v float64) string { return strconv.FormatFloat(v, 'f', 4, 64) }
@@ -3,99 +3,123 @@ package handler
 import (
 	"errors"
 	"net/http"
+	"strings"
 	"time"
 
 	"clipboard-controller/config"
+	"clipboard-controller/logger"
+	"clipboard-controller/model"
 	"clipboard-controller/service"
 
 	"github.com/gin-gonic/gin"
 )
 
 // RegisterToolHandler registers tool management endpoints
-func RegisterToolHandler(router *gin.Engine, tr *service.ToolRegistry, cfg *config.Config) {
+func RegisterToolHandler(router *gin.Engine, tr *service.ToolRegistry, tl *service.ToolLifecycle, cfg *config.Store, el *logger.EventLogger) {
 	tool := router.Group("/tool")
 	{
-		tool.POST("/register", registerTool(tr, cfg))
-		tool.POST("/heartbeat", heartbeatTool(tr, cfg))
-		tool.POST("/unregister", unregisterTool(tr))
+		tool.POST("/register", registerTool(tl, cfg))
+		tool.POST("/heartbeat", heartbeatTool(tr, tl))
+		tool.POST("/unregister", unregisterTool(tl, el))
 		tool.GET("/status", getToolStatus(tr, cfg))
+		tool.GET("/list", listTools(tr))
 	}
 }
 
 // RegisterRequest represents the request body for tool registration
 type RegisterRequest struct {
-	ToolID string `json:"tool_id" binding:"required"`
+	ToolID      string            `json:"tool_id" binding:"required,max=64,id"`
+	Hostname    string            `json:"hostname" binding:"max=255"`
+	PID         int               `json:"pid" binding:"min=0"`
+	Version     string            `json:"version" binding:"max=64"`
+	Machine     string            `json:"machine" binding:"max=255"`
+	Owner       string            `json:"owner" binding:"max=128"`
+	ToolType    string            `json:"tool_type" binding:"max=32"` // bas, rod, ...
+	ThreadCount int               `json:"thread_count" binding:"min=0"`
+	Labels      map[string]string `json:"labels" binding:"max=20,dive,keys,max=64,id,endkeys,max=128"`
+	Takeover    bool              `json:"takeover"` // Replace the session of an instance that is still online
+}
+
+// toolInfo returns the metadata part of the request
+func (r RegisterRequest) toolInfo() model.ToolInfo {
+	return model.ToolInfo{
+		Hostname:    r.Hostname,
+		PID:         r.PID,
+		Version:     r.Version,
+		Machine:     r.Machine,
+		Owner:       r.Owner,
+		ToolType:    r.ToolType,
+		ThreadCount: r.ThreadCount,
+		Labels:      r.Labels,
+	}
 }
 
 // HeartbeatRequest represents the request body for heartbeat
 type HeartbeatRequest struct {
-	ToolID string `json:"tool_id" binding:"required"`
+	ToolID    string `json:"tool_id" binding:"required,max=64,id"`
+	SessionID string `json:"session_id" binding:"required,max=64,id"`
 }
 
 // UnregisterRequest represents the request body for unregister
 type UnregisterRequest struct {
-	ToolID string `json:"tool_id" binding:"required"`
+	ToolID    string `json:"tool_id" binding:"required,max=64,id"`
+	SessionID string `json:"session_id" binding:"required,max=64,id"`
+}
+
+// respondToolError maps tool registry errors to API errors
+func respondToolError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrToolNotFound):
+		respondError(c, http.StatusNotFound, ErrCodeToolNotFound, nil)
+	case errors.Is(err, service.ErrToolOffline):
+		respondError(c, http.StatusBadRequest, ErrCodeToolOffline, nil)
+	case errors.Is(err, service.ErrSessionMismatch):
+		respondError(c, http.StatusConflict, ErrCodeSessionMismatch, nil)
+	default:
+		respondInternalError(c, err)
+	}
 }
 
-func registerTool(tr *service.ToolRegistry, cfg *config.Config) gin.HandlerFunc {
+func registerTool(tl *service.ToolLifecycle, cfg *config.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req RegisterRequest
-		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error":   "invalid_request",
-				"message": "tool_id is required",
-			})
+		if !bindJSON(c, &req) {
 			return
 		}
 
-		tool, err := tr.Register(req.ToolID)
+		tool, released, err := tl.Register(req.ToolID, req.toolInfo(), req.Takeover)
 		if err != nil {
 			if errors.Is(err, service.ErrToolAlreadyRegistered) {
-				c.JSON(http.StatusConflict, gin.H{
-					"error":   "tool_already_registered",
-					"message": "Tool ID đã được đăng ký và đang online",
-				})
+				respondError(c, http.StatusConflict, ErrCodeToolAlreadyRegistered, nil)
 				return
 			}
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error":   "internal_error",
-				"message": err.Error(),
-			})
+			respondInternalError(c, err)
 			return
 		}
 
 		c.JSON(http.StatusOK, gin.H{
-			"tool_id": tool.ToolID,
-			"status":  "registered",
-			"config":  cfg.GetClientConfig(),
+			"tool_id":          tool.ToolID,
+			"session_id":       tool.SessionID,
+			"status":           "registered",
+			"released_tickets": released,
+			"config":           cfg.Get().GetClientConfig(tool.ToolID),
 		})
+
+		// Set context for logging
+		c.Set("tool_id", tool.ToolID)
 	}
 }
 
-func heartbeatTool(tr *service.ToolRegistry, cfg *config.Config) gin.HandlerFunc {
+func heartbeatTool(tr *service.ToolRegistry, tl *service.ToolLifecycle) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req HeartbeatRequest
-		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error":   "invalid_request",
-				"message": "tool_id is required",
-			})
+		if !bindJSON(c, &req) {
 			return
 		}
 
-		tool, err := tr.Heartbeat(req.ToolID)
+		tool, tickets, err := tl.Heartbeat(req.ToolID, req.SessionID)
 		if err != nil {
-			if errors.Is(err, service.ErrToolNotFound) {
-				c.JSON(http.StatusNotFound, gin.H{
-					"error":   "tool_not_found",
-					"message": "Tool chưa được đăng ký",
-				})
-				return
-			}
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error":   "internal_error",
-				"message": err.Error(),
-			})
+			respondToolError(c, err)
 			return
 		}
 
@@ -104,6 +128,7 @@ func heartbeatTool(tr *service.ToolRegistry, cfg *config.Config) gin.HandlerFunc
 		c.JSON(http.StatusOK, gin.H{
 			"status":                "ok",
 			"next_heartbeat_before": deadline.Format(time.RFC3339),
+			"tickets":               tickets,
 		})
 
 		// Set context for logging
@@ -111,67 +136,45 @@ func heartbeatTool(tr *service.ToolRegistry, cfg *config.Config) gin.HandlerFunc
 	}
 }
 
-func unregisterTool(tr *service.ToolRegistry) gin.HandlerFunc {
+func unregisterTool(tl *service.ToolLifecycle, el *logger.EventLogger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req UnregisterRequest
-		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error":   "invalid_request",
-				"message": "tool_id is required",
-			})
+		if !bindJSON(c, &req) {
 			return
 		}
 
-		tool, err := tr.Unregister(req.ToolID)
+		tool, released, err := tl.Unregister(req.ToolID, req.SessionID)
 		if err != nil {
-			if errors.Is(err, service.ErrToolNotFound) {
-				c.JSON(http.StatusNotFound, gin.H{
-					"error":   "tool_not_found",
-					"message": "Tool chưa được đăng ký",
-				})
-				return
-			}
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error":   "internal_error",
-				"message": err.Error(),
-			})
+			respondToolError(c, err)
 			return
 		}
 
 		c.JSON(http.StatusOK, gin.H{
 			"status":           "unregistered",
-			"released_tickets": []string{}, // Will be populated by lock manager cleanup
+			"released_tickets": released,
 		})
 
+		audit(c, el, model.AuditLog{Action: model.AuditToolUnregister, Actor: model.AuditActorTool, Target: tool.ToolID})
+
 		// Set context for logging
 		c.Set("tool_id", tool.ToolID)
 	}
 }
 
-func getToolStatus(tr *service.ToolRegistry, cfg *config.Config) gin.HandlerFunc {
+func getToolStatus(tr *service.ToolRegistry, cfg *config.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		toolID := c.Query("tool_id")
-		if toolID == "" {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error":   "invalid_request",
-				"message": "tool_id query parameter is required",
-			})
+		if !validateIDParam(c, "tool_id", toolID) {
 			return
 		}
 
 		tool, err := tr.GetTool(toolID)
 		if err != nil {
 			if errors.Is(err, service.ErrToolNotFound) {
-				c.JSON(http.StatusNotFound, gin.H{
-					"error":   "tool_not_found",
-					"message": "Tool chưa được đăng ký",
-				})
+				respondError(c, http.StatusNotFound, ErrCodeToolNotFound, nil)
 				return
 			}
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error":   "internal_error",
-				"message": err.Error(),
-			})
+			respondInternalError(c, err)
 			return
 		}
 
@@ -183,6 +186,45 @@ func getToolStatus(tr *service.ToolRegistry, cfg *config.Config) gin.HandlerFunc
 			"registered_at":           tool.RegisteredAt.Format(time.RFC3339),
 			"last_heartbeat":          tool.LastHeartbeat.Format(time.RFC3339),
 			"next_heartbeat_deadline": deadline.Format(time.RFC3339),
+			"hostname":                tool.Hostname,
+			"pid":                     tool.PID,
+			"version":                 tool.Version,
+			"machine":                 tool.Machine,
+			"owner":                   tool.Owner,
+			"tool_type":               tool.ToolType,
+			"thread_count":            tool.ThreadCount,
+			"labels":                  tool.Labels,
 		})
 	}
 }
+
+func listTools(tr *service.ToolRegistry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		selector, ok := parseLabelSelector(c)
+		if !ok {
+			return
+		}
+
+		tools := tr.ListTools(selector)
+
+		c.JSON(http.StatusOK, gin.H{
+			"count": len(tools),
+			"tools": tools,
+		})
+	}
+}
+
+// parseLabelSelector reads repeated ?label=key=value parameters. On failure
+// it writes an invalid_request response and returns false.
+func parseLabelSelector(c *gin.Context) (map[string]string, bool) {
+	selector := make(map[string]string)
+	for _, raw := range c.QueryArray("label") {
+		key, value, found := strings.Cut(raw, "=")
+		if !found || key == "" {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, []FieldError{{Field: "label", Reason: "must be key=value"}})
+			return nil, false
+		}
+		selector[key] = value
+	}
+	return selector, true
+}
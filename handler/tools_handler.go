@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"clipboard-controller/logger"
+	"clipboard-controller/model"
+	"clipboard-controller/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Paging limits for GET /tools
+const (
+	defaultToolsLimit = 50
+	maxToolsLimit     = 500
+)
+
+// ToolSummary is a tool with its live lock activity, as listed by GET /tools
+type ToolSummary struct {
+	model.Tool
+	OfflineAt      *time.Time `json:"offline_at,omitempty"`
+	TicketsWaiting int        `json:"tickets_waiting"`
+	HoldingLock    bool       `json:"holding_lock"`
+	LocksToday     int64      `json:"locks_today"`
+	AvgWaitMs      int64      `json:"avg_wait_ms"`
+	AvgHoldMs      int64      `json:"avg_hold_ms"`
+}
+
+// toolSortKeys maps the sort query values to comparisons (ascending)
+var toolSortKeys = map[string]func(a, b *ToolSummary) bool{
+	"tool_id":         func(a, b *ToolSummary) bool { return a.ToolID < b.ToolID },
+	"status":          func(a, b *ToolSummary) bool { return a.Status < b.Status },
+	"registered_at":   func(a, b *ToolSummary) bool { return a.RegisteredAt.Before(b.RegisteredAt) },
+	"last_heartbeat":  func(a, b *ToolSummary) bool { return a.LastHeartbeat.Before(b.LastHeartbeat) },
+	"tickets_waiting": func(a, b *ToolSummary) bool { return a.TicketsWaiting < b.TicketsWaiting },
+	"locks_today":     func(a, b *ToolSummary) bool { return a.LocksToday < b.LocksToday },
+	"avg_wait_ms":     func(a, b *ToolSummary) bool { return a.AvgWaitMs < b.AvgWaitMs },
+	"avg_hold_ms":     func(a, b *ToolSummary) bool { return a.AvgHoldMs < b.AvgHoldMs },
+}
+
+// RegisterToolsHandler registers the tool listing endpoint
+func RegisterToolsHandler(router *gin.Engine, tr *service.ToolRegistry, lm *service.LockManager, el *logger.EventLogger) {
+	router.GET("/tools", listToolSummaries(tr, lm, el))
+}
+
+// listToolSummaries handles
+// GET /tools?status=online&label=team=seo&sort=-locks_today&limit=50&offset=0
+func listToolSummaries(tr *service.ToolRegistry, lm *service.LockManager, el *logger.EventLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		selector, ok := parseLabelSelector(c)
+		if !ok {
+			return
+		}
+
+		status := c.Query("status")
+		if status != "" && status != string(model.ToolStatusOnline) && status != string(model.ToolStatusOffline) {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, []FieldError{{Field: "status", Reason: "must be online or offline"}})
+			return
+		}
+
+		sortBy := c.DefaultQuery("sort", "tool_id")
+		descending := strings.HasPrefix(sortBy, "-")
+		less, ok := toolSortKeys[strings.TrimPrefix(sortBy, "-")]
+		if !ok {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, []FieldError{{Field: "sort", Reason: "unknown sort key"}})
+			return
+		}
+
+		limit, ok := intQuery(c, "limit", defaultToolsLimit, 1, maxToolsLimit)
+		if !ok {
+			return
+		}
+		offset, ok := intQuery(c, "offset", 0, 0, -1)
+		if !ok {
+			return
+		}
+
+		tickets := lm.TicketsByTool()
+		summaries := make([]*ToolSummary, 0)
+		for _, tool := range tr.ListTools(selector) {
+			if status != "" && string(tool.Status) != status {
+				continue
+			}
+			summaries = append(summaries, newToolSummary(tool, tickets[tool.ToolID], el.GetToolUsage(tool.ToolID)))
+		}
+
+		sort.SliceStable(summaries, func(i, j int) bool {
+			if descending {
+				return less(summaries[j], summaries[i])
+			}
+			return less(summaries[i], summaries[j])
+		})
+
+		// Clamp before adding so a huge offset cannot overflow
+		total := len(summaries)
+		start := min(offset, total)
+		page := summaries[start : start+min(limit, total-start)]
+
+		c.JSON(http.StatusOK, gin.H{
+			"total":  total,
+			"count":  len(page),
+			"offset": offset,
+			"limit":  limit,
+			"tools":  page,
+		})
+	}
+}
+
+// newToolSummary combines a tool snapshot with its tickets and usage
+func newToolSummary(tool model.Tool, tickets []model.Ticket, usage model.ToolUsage) *ToolSummary {
+	summary := &ToolSummary{
+		Tool:       tool,
+		LocksToday: usage.LockCount,
+		AvgWaitMs:  usage.AvgWaitMs,
+		AvgHoldMs:  usage.AvgHoldMs,
+	}
+	if !tool.IsOnline() && !tool.OfflineAt.IsZero() {
+		offlineAt := tool.OfflineAt
+		summary.OfflineAt = &offlineAt
+	}
+	for _, ticket := range tickets {
+		switch {
+		case ticket.IsGranted():
+			summary.HoldingLock = true
+		case ticket.IsWaiting():
+			summary.TicketsWaiting++
+		}
+	}
+	return summary
+}
+
+// intQuery parses an optional integer query parameter within [lo, hi]
+// (hi < 0 means no upper bound). On failure it writes an invalid_request
+// response and returns false.
+func intQuery(c *gin.Context, name string, def, lo, hi int) (int, bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return def, true
+	}
+
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < lo || (hi >= 0 && n > hi) {
+		reason := "must be an integer >= " + strconv.Itoa(lo)
+		if hi >= 0 {
+			reason = "must be an integer between " + strconv.Itoa(lo) + " and " + strconv.Itoa(hi)
+		}
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, []FieldError{{Field: name, Reason: reason}})
+		return 0, false
+	}
+	return n, true
+}
@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+// Any offset past the end returns an empty page, including one that would
+// overflow when the limit is added
+func TestListToolsOffset(t *testing.T) {
+	s := newTestServer(t)
+	for i := 0; i < 3; i++ {
+		if w := s.do("POST", "/tool/register", map[string]string{"tool_id": fmt.Sprintf("tool_%d", i)}); w.Code != http.StatusOK {
+			t.Fatalf("register: %d %s", w.Code, w.Body.String())
+		}
+	}
+
+	for _, tc := range []struct {
+		query string
+		count int
+	}{
+		{"offset=0&limit=2", 2},
+		{"offset=2&limit=2", 1},
+		{"offset=3", 0},
+		{"offset=" + strconv.Itoa(math.MaxInt), 0},
+		{"offset=" + strconv.Itoa(math.MaxInt) + "&limit=500", 0},
+	} {
+		w := s.do("GET", "/tools?"+tc.query, nil)
+		if w.Code != http.StatusOK {
+			t.Errorf("GET /tools?%s: %d %s", tc.query, w.Code, w.Body.String())
+			continue
+		}
+		body := decode(t, w)
+		if body["count"] != float64(tc.count) || body["total"] != float64(3) {
+			t.Errorf("GET /tools?%s: count %v total %v, want %d of 3", tc.query, body["count"], body["total"], tc.count)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// Limits for client-supplied identifiers (tool_id, thread_id, ticket_id)
+const maxIDLength = 64
+
+// idPattern is the allowed charset for identifiers
+var idPattern = regexp.MustCompile(`^[A-Za-z0-9._:@-]+$`)
+
+// FieldError describes why a single request field was rejected
+type FieldError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+func init() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(jsonFieldName)
+		_ = v.RegisterValidation("id", func(fl validator.FieldLevel) bool {
+			return idPattern.MatchString(fl.Field().String())
+		})
+	}
+}
+
+// bindJSON strictly decodes the request body into obj (unknown fields and
+// wrong types are rejected), then runs its binding validation. On failure it
+// writes an invalid_request response and returns false.
+func bindJSON(c *gin.Context, obj interface{}) bool {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, []FieldError{{Field: "body", Reason: "unreadable body"}})
+		return false
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(obj); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, []FieldError{decodeFieldError(err)})
+		return false
+	}
+
+	if err := binding.Validator.ValidateStruct(obj); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, validationFieldErrors(err))
+		return false
+	}
+
+	return true
+}
+
+// validateIDParam validates an identifier taken from the query string. On
+// failure it writes an invalid_request response and returns false.
+func validateIDParam(c *gin.Context, name, value string) bool {
+	var reason string
+	switch {
+	case value == "":
+		reason = "is required"
+	case len(value) > maxIDLength:
+		reason = fmt.Sprintf("must be at most %d characters", maxIDLength)
+	case !idPattern.MatchString(value):
+		reason = "may only contain letters, digits and . _ : @ -"
+	default:
+		return true
+	}
+
+	respondError(c, http.StatusBadRequest, ErrCodeInvalidRequest, []FieldError{{Field: name, Reason: reason}})
+	return false
+}
+
+// decodeFieldError converts a JSON decoding error into a field error
+func decodeFieldError(err error) FieldError {
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &typeErr):
+		return FieldError{Field: typeErr.Field, Reason: "must be of type " + typeErr.Type.String()}
+	case errors.As(err, &syntaxErr):
+		return FieldError{Field: "body", Reason: "malformed JSON"}
+	case errors.Is(err, io.EOF):
+		return FieldError{Field: "body", Reason: "body is required"}
+	}
+
+	// encoding/json reports unknown fields as: json: unknown field "name"
+	var name string
+	if _, scanErr := fmt.Sscanf(err.Error(), "json: unknown field %q", &name); scanErr == nil {
+		return FieldError{Field: name, Reason: "unknown field"}
+	}
+	return FieldError{Field: "body", Reason: err.Error()}
+}
+
+// validationFieldErrors converts validator errors into field errors
+func validationFieldErrors(err error) []FieldError {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return []FieldError{{Field: "body", Reason: err.Error()}}
+	}
+
+	result := make([]FieldError, 0, len(verrs))
+	for _, fe := range verrs {
+		var reason string
+		switch fe.Tag() {
+		case "required":
+			reason = "is required"
+		case "max":
+			if fe.Kind() == reflect.String {
+				reason = "must be at most " + fe.Param() + " characters"
+			} else {
+				reason = "must have at most " + fe.Param() + " entries"
+			}
+		case "min":
+			reason = "must be at least " + fe.Param()
+		case "id":
+			reason = "may only contain letters, digits and . _ : @ -"
+		default:
+			reason = "failed " + fe.Tag() + " validation"
+		}
+		result = append(result, FieldError{Field: fe.Field(), Reason: reason})
+	}
+	return result
+}
+
+// jsonFieldName reports struct fields by their JSON name in validation errors
+func jsonFieldName(f reflect.StructField) string {
+	name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+	if name == "-" {
+		return ""
+	}
+	return name
+}
@@ -0,0 +1,202 @@
+package handler
+
+import (
+	"net/http"
+
+	"clipboard-controller/config"
+	"clipboard-controller/logger"
+	"clipboard-controller/model"
+	"clipboard-controller/webhook"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterWebhookHandler registers the webhook management endpoints. Changes
+// are stored in the runtime config and recorded in the audit log.
+func RegisterWebhookHandler(router *gin.Engine, d *webhook.Dispatcher, cfg *config.Store, reloader *config.Reloader, el *logger.EventLogger) {
+	webhooks := router.Group("/webhooks", adminAuth(cfg, el))
+	{
+		webhooks.GET("", listWebhooks(d, cfg))
+		webhooks.POST("", createWebhook(cfg, reloader, el))
+		webhooks.GET("/dead-letter", listDeadLetters(d))
+		webhooks.PUT("/:name", updateWebhook(cfg, reloader, el))
+		webhooks.DELETE("/:name", deleteWebhook(cfg, reloader, el))
+		webhooks.POST("/:name/test", testWebhook(d, cfg))
+	}
+}
+
+// webhookStatus is a webhook as listed, with its delivery stats
+type webhookStatus struct {
+	config.WebhookConfig
+	Stats webhook.Stats `json:"stats"`
+}
+
+func listWebhooks(d *webhook.Dispatcher, cfg *config.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		stats := d.Stats()
+		webhooks := make([]webhookStatus, 0)
+		for _, w := range config.MaskedWebhooks(cfg.Get().Webhooks) {
+			webhooks = append(webhooks, webhookStatus{WebhookConfig: w, Stats: stats[w.Name]})
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"count":    len(webhooks),
+			"events":   config.WebhookEvents,
+			"webhooks": webhooks,
+		})
+	}
+}
+
+func createWebhook(cfg *config.Store, reloader *config.Reloader, el *logger.EventLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var w config.WebhookConfig
+		if !bindJSON(c, &w) {
+			return
+		}
+		if !validateIDParam(c, "name", w.Name) {
+			return
+		}
+
+		current := cfg.Get().Webhooks
+		if webhookIndex(current, w.Name) >= 0 {
+			respondError(c, http.StatusConflict, ErrCodeWebhookExists, gin.H{"name": w.Name})
+			return
+		}
+
+		webhooks := append(append([]config.WebhookConfig(nil), current...), w)
+		saveWebhooks(c, cfg, reloader, el, model.AuditWebhookCreate, w.Name, webhooks)
+	}
+}
+
+// updateWebhook replaces a webhook. The name in the path wins over the body;
+// a masked secret keeps the current one.
+func updateWebhook(cfg *config.Store, reloader *config.Reloader, el *logger.EventLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.Param("name")
+		if !validateIDParam(c, "name", name) {
+			return
+		}
+
+		var w config.WebhookConfig
+		if !bindJSON(c, &w) {
+			return
+		}
+		w.Name = name
+
+		webhooks := append([]config.WebhookConfig(nil), cfg.Get().Webhooks...)
+		index := webhookIndex(webhooks, name)
+		if index < 0 {
+			respondError(c, http.StatusNotFound, ErrCodeWebhookNotFound, gin.H{"name": name})
+			return
+		}
+		webhooks[index] = w
+
+		saveWebhooks(c, cfg, reloader, el, model.AuditWebhookUpdate, name, webhooks)
+	}
+}
+
+func deleteWebhook(cfg *config.Store, reloader *config.Reloader, el *logger.EventLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.Param("name")
+		if !validateIDParam(c, "name", name) {
+			return
+		}
+
+		current := cfg.Get().Webhooks
+		index := webhookIndex(current, name)
+		if index < 0 {
+			respondError(c, http.StatusNotFound, ErrCodeWebhookNotFound, gin.H{"name": name})
+			return
+		}
+
+		webhooks := append(append([]config.WebhookConfig{}, current[:index]...), current[index+1:]...)
+		saveWebhooks(c, cfg, reloader, el, model.AuditWebhookDelete, name, webhooks)
+	}
+}
+
+// testWebhook sends a webhook_test event once, even if the webhook is
+// disabled or not subscribed to it
+// POST /webhooks/:name/test
+func testWebhook(d *webhook.Dispatcher, cfg *config.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.Param("name")
+		if !validateIDParam(c, "name", name) {
+			return
+		}
+
+		w, ok := cfg.Get().Webhook(name)
+		if !ok {
+			respondError(c, http.StatusNotFound, ErrCodeWebhookNotFound, gin.H{"name": name})
+			return
+		}
+
+		c.JSON(http.StatusOK, d.Test(c.Request.Context(), w))
+	}
+}
+
+// listDeadLetters returns the newest deliveries that were given up on
+// GET /webhooks/dead-letter?limit=50
+func listDeadLetters(d *webhook.Dispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, ok := intQuery(c, "limit", 50, 1, 1000)
+		if !ok {
+			return
+		}
+
+		entries, total, err := d.DeadLetters(limit)
+		if err != nil {
+			respondInternalError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"count":   len(entries),
+			"total":   total,
+			"entries": entries,
+		})
+	}
+}
+
+// saveWebhooks validates and applies the new webhook list, records the change
+// and writes it back to the config file when config_write_back is on
+func saveWebhooks(c *gin.Context, cfg *config.Store, reloader *config.Reloader, el *logger.EventLogger, action, name string, webhooks []config.WebhookConfig) {
+	before := cfg.Get()
+	updated, err := cfg.Update(map[string]interface{}{"webhooks": webhooks})
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidConfig, []config.UpdateError{{Key: "webhooks", Reason: err.Error()}})
+		return
+	}
+
+	changes := config.DiffConfig(before, updated)
+	for i := range changes {
+		changes[i].Applied = true
+	}
+	audit(c, el, model.AuditLog{Action: action, Target: name, Changes: logger.AuditChanges(changes)})
+
+	response := gin.H{
+		"status":   "updated",
+		"webhooks": config.MaskedWebhooks(updated.Webhooks),
+	}
+
+	// Persist to config file so changes survive restarts
+	if updated.ConfigWriteBack {
+		if err := reloader.Persist(map[string]interface{}{"webhooks": updated.Webhooks}); err != nil {
+			response["persisted"] = false
+			response["persist_error"] = err.Error()
+		} else {
+			response["persisted"] = true
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// webhookIndex returns the position of the webhook called name, or -1
+func webhookIndex(webhooks []config.WebhookConfig, name string) int {
+	for i, w := range webhooks {
+		if w.Name == name {
+			return i
+		}
+	}
+	return -1
+}
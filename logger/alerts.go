@@ -0,0 +1,392 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"clipboard-controller/config"
+	"clipboard-controller/model"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Alert evaluation settings
+const (
+	alertEvalInterval = 10 * time.Second
+	alertWindow       = time.Minute // Window of the wait, hold and expiry metrics
+	alertEventBuffer  = 2048        // Lock events buffered between evaluations
+	alertHistorySize  = 100         // Resolved alerts kept for GET /alerts
+	alertPercentile   = 95
+)
+
+// AlertSources provides the live state the lock events do not carry
+type AlertSources struct {
+	QueueLength  func() int
+	OfflineTools func() []string
+}
+
+// alertSample is a lock event of the metric window
+type alertSample struct {
+	at        time.Time
+	eventType string
+	waitMs    int64
+	holdMs    int64
+	held      bool // lock_expired of a ticket that held the lock
+}
+
+// AlertManager evaluates the alert rules against live metrics and notifies
+// alerts that start firing or resolve. Each rule has at most one alert, or
+// one per tool for tool_offline, so a lasting condition is notified once.
+type AlertManager struct {
+	config      *config.Store
+	sources     AlertSources
+	events      <-chan model.LockEventLog
+	unsubscribe func()
+	window      []alertSample   // Owned by the evaluating goroutine
+	granted     map[string]bool // Tickets granted and not yet ended, also owned by it
+	client      *http.Client
+
+	mu         sync.Mutex
+	active     map[string]*model.Alert // By rule name and tool ID
+	history    []model.Alert           // Resolved alerts, oldest first
+	trayNotify func(title, message string)
+}
+
+// NewAlertManager creates an alert manager fed by the lock events of el.
+// Call Evaluate periodically (see BackgroundJobs.SetAlertManager).
+func NewAlertManager(el *EventLogger, cfg *config.Store, sources AlertSources) *AlertManager {
+	events, unsubscribe := el.Subscribe(alertEventBuffer)
+	return &AlertManager{
+		config:      cfg,
+		sources:     sources,
+		events:      events,
+		unsubscribe: unsubscribe,
+		client:      &http.Client{Timeout: remoteTimeout},
+		granted:     make(map[string]bool),
+		active:      make(map[string]*model.Alert),
+	}
+}
+
+// SetTrayNotifier sets the function showing a tray notification, used when
+// alert_tray_notify is on
+func (am *AlertManager) SetTrayNotifier(fn func(title, message string)) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	am.trayNotify = fn
+}
+
+// Close stops receiving lock events
+func (am *AlertManager) Close() {
+	am.unsubscribe()
+}
+
+// Alerts returns the firing and pending alerts, then the recently resolved
+// ones, newest first
+func (am *AlertManager) Alerts() []model.Alert {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	alerts := make([]model.Alert, 0, len(am.active)+len(am.history))
+	for _, alert := range am.active {
+		alerts = append(alerts, *alert)
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		a, b := alerts[i], alerts[j]
+		if a.State != b.State {
+			return a.State == model.AlertStateFiring
+		}
+		if !a.StartedAt.Equal(b.StartedAt) {
+			return a.StartedAt.After(b.StartedAt)
+		}
+		return a.Rule+a.ToolID < b.Rule+b.ToolID
+	})
+	for i := len(am.history) - 1; i >= 0; i-- {
+		alerts = append(alerts, am.history[i])
+	}
+	return alerts
+}
+
+// FiringCount returns the number of firing alerts
+func (am *AlertManager) FiringCount() int {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	n := 0
+	for _, alert := range am.active {
+		if alert.State == model.AlertStateFiring {
+			n++
+		}
+	}
+	return n
+}
+
+// Evaluate updates the metric window and every rule's alerts, and notifies
+// the alerts that changed state
+func (am *AlertManager) Evaluate(now time.Time) {
+	am.drain(now)
+	cfg := am.config.Get()
+	metrics := am.metrics()
+
+	var changed []model.Alert
+	am.mu.Lock()
+	trayNotify := am.trayNotify
+	seen := make(map[string]bool, len(am.active))
+	for _, rule := range cfg.AlertRules {
+		for toolID, value := range am.ruleValues(rule, metrics) {
+			key := rule.Name + "/" + toolID
+			seen[key] = true
+			if alert, ok := am.update(key, rule, toolID, value, now); ok {
+				changed = append(changed, alert)
+			}
+		}
+	}
+	for key, alert := range am.active {
+		if seen[key] {
+			continue
+		}
+		// Removed rules are dropped; a tool that is no longer offline resolves
+		if alert.State == model.AlertStateFiring && am.hasRule(cfg, alert.Rule) {
+			alert.Value = 0
+			changed = append(changed, am.resolve(key, now))
+		} else {
+			delete(am.active, key)
+		}
+	}
+	am.mu.Unlock()
+
+	for _, alert := range changed {
+		am.notify(cfg, alert, trayNotify)
+	}
+}
+
+// drain moves the lock events received since the last evaluation into the
+// window and drops the samples older than alertWindow
+func (am *AlertManager) drain(now time.Time) {
+	for drained := false; !drained; {
+		select {
+		case event := <-am.events:
+			sample := alertSample{at: event.Timestamp, eventType: event.EventType, waitMs: event.WaitDurationMs, holdMs: event.HoldDurationMs}
+			switch event.EventType {
+			case model.LockEventGranted:
+				am.granted[event.TicketID] = true
+			case model.LockEventReleased:
+				delete(am.granted, event.TicketID)
+			case model.LockEventExpired:
+				// Tickets expiring in the queue never held the lock
+				sample.held = am.granted[event.TicketID]
+				delete(am.granted, event.TicketID)
+			}
+			am.window = append(am.window, sample)
+		default:
+			drained = true
+		}
+	}
+
+	cutoff := now.Add(-alertWindow)
+	i := 0
+	for i < len(am.window) && am.window[i].at.Before(cutoff) {
+		i++
+	}
+	am.window = append(am.window[:0], am.window[i:]...)
+}
+
+// alertMetrics are the metric values of one evaluation
+type alertMetrics struct {
+	queueLength  float64
+	waitP95      float64
+	holdP95      float64
+	expiries     float64
+	offlineTools []string
+}
+
+func (am *AlertManager) metrics() alertMetrics {
+	var m alertMetrics
+	var wait, hold histogram
+	for _, sample := range am.window {
+		switch sample.eventType {
+		case model.LockEventGranted:
+			wait.record(sample.waitMs)
+		case model.LockEventReleased:
+			hold.record(sample.holdMs)
+		case model.LockEventExpired:
+			if sample.held {
+				m.expiries++
+				hold.record(sample.holdMs)
+			}
+		}
+	}
+	m.waitP95 = float64(wait.percentile(alertPercentile))
+	m.holdP95 = float64(hold.percentile(alertPercentile))
+
+	if am.sources.QueueLength != nil {
+		m.queueLength = float64(am.sources.QueueLength())
+	}
+	if am.sources.OfflineTools != nil {
+		m.offlineTools = am.sources.OfflineTools()
+	}
+	return m
+}
+
+// ruleValues returns the rule's metric by tool ID; rules that are not per
+// tool have a single value under ""
+func (am *AlertManager) ruleValues(rule config.AlertRule, m alertMetrics) map[string]float64 {
+	switch rule.Metric {
+	case config.AlertMetricQueueLength:
+		return map[string]float64{"": m.queueLength}
+	case config.AlertMetricWaitP95:
+		return map[string]float64{"": m.waitP95}
+	case config.AlertMetricHoldP95:
+		return map[string]float64{"": m.holdP95}
+	case config.AlertMetricExpiries:
+		return map[string]float64{"": m.expiries}
+	case config.AlertMetricToolOffline:
+		values := make(map[string]float64, len(m.offlineTools))
+		for _, toolID := range m.offlineTools {
+			if rule.MatchesTool(toolID) {
+				values[toolID] = 1
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// update applies one evaluation to the alert under key and returns the alert
+// if it started firing or resolved. Callers hold am.mu.
+func (am *AlertManager) update(key string, rule config.AlertRule, toolID string, value float64, now time.Time) (model.Alert, bool) {
+	alert, ok := am.active[key]
+	if !rule.Compare(value) {
+		if !ok {
+			return model.Alert{}, false
+		}
+		if alert.State == model.AlertStatePending {
+			delete(am.active, key)
+			return model.Alert{}, false
+		}
+		alert.Value = value
+		return am.resolve(key, now), true
+	}
+
+	if !ok {
+		alert = &model.Alert{Rule: rule.Name, ToolID: toolID, State: model.AlertStatePending, StartedAt: now}
+		am.active[key] = alert
+	}
+	alert.Metric, alert.Op, alert.Threshold, alert.Severity = rule.Metric, ruleOp(rule), rule.Threshold, ruleSeverity(rule)
+	alert.Value = value
+	alert.Message = alertMessage(alert)
+
+	if alert.State == model.AlertStatePending && now.Sub(alert.StartedAt) >= time.Duration(rule.For)*time.Second {
+		firedAt := now
+		alert.State = model.AlertStateFiring
+		alert.FiredAt = &firedAt
+		return *alert, true
+	}
+	return model.Alert{}, false
+}
+
+// resolve moves a firing alert to the history. Callers hold am.mu.
+func (am *AlertManager) resolve(key string, now time.Time) model.Alert {
+	alert := am.active[key]
+	delete(am.active, key)
+
+	resolvedAt := now
+	alert.State = model.AlertStateResolved
+	alert.ResolvedAt = &resolvedAt
+	alert.Message = alertMessage(alert)
+
+	am.history = append(am.history, *alert)
+	if len(am.history) > alertHistorySize {
+		am.history = am.history[len(am.history)-alertHistorySize:]
+	}
+	return *alert
+}
+
+func (am *AlertManager) hasRule(cfg *config.Config, name string) bool {
+	for _, rule := range cfg.AlertRules {
+		if rule.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// notify logs an alert state change and sends it to the tray and the webhook
+func (am *AlertManager) notify(cfg *config.Config, alert model.Alert, trayNotify func(title, message string)) {
+	entry := log.Warn()
+	if alert.State == model.AlertStateResolved {
+		entry = log.Info()
+	}
+	entry.Str("rule", alert.Rule).
+		Str("state", alert.State).
+		Str("severity", alert.Severity).
+		Str("tool_id", alert.ToolID).
+		Float64("value", alert.Value).
+		Msg(alert.Message)
+
+	if cfg.AlertTrayNotify && trayNotify != nil {
+		trayNotify(alertTitle(alert), alert.Message)
+	}
+	if cfg.AlertWebhookURL != "" {
+		go am.deliver(cfg.AlertWebhookURL, alert)
+	}
+}
+
+// deliver POSTs the alert as JSON to the webhook URL
+func (am *AlertManager) deliver(url string, alert model.Alert) {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return
+	}
+
+	resp, err := am.client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Warn().Err(err).Str("rule", alert.Rule).Msg("Failed to deliver alert webhook")
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Warn().Int("status", resp.StatusCode).Str("rule", alert.Rule).Msg("Alert webhook rejected the alert")
+	}
+}
+
+func ruleOp(rule config.AlertRule) string {
+	if rule.Op == "" {
+		return ">"
+	}
+	return rule.Op
+}
+
+func ruleSeverity(rule config.AlertRule) string {
+	if rule.Severity == "" {
+		return config.AlertSeverityWarning
+	}
+	return rule.Severity
+}
+
+// alertMessage describes the alert's condition
+func alertMessage(alert *model.Alert) string {
+	if alert.Metric == config.AlertMetricToolOffline {
+		if alert.State == model.AlertStateResolved {
+			return fmt.Sprintf("tool %s is no longer offline", alert.ToolID)
+		}
+		return fmt.Sprintf("tool %s is offline", alert.ToolID)
+	}
+	value := strconv.FormatFloat(alert.Value, 'f', -1, 64)
+	threshold := strconv.FormatFloat(alert.Threshold, 'f', -1, 64)
+	return fmt.Sprintf("%s is %s (%s %s)", alert.Metric, value, alert.Op, threshold)
+}
+
+// alertTitle is the notification title, e.g. "[FIRING] queue_backlog"
+func alertTitle(alert model.Alert) string {
+	state := "FIRING"
+	if alert.State == model.AlertStateResolved {
+		state = "RESOLVED"
+	}
+	return "[" + state + "] " + alert.Rule
+}
@@ -0,0 +1,37 @@
+package logger
+
+import (
+	"testing"
+	"time"
+
+	"clipboard-controller/config"
+)
+
+// lock_expiries counts held locks that expired, not tickets expiring in the
+// queue
+func TestLockExpiriesCountsHeldLocks(t *testing.T) {
+	lfm, err := NewLogFileManager(t.TempDir(), 7, 100, OverflowBlock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lfm.Close()
+	el := NewEventLogger(lfm, config.NewStore(config.Default(), config.Sources{}))
+	am := NewAlertManager(el, el.config, AlertSources{})
+	defer am.Close()
+
+	el.LogLockGranted("held", "tool_a", "thread_1", 10)
+	el.LogLockExpired("held", "tool_a", "thread_1", "max_duration_expired", 30000)
+	el.LogTicketExpired("queued", "tool_a", "thread_2", "ttl_expired")
+	el.LogTicketExpired("queued_2", "tool_b", "thread_1", "tool_offline")
+	el.LogLockGranted("released", "tool_b", "thread_2", 10)
+	el.LogLockReleased("released", "tool_b", "thread_2", 500)
+
+	am.drain(time.Now())
+	m := am.metrics()
+	if m.expiries != 1 {
+		t.Errorf("lock_expiries = %v, want 1", m.expiries)
+	}
+	if len(am.granted) != 0 {
+		t.Errorf("ended tickets still tracked: %v", am.granted)
+	}
+}
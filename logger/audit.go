@@ -0,0 +1,355 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"clipboard-controller/config"
+	"clipboard-controller/model"
+)
+
+// AuditLogType is the log type of the audit stream. Its files are never
+// removed by the retention cleanup or the disk budget.
+const AuditLogType = "audit"
+
+// authFailureWindow is how long auth failures of one client IP are
+// aggregated after the one that was written
+const authFailureWindow = time.Minute
+
+// authFailures aggregates auth failure audits by client IP, so that a client
+// retrying a bad token writes one entry per window instead of one per call
+type authFailures struct {
+	mu      sync.Mutex
+	clients map[string]*clientFailures
+}
+
+// clientFailures are the failures of one client IP in the current window
+type clientFailures struct {
+	since time.Time      // When the window's first failure was written
+	count int            // Failures not written since
+	last  model.AuditLog // The last of them
+}
+
+// auditHashField precedes the hash at the end of every audit line
+var auditHashField = []byte(`,"hash":"`)
+
+// sealAudit appends the hash of data, an audit entry marshalled without its
+// hash, as the last field. It returns the line and the hash.
+func sealAudit(data []byte) ([]byte, string) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	line := make([]byte, 0, len(data)+len(auditHashField)+len(hash)+2)
+	line = append(line, data[:len(data)-1]...)
+	line = append(line, auditHashField...)
+	line = append(line, hash...)
+	line = append(line, '"', '}')
+	return line, hash
+}
+
+// splitAudit undoes sealAudit: it returns the entry as it was hashed and the
+// stored hash
+func splitAudit(line []byte) ([]byte, string, bool) {
+	if !bytes.HasSuffix(line, []byte(`"}`)) {
+		return nil, "", false
+	}
+	i := bytes.LastIndex(line, auditHashField)
+	if i < 0 {
+		return nil, "", false
+	}
+	hash := string(line[i+len(auditHashField) : len(line)-2])
+	data := append(line[:i:i], '}')
+	return data, hash, true
+}
+
+// encode marshals a queued entry. Audit entries are chained to the last
+// written one and sealed; their hash is returned so the writer can advance
+// the chain once the line is written.
+func (lfm *LogFileManager) encode(item queuedEntry) ([]byte, string, error) {
+	entry, ok := item.entry.(*model.AuditLog)
+	if !ok {
+		data, err := json.Marshal(item.entry)
+		return data, "", err
+	}
+
+	entry.PrevHash = lfm.auditHead
+	entry.Hash = ""
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return nil, "", err
+	}
+	line, hash := sealAudit(data)
+	entry.Hash = hash
+	return line, hash, nil
+}
+
+// loadAuditHead continues the chain from the last audit entry on disk
+func (lfm *LogFileManager) loadAuditHead() error {
+	dir, err := lfm.typeDir(AuditLogType)
+	if err != nil {
+		return err
+	}
+	files, err := listLogFiles(dir)
+	if err != nil {
+		return err
+	}
+
+	for i := len(files) - 1; i >= 0; i-- {
+		hash, err := lastAuditHash(filepath.Join(dir, files[i].name), files[i].compressed)
+		if err != nil {
+			return err
+		}
+		if hash != "" {
+			lfm.auditHead = hash
+			return nil
+		}
+	}
+	return nil
+}
+
+// lastAuditHash returns the hash of the last sealed line of an audit file
+func lastAuditHash(path string, compressed bool) (string, error) {
+	src, err := openLogFile(path, compressed)
+	if err != nil || src == nil {
+		return "", err
+	}
+	defer src.Close()
+
+	var last string
+	reader := bufio.NewReaderSize(src, 64*1024)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if _, hash, ok := splitAudit(bytes.TrimSpace(line)); ok {
+			last = hash
+		}
+		if err == io.EOF {
+			return last, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read log file %s: %w", path, err)
+		}
+	}
+}
+
+// AuditVerification is the result of VerifyAudit
+type AuditVerification struct {
+	Valid    bool        `json:"valid"`
+	Files    int         `json:"files"`
+	Entries  int64       `json:"entries"`
+	LastSeq  uint64      `json:"last_seq,omitempty"`
+	HeadHash string      `json:"head_hash,omitempty"` // Hash of the last entry; keep a copy elsewhere to detect truncation
+	Break    *AuditBreak `json:"break,omitempty"`
+}
+
+// AuditBreak locates the first audit entry that does not verify
+type AuditBreak struct {
+	File   string `json:"file"`
+	Line   int64  `json:"line"`
+	Seq    uint64 `json:"seq,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// VerifyAudit checks the hash chain of all audit files, oldest first, and
+// stops at the first entry that was modified, removed or reordered. The chain
+// must start at the first entry ever written.
+func (lfm *LogFileManager) VerifyAudit(ctx context.Context) (*AuditVerification, error) {
+	dir, err := lfm.typeDir(AuditLogType)
+	if err != nil {
+		return nil, err
+	}
+	files, err := listLogFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Make buffered entries visible
+	lfm.FlushAll()
+
+	result := &AuditVerification{Valid: true}
+	prevHash := ""
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		result.Files++
+		brk, err := verifyAuditFile(filepath.Join(dir, file.name), file.compressed, &prevHash, result)
+		if err != nil {
+			return nil, err
+		}
+		if brk != nil {
+			brk.File = file.name
+			result.Valid = false
+			result.Break = brk
+			return result, nil
+		}
+	}
+
+	result.HeadHash = prevHash
+	return result, nil
+}
+
+// verifyAuditFile checks the lines of one audit file against the chain ending
+// in prevHash and advances it
+func verifyAuditFile(path string, compressed bool, prevHash *string, result *AuditVerification) (*AuditBreak, error) {
+	src, err := openLogFile(path, compressed)
+	if err != nil || src == nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	reader := bufio.NewReaderSize(src, 64*1024)
+	var lineNo int64
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return nil, fmt.Errorf("failed to read log file %s: %w", path, readErr)
+		}
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			lineNo++
+			if brk := verifyAuditLine(line, *prevHash); brk != nil {
+				brk.Line = lineNo
+				return brk, nil
+			}
+
+			_, hash, _ := splitAudit(line)
+			var entry model.AuditLog
+			json.Unmarshal(line, &entry)
+			*prevHash = hash
+			result.Entries++
+			result.LastSeq = entry.Seq
+		}
+		if readErr == io.EOF {
+			return nil, nil
+		}
+	}
+}
+
+// verifyAuditLine checks that line is sealed with its own hash and follows
+// the entry with hash prevHash
+func verifyAuditLine(line []byte, prevHash string) *AuditBreak {
+	data, hash, ok := splitAudit(line)
+	if !ok {
+		return &AuditBreak{Reason: "entry has no hash"}
+	}
+
+	var entry model.AuditLog
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return &AuditBreak{Reason: "entry is not valid JSON"}
+	}
+
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != hash {
+		return &AuditBreak{Seq: entry.Seq, Reason: "hash mismatch, entry was modified"}
+	}
+	if entry.PrevHash != prevHash {
+		return &AuditBreak{Seq: entry.Seq, Reason: "prev_hash does not match the previous entry, entries were removed or reordered"}
+	}
+	return nil
+}
+
+// LogAudit queues an audit entry. Audit entries are written regardless of
+// log_events and sampling.
+func (el *EventLogger) LogAudit(entry *model.AuditLog) {
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+	if entry.Result == "" {
+		entry.Result = model.AuditResultSuccess
+	}
+	el.fileManager.Enqueue(AuditLogType, entry)
+}
+
+// LogAuthFailure records a rejected call. The first failure of a client IP
+// is written at once; further ones within authFailureWindow are counted and
+// written as one entry, the last failure with count set, when the window
+// ends.
+func (el *EventLogger) LogAuthFailure(entry *model.AuditLog) {
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+
+	af := &el.authFailures
+	af.mu.Lock()
+	client, ok := af.clients[entry.ClientIP]
+	if ok && entry.Timestamp.Sub(client.since) < authFailureWindow {
+		client.count++
+		client.last = *entry
+		af.mu.Unlock()
+		return
+	}
+	var summary *model.AuditLog
+	if ok && client.count > 0 {
+		summary = client.summary()
+	}
+	if af.clients == nil {
+		af.clients = make(map[string]*clientFailures)
+	}
+	af.clients[entry.ClientIP] = &clientFailures{since: entry.Timestamp}
+	af.mu.Unlock()
+
+	if summary != nil {
+		el.LogAudit(summary)
+	}
+	el.LogAudit(entry)
+}
+
+// flushAuthFailures writes the aggregated failures of the windows that
+// started before cutoff and forgets those clients
+func (el *EventLogger) flushAuthFailures(cutoff time.Time) {
+	var summaries []*model.AuditLog
+
+	af := &el.authFailures
+	af.mu.Lock()
+	for ip, client := range af.clients {
+		if !client.since.Before(cutoff) {
+			continue
+		}
+		if client.count > 0 {
+			summaries = append(summaries, client.summary())
+		}
+		delete(af.clients, ip)
+	}
+	af.mu.Unlock()
+
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Timestamp.Before(summaries[j].Timestamp) })
+	for _, summary := range summaries {
+		el.LogAudit(summary)
+	}
+}
+
+// summary is the entry standing for the failures not written
+func (cf *clientFailures) summary() *model.AuditLog {
+	entry := cf.last
+	entry.Count = cf.count
+	return &entry
+}
+
+// LogConfigReload records a reload of the config file not requested over
+// HTTP, by the file watcher or a signal
+func (el *EventLogger) LogConfigReload(actor string, changes []config.Change) {
+	el.LogAudit(&model.AuditLog{
+		Action:  model.AuditConfigReload,
+		Actor:   actor,
+		Changes: AuditChanges(changes),
+	})
+}
+
+// AuditChanges converts config changes for the audit log
+func AuditChanges(changes []config.Change) []model.AuditChange {
+	out := make([]model.AuditChange, len(changes))
+	for i, change := range changes {
+		out[i] = model.AuditChange{Key: change.Key, Old: change.Old, New: change.New, RestartRequired: !change.Applied}
+	}
+	return out
+}
@@ -0,0 +1,117 @@
+package logger
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"clipboard-controller/config"
+	"clipboard-controller/model"
+)
+
+// readAudit returns the audit entries written under dir
+func readAudit(t *testing.T, dir string) []model.AuditLog {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, AuditLogType, "*.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var entries []model.AuditLog
+	for _, path := range files {
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var entry model.AuditLog
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				t.Fatal(err)
+			}
+			entries = append(entries, entry)
+		}
+		f.Close()
+	}
+	return entries
+}
+
+// Audit entries are written even when drop_oldest discards other entries
+func TestAuditNeverDropped(t *testing.T) {
+	dir := t.TempDir()
+	lfm, err := NewLogFileManager(dir, 7, 1, OverflowDropOldest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	el := NewEventLogger(lfm, config.NewStore(config.Default(), config.Sources{}))
+
+	const audits = auditQueueSize * 2
+	for i := 0; i < audits; i++ {
+		lfm.Enqueue("metrics", &model.SystemMetricsLog{})
+		el.LogAudit(&model.AuditLog{Action: model.AuditConfigUpdate})
+	}
+	if err := lfm.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := lfm.VerifyAudit(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Entries != audits {
+		t.Errorf("audit entries = %d, want %d", result.Entries, audits)
+	}
+	if result.Break != nil {
+		t.Errorf("audit chain broken: %+v", result.Break)
+	}
+}
+
+// Repeated auth failures of one client are written as one entry per window
+func TestAuthFailuresAggregated(t *testing.T) {
+	dir := t.TempDir()
+	lfm, err := NewLogFileManager(dir, 7, 100, OverflowBlock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	el := NewEventLogger(lfm, config.NewStore(config.Default(), config.Sources{}))
+
+	start := time.Now()
+	fail := func(ip string, at time.Duration) {
+		el.LogAuthFailure(&model.AuditLog{
+			Timestamp: start.Add(at),
+			Action:    model.AuditAuthFailure,
+			Actor:     model.AuditActorAnonymous,
+			ClientIP:  ip,
+			Result:    model.AuditResultDenied,
+		})
+	}
+
+	// 10.0.0.1: written, 4 aggregated, then a new window
+	for i := 0; i < 5; i++ {
+		fail("10.0.0.1", time.Duration(i)*time.Second)
+	}
+	fail("10.0.0.1", authFailureWindow+time.Second)
+	// 10.0.0.2: written, 2 aggregated and written when flushed
+	for i := 0; i < 3; i++ {
+		fail("10.0.0.2", time.Duration(i)*time.Second)
+	}
+	el.flushAuthFailures(start.Add(time.Second))
+	if err := lfm.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	counts := make(map[string][]int)
+	for _, entry := range readAudit(t, dir) {
+		counts[entry.ClientIP] = append(counts[entry.ClientIP], entry.Count)
+	}
+	if got := counts["10.0.0.1"]; len(got) != 3 || got[0] != 0 || got[1] != 4 || got[2] != 0 {
+		t.Errorf("10.0.0.1 entries have counts %v, want [0 4 0]", got)
+	}
+	if got := counts["10.0.0.2"]; len(got) != 2 || got[0] != 0 || got[1] != 2 {
+		t.Errorf("10.0.0.2 entries have counts %v, want [0 2]", got)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"time"
 
+	"clipboard-controller/config"
+
 	"github.com/rs/zerolog/log"
 )
 
@@ -11,7 +13,9 @@ import (
 type BackgroundJobs struct {
 	fileManager *LogFileManager
 	eventLogger *EventLogger
+	config      *config.Store
 	getMetrics  func() (activeTools int, queueLength int, currentHolder string)
+	alerts      *AlertManager
 	stopChan    chan struct{}
 }
 
@@ -19,16 +23,24 @@ type BackgroundJobs struct {
 func NewBackgroundJobs(
 	fm *LogFileManager,
 	el *EventLogger,
+	cfg *config.Store,
 	metricsProvider func() (activeTools int, queueLength int, currentHolder string),
 ) *BackgroundJobs {
 	return &BackgroundJobs{
 		fileManager: fm,
 		eventLogger: el,
+		config:      cfg,
 		getMetrics:  metricsProvider,
 		stopChan:    make(chan struct{}),
 	}
 }
 
+// SetAlertManager sets the alert manager evaluated by the background jobs.
+// Call before Start.
+func (bj *BackgroundJobs) SetAlertManager(am *AlertManager) {
+	bj.alerts = am
+}
+
 // Start starts all background jobs
 func (bj *BackgroundJobs) Start(ctx context.Context) {
 	log.Info().Msg("Starting logging background jobs")
@@ -41,11 +53,18 @@ func (bj *BackgroundJobs) Start(ctx context.Context) {
 
 	// Log cleanup every hour
 	go bj.logCleanup(ctx)
+
+	// Alert rules every few seconds
+	if bj.alerts != nil {
+		go bj.alertEvaluator(ctx)
+	}
 }
 
-// Stop stops all background jobs
+// Stop stops all background jobs and writes the auth failures still being
+// aggregated
 func (bj *BackgroundJobs) Stop() {
 	close(bj.stopChan)
+	bj.eventLogger.flushAuthFailures(time.Now().Add(time.Nanosecond))
 }
 
 // metricsCollector collects and logs metrics every minute
@@ -66,6 +85,9 @@ func (bj *BackgroundJobs) metricsCollector(ctx context.Context) {
 }
 
 func (bj *BackgroundJobs) collectMetrics() {
+	// Auth failures of windows that ended are written with the metrics
+	bj.eventLogger.flushAuthFailures(time.Now().Add(-authFailureWindow))
+
 	// Get minute metrics from event logger
 	metrics := bj.eventLogger.GetMinuteMetrics()
 
@@ -77,10 +99,11 @@ func (bj *BackgroundJobs) collectMetrics() {
 		metrics.CurrentLockHolder = currentHolder
 	}
 
-	// Write to file
-	if err := bj.fileManager.WriteJSON("metrics", metrics); err != nil {
-		log.Warn().Err(err).Msg("Failed to write metrics log")
+	// Write to file; the minute counters are reset either way
+	if !bj.config.Get().LogMetrics {
+		return
 	}
+	bj.fileManager.Enqueue("metrics", &metrics)
 
 	log.Debug().
 		Int("active_tools", metrics.ActiveTools).
@@ -89,11 +112,37 @@ func (bj *BackgroundJobs) collectMetrics() {
 		Msg("Metrics collected")
 }
 
-// dailySummaryGenerator generates daily summary at midnight
+// alertEvaluator evaluates the alert rules every alertEvalInterval
+func (bj *BackgroundJobs) alertEvaluator(ctx context.Context) {
+	ticker := time.NewTicker(alertEvalInterval)
+	defer ticker.Stop()
+	defer bj.alerts.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-bj.stopChan:
+			return
+		case now := <-ticker.C:
+			bj.alerts.Evaluate(now)
+		}
+	}
+}
+
+// dailySummaryGenerator writes the summary of each day at midnight. At
+// startup it first rebuilds yesterday's summary from the log files if the
+// server was not running at midnight.
 func (bj *BackgroundJobs) dailySummaryGenerator(ctx context.Context) {
+	yesterday := time.Now().AddDate(0, 0, -1).Format("2006-01-02")
+	if bj.config.Get().LogSummary && !bj.fileManager.HasSummary(yesterday) {
+		bj.backfillSummary(ctx, yesterday)
+	}
+
 	for {
-		// Calculate time until next midnight
+		// Calculate time until next midnight; the counters belong to today
 		now := time.Now()
+		today := now.Format("2006-01-02")
 		nextMidnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
 		durationUntilMidnight := nextMidnight.Sub(now)
 
@@ -103,29 +152,50 @@ func (bj *BackgroundJobs) dailySummaryGenerator(ctx context.Context) {
 		case <-bj.stopChan:
 			return
 		case <-time.After(durationUntilMidnight):
-			bj.generateDailySummary()
+			bj.generateDailySummary(today)
 		}
 	}
 }
 
-func (bj *BackgroundJobs) generateDailySummary() {
-	// Get yesterday's date for the summary
-	yesterday := time.Now().Add(-1 * time.Second).Format("2006-01-02")
-
+// generateDailySummary writes the day's counters as the summary of date
+// and resets them
+func (bj *BackgroundJobs) generateDailySummary(date string) {
 	summary := bj.eventLogger.GetDailySummary()
-	summary.Date = yesterday
+	summary.Date = date
 
-	if err := bj.fileManager.WriteSummary(summary); err != nil {
+	// The daily counters are reset either way
+	if !bj.config.Get().LogSummary {
+		return
+	}
+
+	if err := bj.fileManager.WriteSummary(&summary); err != nil {
 		log.Error().Err(err).Msg("Failed to write daily summary")
 	} else {
 		log.Info().
-			Str("date", yesterday).
+			Str("date", date).
 			Int64("total_requests", summary.TotalRequests).
 			Int64("locks_granted", summary.TotalLocksGranted).
 			Msg("Daily summary generated")
 	}
 }
 
+// backfillSummary writes the summary of date computed from its log files
+func (bj *BackgroundJobs) backfillSummary(ctx context.Context, date string) {
+	summary, err := bj.fileManager.ComputeDailySummary(ctx, date, bj.config.Get().MetricsPercentiles)
+	if err != nil {
+		log.Error().Err(err).Str("date", date).Msg("Failed to compute daily summary")
+		return
+	}
+	if summary.TotalRequests == 0 && summary.TotalLocksGranted == 0 && summary.TotalToolsRegistered == 0 {
+		return // Nothing was logged that day
+	}
+	if err := bj.fileManager.WriteSummary(summary); err != nil {
+		log.Error().Err(err).Msg("Failed to write daily summary")
+		return
+	}
+	log.Info().Str("date", date).Msg("Daily summary rebuilt from log files")
+}
+
 // logCleanup removes old log files every hour
 func (bj *BackgroundJobs) logCleanup(ctx context.Context) {
 	ticker := time.NewTicker(1 * time.Hour)
@@ -153,7 +223,7 @@ func (bj *BackgroundJobs) logCleanup(ctx context.Context) {
 // ForceGenerateSummary forces generation of daily summary (for testing)
 func (bj *BackgroundJobs) ForceGenerateSummary() {
 	summary := bj.eventLogger.GetDailySummary()
-	if err := bj.fileManager.WriteSummary(summary); err != nil {
+	if err := bj.fileManager.WriteSummary(&summary); err != nil {
 		log.Error().Err(err).Msg("Failed to write daily summary")
 	}
 }
@@ -0,0 +1,243 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"clipboard-controller/model"
+)
+
+// dailyTotals accumulates a day's summary counters from its log files
+type dailyTotals struct {
+	requests        int64
+	errors          int64
+	locksGranted    int64
+	locksReleased   int64
+	locksExpired    int64
+	toolsRegistered int64
+	totalWaitTime   int64
+	totalHoldTime   int64
+	waitCount       int64
+	holdCount       int64
+	maxQueueLength  int
+	maxWaitTime     int64
+	maxHoldTime     int64
+	hists           lockHistograms
+	tools           map[string]*toolStats
+	grantedTickets  map[string]bool  // Tells held-lock expiries from queued ticket expiries
+	heldExtends     map[string]int64 // Extend count of locks not yet released or expired
+}
+
+func newDailyTotals() *dailyTotals {
+	return &dailyTotals{
+		tools:          make(map[string]*toolStats),
+		grantedTickets: make(map[string]bool),
+		heldExtends:    make(map[string]int64),
+	}
+}
+
+func (t *dailyTotals) tool(toolID string) *toolStats {
+	stats, ok := t.tools[toolID]
+	if !ok {
+		stats = &toolStats{}
+		t.tools[toolID] = stats
+	}
+	return stats
+}
+
+func (t *dailyTotals) addRequest(statusCode int) {
+	t.requests++
+	if statusCode >= 400 {
+		t.errors++
+	}
+}
+
+func (t *dailyTotals) addLockEvent(event *model.LockEventLog) {
+	t.maxQueueLength = max(t.maxQueueLength, event.QueueLength)
+
+	switch event.EventType {
+	case model.LockEventRequested:
+		t.hists.queue.record(int64(event.QueueLength))
+	case model.LockEventGranted:
+		t.locksGranted++
+		t.totalWaitTime += event.WaitDurationMs
+		t.waitCount++
+		t.maxWaitTime = max(t.maxWaitTime, event.WaitDurationMs)
+		t.grantedTickets[event.TicketID] = true
+		t.hists.wait.record(event.WaitDurationMs)
+		stats := t.tool(event.ToolID)
+		stats.grantCount++
+		stats.totalWaitTime += event.WaitDurationMs
+		stats.hists.wait.record(event.WaitDurationMs)
+	case model.LockEventReleased:
+		t.locksReleased++
+		t.totalHoldTime += event.HoldDurationMs
+		t.holdCount++
+		t.maxHoldTime = max(t.maxHoldTime, event.HoldDurationMs)
+		extends := t.endLock(event.TicketID)
+		t.hists.hold.record(event.HoldDurationMs)
+		t.hists.extend.record(extends)
+		stats := t.tool(event.ToolID)
+		stats.lockCount++
+		stats.totalHoldTime += event.HoldDurationMs
+		stats.hists.hold.record(event.HoldDurationMs)
+		stats.hists.extend.record(extends)
+	case model.LockEventExpired:
+		if t.grantedTickets[event.TicketID] {
+			t.locksExpired++
+			extends := t.endLock(event.TicketID)
+			t.hists.hold.record(event.HoldDurationMs)
+			t.hists.extend.record(extends)
+			stats := t.tool(event.ToolID)
+			stats.hists.hold.record(event.HoldDurationMs)
+			stats.hists.extend.record(extends)
+		}
+	case model.LockEventExtended:
+		// Reason is "extend_<count>"
+		var extends int64
+		if _, err := fmt.Sscanf(event.Reason, "extend_%d", &extends); err == nil {
+			t.heldExtends[event.TicketID] = extends
+		}
+	}
+}
+
+// endLock returns the extend count of a ticket's lock and forgets it
+func (t *dailyTotals) endLock(ticketID string) int64 {
+	extends := t.heldExtends[ticketID]
+	delete(t.heldExtends, ticketID)
+	return extends
+}
+
+func (t *dailyTotals) addToolEvent(event *model.ToolEventLog) {
+	if event.EventType == model.ToolEventRegistered {
+		t.toolsRegistered++
+	}
+}
+
+// ComputeDailySummary rebuilds the summary of date (YYYY-MM-DD) from that
+// day's request and event files. Entries dropped by sampling, by the log_*
+// switches or by drop_oldest are not counted.
+func (lfm *LogFileManager) ComputeDailySummary(ctx context.Context, date string, percentiles []float64) (*model.DailySummaryLog, error) {
+	totals, err := lfm.dailyTotals(ctx, date)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := make([]model.ToolUsage, 0, len(totals.tools))
+	for toolID, stats := range totals.tools {
+		usage = append(usage, stats.usage(toolID, percentiles))
+	}
+
+	summary := &model.DailySummaryLog{
+		Date:                 date,
+		TotalRequests:        totals.requests,
+		TotalLocksGranted:    totals.locksGranted,
+		TotalLocksExpired:    totals.locksExpired,
+		TotalLocksReleased:   totals.locksReleased,
+		TotalToolsRegistered: totals.toolsRegistered,
+		MaxQueueLength:       totals.maxQueueLength,
+		MaxWaitTimeMs:        totals.maxWaitTime,
+		MaxHoldTimeMs:        totals.maxHoldTime,
+		ErrorCount:           totals.errors,
+		TopTools:             topToolUsage(usage, 10),
+		Distributions:        totals.hists.distributions(percentiles),
+	}
+	if totals.waitCount > 0 {
+		summary.AvgWaitTimeMs = totals.totalWaitTime / totals.waitCount
+	}
+	if totals.holdCount > 0 {
+		summary.AvgHoldTimeMs = totals.totalHoldTime / totals.holdCount
+	}
+	return summary, nil
+}
+
+// dailyTotals scans the request, lock event and tool event files of date.
+// Lock events are read in file order, which is their write order.
+func (lfm *LogFileManager) dailyTotals(ctx context.Context, date string) (*dailyTotals, error) {
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return nil, fmt.Errorf("invalid date %q: expected YYYY-MM-DD", date)
+	}
+
+	totals := newDailyTotals()
+	visits := map[string]func(*logQueryFields, []byte) bool{
+		"requests": func(fields *logQueryFields, _ []byte) bool {
+			totals.addRequest(fields.StatusCode)
+			return true
+		},
+		"lock_events": func(_ *logQueryFields, line []byte) bool {
+			var event model.LockEventLog
+			if json.Unmarshal(line, &event) == nil {
+				totals.addLockEvent(&event)
+			}
+			return true
+		},
+		"tool_events": func(_ *logQueryFields, line []byte) bool {
+			var event model.ToolEventLog
+			if json.Unmarshal(line, &event) == nil {
+				totals.addToolEvent(&event)
+			}
+			return true
+		},
+	}
+	for _, logType := range []string{"requests", "lock_events", "tool_events"} {
+		if _, _, err := lfm.scan(ctx, logType, logPosition{date: date}, date, visits[logType]); err != nil {
+			return nil, err
+		}
+	}
+	return totals, nil
+}
+
+// HasSummary reports whether the summary file of date exists
+func (lfm *LogFileManager) HasSummary(date string) bool {
+	_, err := os.Stat(filepath.Join(lfm.baseDir, "summary", date+".json"))
+	return !errors.Is(err, os.ErrNotExist)
+}
+
+// RestoreDailyCounters adds today's totals from the log files to the daily
+// counters, so a restart does not lose the day so far. It must be called
+// before any event is logged.
+func (el *EventLogger) RestoreDailyCounters(ctx context.Context) error {
+	totals, err := el.fileManager.dailyTotals(ctx, time.Now().Format("2006-01-02"))
+	if err != nil {
+		return err
+	}
+
+	el.dailyRequests.Add(totals.requests)
+	el.dailyErrors.Add(totals.errors)
+	el.dailyLocksGranted.Add(totals.locksGranted)
+	el.dailyLocksReleased.Add(totals.locksReleased)
+	el.dailyLocksExpired.Add(totals.locksExpired)
+	el.dailyToolsRegistered.Add(totals.toolsRegistered)
+	el.dailyTotalWaitTime.Add(totals.totalWaitTime)
+	el.dailyTotalHoldTime.Add(totals.totalHoldTime)
+	el.dailyWaitCount.Add(totals.waitCount)
+	el.dailyHoldCount.Add(totals.holdCount)
+	el.updateMaxQueueLength(int32(totals.maxQueueLength))
+	el.updateMaxWaitTime(totals.maxWaitTime)
+	el.updateMaxHoldTime(totals.maxHoldTime)
+
+	el.histMu.Lock()
+	el.dailyHists.merge(&totals.hists)
+	for ticketID, extends := range totals.heldExtends {
+		el.heldExtends[ticketID] = extends
+	}
+	el.histMu.Unlock()
+
+	el.toolUsageMu.Lock()
+	for toolID, restored := range totals.tools {
+		stats := el.getToolStats(toolID)
+		stats.lockCount += restored.lockCount
+		stats.grantCount += restored.grantCount
+		stats.totalWaitTime += restored.totalWaitTime
+		stats.totalHoldTime += restored.totalHoldTime
+		stats.hists.merge(&restored.hists)
+	}
+	el.toolUsageMu.Unlock()
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package logger
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"clipboard-controller/config"
+	"clipboard-controller/model"
+)
+
+// Held locks that expire count in the hold time distributions, live and
+// when the totals are rebuilt from the log files
+func TestExpiredLocksInHoldDistribution(t *testing.T) {
+	lfm, err := NewLogFileManager(t.TempDir(), 7, 100, OverflowBlock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	el := NewEventLogger(lfm, config.NewStore(config.Default(), config.Sources{}))
+
+	el.LogLockGranted("released", "tool_a", "thread_1", 10)
+	el.LogLockReleased("released", "tool_a", "thread_1", 200)
+	el.LogLockGranted("expired", "tool_a", "thread_2", 10)
+	el.LogLockExpired("expired", "tool_a", "thread_2", "max_duration_expired", 30000)
+	el.LogTicketExpired("queued", "tool_a", "thread_3", "ttl_expired")
+
+	live := el.PeekDistributions()
+	for name, dist := range map[string]*model.Distribution{
+		"today":  live.Today.HoldTimeMs,
+		"tool_a": live.Tools["tool_a"].HoldTimeMs,
+	} {
+		if dist == nil || dist.Count != 2 || dist.Max != 30000 {
+			t.Errorf("live %s hold distribution = %+v, want 2 values up to 30000", name, dist)
+		}
+	}
+
+	if err := lfm.Close(); err != nil {
+		t.Fatal(err)
+	}
+	totals, err := lfm.dailyTotals(context.Background(), time.Now().Format("2006-01-02"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h := totals.hists.hold; h.count != 2 || h.max != 30000 {
+		t.Errorf("restored hold distribution: count %d max %d, want 2 and 30000", h.count, h.max)
+	}
+	if h := totals.tools["tool_a"].hists.hold; h.count != 2 || h.max != 30000 {
+		t.Errorf("restored tool_a hold distribution: count %d max %d, want 2 and 30000", h.count, h.max)
+	}
+}
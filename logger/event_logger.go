@@ -2,24 +2,31 @@ package logger
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"clipboard-controller/config"
 	"clipboard-controller/model"
 )
 
 // EventLogger logs lock and tool events, and collects metrics
 type EventLogger struct {
-	fileManager   *LogFileManager
-	logHeartbeats bool
-	mu            sync.Mutex
+	fileManager *LogFileManager
+	config      *config.Store
+	mu          sync.Mutex
 
 	// Recent events buffer for debugging (circular buffer)
 	recentEvents   []model.LockEventLog
 	recentEventIdx int
 	recentEventMu  sync.RWMutex
 
+	// Live lock and tool event subscribers (dashboard push, webhooks)
+	subscribersMu   sync.Mutex
+	subscribers     map[chan model.LockEventLog]struct{}
+	toolSubscribers map[chan model.ToolEventLog]struct{}
+
 	// Metrics counters (reset every minute)
 	locksGranted   atomic.Int64
 	locksReleased  atomic.Int64
@@ -53,32 +60,58 @@ type EventLogger struct {
 	dailyMaxWaitTime     atomic.Int64
 	dailyMaxHoldTime     atomic.Int64
 
+	// Distributions of the current minute and day (see histogram.go), and the
+	// extend count of each lock being held, by ticket ID
+	histMu      sync.Mutex
+	minuteHists lockHistograms
+	dailyHists  lockHistograms
+	heldExtends map[string]int64
+
 	// Per-tool usage tracking
 	toolUsageMu sync.Mutex
 	toolUsage   map[string]*toolStats
+
+	// Tool metadata attached to events, keyed by tool ID
+	toolInfoMu sync.RWMutex
+	toolInfo   map[string]model.ToolInfo
+
+	// Auth failures not yet written to the audit log (see LogAuthFailure)
+	authFailures authFailures
 }
 
 type toolStats struct {
-	lockCount     int64
+	lockCount     int64 // Locks released
+	grantCount    int64 // Locks granted
 	totalWaitTime int64
 	totalHoldTime int64
+	hists         lockHistograms
 }
 
 const recentEventsBufferSize = 100
 
 // NewEventLogger creates a new event logger
-func NewEventLogger(fm *LogFileManager) *EventLogger {
+func NewEventLogger(fm *LogFileManager, cfg *config.Store) *EventLogger {
 	return &EventLogger{
-		fileManager:   fm,
-		logHeartbeats: false, // Default: don't log heartbeats (too noisy)
-		toolUsage:     make(map[string]*toolStats),
-		recentEvents:  make([]model.LockEventLog, recentEventsBufferSize),
+		fileManager:     fm,
+		config:          cfg,
+		toolUsage:       make(map[string]*toolStats),
+		toolInfo:        make(map[string]model.ToolInfo),
+		heldExtends:     make(map[string]int64),
+		subscribers:     make(map[chan model.LockEventLog]struct{}),
+		toolSubscribers: make(map[chan model.ToolEventLog]struct{}),
+		recentEvents:    make([]model.LockEventLog, recentEventsBufferSize),
 	}
 }
 
-// SetLogHeartbeats enables/disables heartbeat logging
-func (el *EventLogger) SetLogHeartbeats(enabled bool) {
-	el.logHeartbeats = enabled
+// writeEvent queues an event for its log file when log_events is on and the
+// event passes the stream's sampling rules. Metrics and the recent events
+// buffer are not affected.
+func (el *EventLogger) writeEvent(stream, eventType string, event model.Sequenced) {
+	cfg := el.config.Get()
+	if !cfg.LogEvents || !sampled(cfg, stream, eventType) {
+		return
+	}
+	el.fileManager.Enqueue(stream, event)
 }
 
 // addToRecentEvents adds an event to the circular buffer for debugging
@@ -88,6 +121,78 @@ func (el *EventLogger) addToRecentEvents(event model.LockEventLog) {
 
 	el.recentEvents[el.recentEventIdx] = event
 	el.recentEventIdx = (el.recentEventIdx + 1) % recentEventsBufferSize
+
+	el.publish(event)
+}
+
+// Subscribe returns a channel receiving every lock event as it is logged,
+// and a function to unsubscribe. Events are dropped for a subscriber whose
+// buffer is full.
+func (el *EventLogger) Subscribe(buffer int) (<-chan model.LockEventLog, func()) {
+	ch := make(chan model.LockEventLog, buffer)
+
+	el.subscribersMu.Lock()
+	el.subscribers[ch] = struct{}{}
+	el.subscribersMu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			el.subscribersMu.Lock()
+			delete(el.subscribers, ch)
+			el.subscribersMu.Unlock()
+		})
+	}
+
+	return ch, unsubscribe
+}
+
+// SubscribeTools returns a channel receiving every tool event except
+// heartbeats, and a function to unsubscribe. Events are dropped for a
+// subscriber whose buffer is full.
+func (el *EventLogger) SubscribeTools(buffer int) (<-chan model.ToolEventLog, func()) {
+	ch := make(chan model.ToolEventLog, buffer)
+
+	el.subscribersMu.Lock()
+	el.toolSubscribers[ch] = struct{}{}
+	el.subscribersMu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			el.subscribersMu.Lock()
+			delete(el.toolSubscribers, ch)
+			el.subscribersMu.Unlock()
+		})
+	}
+
+	return ch, unsubscribe
+}
+
+// publishTool sends a tool event to all tool subscribers without blocking
+func (el *EventLogger) publishTool(event model.ToolEventLog) {
+	el.subscribersMu.Lock()
+	defer el.subscribersMu.Unlock()
+
+	for ch := range el.toolSubscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// publish sends an event to all subscribers without blocking
+func (el *EventLogger) publish(event model.LockEventLog) {
+	el.subscribersMu.Lock()
+	defer el.subscribersMu.Unlock()
+
+	for ch := range el.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
 }
 
 // GetRecentEvents returns the most recent lock events for debugging
@@ -123,14 +228,16 @@ func (el *EventLogger) LogLockRequestedWithContext(requestID, ticketID, toolID,
 		RequestID:     requestID,
 		TicketID:      ticketID,
 		ToolID:        toolID,
+		ToolInfo:      el.getToolInfo(toolID),
 		ThreadID:      threadID,
 		QueuePosition: queuePosition,
 		QueueLength:   queueLength,
 	}
 
 	el.updateMaxQueueLength(int32(queueLength))
+	el.observe(func(h *lockHistograms) { h.queue.record(int64(queueLength)) })
+	el.writeEvent("lock_events", event.EventType, &event)
 	el.addToRecentEvents(event)
-	go el.fileManager.WriteJSON("lock_events", event)
 }
 
 // LogLockRequested logs a lock request event (backward compatible)
@@ -146,6 +253,7 @@ func (el *EventLogger) LogLockGrantedWithContext(requestID, ticketID, toolID, th
 		RequestID:      requestID,
 		TicketID:       ticketID,
 		ToolID:         toolID,
+		ToolInfo:       el.getToolInfo(toolID),
 		ThreadID:       threadID,
 		WaitDurationMs: waitDurationMs,
 		QueueLength:    queueLength,
@@ -158,9 +266,11 @@ func (el *EventLogger) LogLockGrantedWithContext(requestID, ticketID, toolID, th
 	el.waitCount.Add(1)
 	el.dailyWaitCount.Add(1)
 	el.updateMaxWaitTime(waitDurationMs)
+	el.observe(func(h *lockHistograms) { h.wait.record(waitDurationMs) })
+	el.recordToolWait(toolID, waitDurationMs)
 
+	el.writeEvent("lock_events", event.EventType, &event)
 	el.addToRecentEvents(event)
-	go el.fileManager.WriteJSON("lock_events", event)
 }
 
 // LogLockGranted logs a lock granted event (backward compatible)
@@ -176,6 +286,7 @@ func (el *EventLogger) LogLockReleasedWithContext(requestID, ticketID, toolID, t
 		RequestID:      requestID,
 		TicketID:       ticketID,
 		ToolID:         toolID,
+		ToolInfo:       el.getToolInfo(toolID),
 		ThreadID:       threadID,
 		HoldDurationMs: holdDurationMs,
 		QueueLength:    queueLength,
@@ -188,10 +299,15 @@ func (el *EventLogger) LogLockReleasedWithContext(requestID, ticketID, toolID, t
 	el.holdCount.Add(1)
 	el.dailyHoldCount.Add(1)
 	el.updateMaxHoldTime(holdDurationMs)
-	el.updateToolUsage(toolID, holdDurationMs, 0)
-
+	extends := el.endLock(ticketID)
+	el.observe(func(h *lockHistograms) {
+		h.hold.record(holdDurationMs)
+		h.extend.record(extends)
+	})
+	el.updateToolUsage(toolID, holdDurationMs, extends)
+
+	el.writeEvent("lock_events", event.EventType, &event)
 	el.addToRecentEvents(event)
-	go el.fileManager.WriteJSON("lock_events", event)
 }
 
 // LogLockReleased logs a lock released event (backward compatible)
@@ -206,6 +322,7 @@ func (el *EventLogger) LogLockExpired(ticketID, toolID, threadID, reason string,
 		EventType:      model.LockEventExpired,
 		TicketID:       ticketID,
 		ToolID:         toolID,
+		ToolInfo:       el.getToolInfo(toolID),
 		ThreadID:       threadID,
 		HoldDurationMs: holdDurationMs,
 		Reason:         reason,
@@ -213,24 +330,41 @@ func (el *EventLogger) LogLockExpired(ticketID, toolID, threadID, reason string,
 
 	el.locksExpired.Add(1)
 	el.dailyLocksExpired.Add(1)
-
+	extends := el.endLock(ticketID)
+	el.observe(func(h *lockHistograms) {
+		h.hold.record(holdDurationMs)
+		h.extend.record(extends)
+	})
+	el.recordToolExpiry(toolID, holdDurationMs, extends)
+
+	el.writeEvent("lock_events", event.EventType, &event)
 	el.addToRecentEvents(event)
-	go el.fileManager.WriteJSON("lock_events", event)
 }
 
-// LogLockExtended logs a lock extended event
-func (el *EventLogger) LogLockExtended(ticketID, toolID, threadID string, extendCount int) {
+// LogLockExtendedWithContext logs a lock extended event with request context
+func (el *EventLogger) LogLockExtendedWithContext(requestID, ticketID, toolID, threadID string, extendCount int) {
 	event := model.LockEventLog{
 		Timestamp: time.Now(),
 		EventType: model.LockEventExtended,
+		RequestID: requestID,
 		TicketID:  ticketID,
 		ToolID:    toolID,
+		ToolInfo:  el.getToolInfo(toolID),
 		ThreadID:  threadID,
 		Reason:    fmt.Sprintf("extend_%d", extendCount),
 	}
 
+	el.histMu.Lock()
+	el.heldExtends[ticketID] = int64(extendCount)
+	el.histMu.Unlock()
+
+	el.writeEvent("lock_events", event.EventType, &event)
 	el.addToRecentEvents(event)
-	go el.fileManager.WriteJSON("lock_events", event)
+}
+
+// LogLockExtended logs a lock extended event (backward compatible)
+func (el *EventLogger) LogLockExtended(ticketID, toolID, threadID string, extendCount int) {
+	el.LogLockExtendedWithContext("", ticketID, toolID, threadID, extendCount)
 }
 
 // LogTicketExpired logs a ticket TTL expiry
@@ -240,33 +374,41 @@ func (el *EventLogger) LogTicketExpired(ticketID, toolID, threadID, reason strin
 		EventType: model.LockEventExpired,
 		TicketID:  ticketID,
 		ToolID:    toolID,
+		ToolInfo:  el.getToolInfo(toolID),
 		ThreadID:  threadID,
 		Reason:    reason,
 	}
 
 	el.expiredTickets.Add(1)
 
+	el.writeEvent("lock_events", event.EventType, &event)
 	el.addToRecentEvents(event)
-	go el.fileManager.WriteJSON("lock_events", event)
 }
 
-// LogToolRegistered logs a tool registration event
-func (el *EventLogger) LogToolRegistered(toolID string) {
+// LogToolRegistered logs a tool registration event and remembers the tool's
+// metadata for its later events
+func (el *EventLogger) LogToolRegistered(toolID string, info model.ToolInfo) {
+	el.toolInfoMu.Lock()
+	el.toolInfo[toolID] = info.Clone()
+	el.toolInfoMu.Unlock()
+
 	event := model.ToolEventLog{
 		Timestamp: time.Now(),
 		EventType: model.ToolEventRegistered,
 		ToolID:    toolID,
+		ToolInfo:  el.getToolInfo(toolID),
 	}
 
 	el.dailyToolsRegistered.Add(1)
 
-	go el.fileManager.WriteJSON("tool_events", event)
+	el.publishTool(event)
+	el.writeEvent("tool_events", event.EventType, &event)
 }
 
-// LogToolHeartbeat logs a tool heartbeat event (controlled by logHeartbeats flag)
+// LogToolHeartbeat logs a tool heartbeat event (controlled by log_heartbeats)
 func (el *EventLogger) LogToolHeartbeat(toolID string) {
 	// Skip if heartbeat logging is disabled (default)
-	if !el.logHeartbeats {
+	if !el.config.Get().LogHeartbeats {
 		return
 	}
 
@@ -274,9 +416,10 @@ func (el *EventLogger) LogToolHeartbeat(toolID string) {
 		Timestamp: time.Now(),
 		EventType: model.ToolEventHeartbeat,
 		ToolID:    toolID,
+		ToolInfo:  el.getToolInfo(toolID),
 	}
 
-	go el.fileManager.WriteJSON("tool_events", event)
+	el.writeEvent("tool_events", event.EventType, &event)
 }
 
 // LogToolOffline logs a tool going offline
@@ -285,10 +428,12 @@ func (el *EventLogger) LogToolOffline(toolID, reason string) {
 		Timestamp: time.Now(),
 		EventType: model.ToolEventOffline,
 		ToolID:    toolID,
+		ToolInfo:  el.getToolInfo(toolID),
 		Reason:    reason,
 	}
 
-	go el.fileManager.WriteJSON("tool_events", event)
+	el.publishTool(event)
+	el.writeEvent("tool_events", event.EventType, &event)
 }
 
 // LogToolUnregistered logs a tool unregistration
@@ -297,9 +442,41 @@ func (el *EventLogger) LogToolUnregistered(toolID string) {
 		Timestamp: time.Now(),
 		EventType: model.ToolEventUnregistered,
 		ToolID:    toolID,
+		ToolInfo:  el.getToolInfo(toolID),
 	}
 
-	go el.fileManager.WriteJSON("tool_events", event)
+	el.publishTool(event)
+	el.writeEvent("tool_events", event.EventType, &event)
+}
+
+// getToolInfo returns the registered metadata of a tool, nil if unknown
+func (el *EventLogger) getToolInfo(toolID string) *model.ToolInfo {
+	el.toolInfoMu.RLock()
+	defer el.toolInfoMu.RUnlock()
+
+	info, ok := el.toolInfo[toolID]
+	if !ok {
+		return nil
+	}
+	return &info
+}
+
+// LogToolRemoved logs a tool removed from the registry after staying offline
+// and forgets its metadata
+func (el *EventLogger) LogToolRemoved(toolID string) {
+	event := model.ToolEventLog{
+		Timestamp: time.Now(),
+		EventType: model.ToolEventRemoved,
+		ToolID:    toolID,
+		ToolInfo:  el.getToolInfo(toolID),
+	}
+
+	el.toolInfoMu.Lock()
+	delete(el.toolInfo, toolID)
+	el.toolInfoMu.Unlock()
+
+	el.publishTool(event)
+	el.writeEvent("tool_events", event.EventType, &event)
 }
 
 // IncrementRequests increments the daily request counter
@@ -330,6 +507,11 @@ func (el *EventLogger) GetMinuteMetrics() model.SystemMetricsLog {
 		avgHold = totalHold / holdCount
 	}
 
+	el.histMu.Lock()
+	distributions := el.minuteHists.distributions(el.config.Get().MetricsPercentiles)
+	el.minuteHists = lockHistograms{}
+	el.histMu.Unlock()
+
 	metrics := model.SystemMetricsLog{
 		Timestamp:           time.Now(),
 		LocksGrantedLastMin: el.locksGranted.Swap(0),
@@ -337,6 +519,7 @@ func (el *EventLogger) GetMinuteMetrics() model.SystemMetricsLog {
 		FailedRequests:      el.failedRequests.Swap(0),
 		AvgWaitTimeMs:       avgWait,
 		AvgHoldTimeMs:       avgHold,
+		Distributions:       distributions,
 	}
 
 	// Reset lock counters
@@ -348,13 +531,71 @@ func (el *EventLogger) GetMinuteMetrics() model.SystemMetricsLog {
 
 // GetDailySummary returns the daily summary and resets counters
 func (el *EventLogger) GetDailySummary() model.DailySummaryLog {
+	summary := el.dailySummary(true)
+
+	// Reset tool usage
+	el.toolUsageMu.Lock()
+	el.toolUsage = make(map[string]*toolStats)
+	el.toolUsageMu.Unlock()
+
+	return summary
+}
+
+// PeekDailySummary returns the summary of the day so far without resetting
+// counters
+func (el *EventLogger) PeekDailySummary() model.DailySummaryLog {
+	return el.dailySummary(false)
+}
+
+// DistributionsSnapshot is the state of the distributions so far
+type DistributionsSnapshot struct {
+	Percentiles []float64                       `json:"percentiles"`
+	Minute      *model.Distributions            `json:"minute"` // Current minute so far
+	Today       *model.Distributions            `json:"today"`
+	Tools       map[string]*model.Distributions `json:"tools"` // Today, by tool ID
+}
+
+// PeekDistributions returns the distributions of the current minute, of
+// today and of each tool today, without resetting them
+func (el *EventLogger) PeekDistributions() DistributionsSnapshot {
+	percentiles := el.config.Get().MetricsPercentiles
+	snapshot := DistributionsSnapshot{
+		Percentiles: percentiles,
+		Tools:       make(map[string]*model.Distributions),
+	}
+
+	el.histMu.Lock()
+	snapshot.Minute = el.minuteHists.distributions(percentiles)
+	snapshot.Today = el.dailyHists.distributions(percentiles)
+	el.histMu.Unlock()
+
+	el.toolUsageMu.Lock()
+	for toolID, stats := range el.toolUsage {
+		if d := stats.hists.distributions(percentiles); d != nil {
+			snapshot.Tools[toolID] = d
+		}
+	}
+	el.toolUsageMu.Unlock()
+
+	return snapshot
+}
+
+// dailySummary builds the daily summary, resetting the counters if reset
+func (el *EventLogger) dailySummary(reset bool) model.DailySummaryLog {
+	take := func(v *atomic.Int64) int64 {
+		if reset {
+			return v.Swap(0)
+		}
+		return v.Load()
+	}
+
 	avgWait := int64(0)
 	avgHold := int64(0)
 
-	waitCount := el.dailyWaitCount.Swap(0)
-	holdCount := el.dailyHoldCount.Swap(0)
-	totalWait := el.dailyTotalWaitTime.Swap(0)
-	totalHold := el.dailyTotalHoldTime.Swap(0)
+	waitCount := take(&el.dailyWaitCount)
+	holdCount := take(&el.dailyHoldCount)
+	totalWait := take(&el.dailyTotalWaitTime)
+	totalHold := take(&el.dailyTotalHoldTime)
 
 	if waitCount > 0 {
 		avgWait = totalWait / waitCount
@@ -363,28 +604,35 @@ func (el *EventLogger) GetDailySummary() model.DailySummaryLog {
 		avgHold = totalHold / holdCount
 	}
 
-	summary := model.DailySummaryLog{
+	maxQueueLength := el.dailyMaxQueueLength.Load()
+	if reset {
+		maxQueueLength = el.dailyMaxQueueLength.Swap(0)
+	}
+
+	percentiles := el.config.Get().MetricsPercentiles
+	el.histMu.Lock()
+	distributions := el.dailyHists.distributions(percentiles)
+	if reset {
+		el.dailyHists = lockHistograms{}
+	}
+	el.histMu.Unlock()
+
+	return model.DailySummaryLog{
 		Date:                 time.Now().Format("2006-01-02"),
-		TotalRequests:        el.dailyRequests.Swap(0),
-		TotalLocksGranted:    el.dailyLocksGranted.Swap(0),
-		TotalLocksExpired:    el.dailyLocksExpired.Swap(0),
-		TotalLocksReleased:   el.dailyLocksReleased.Swap(0),
-		TotalToolsRegistered: el.dailyToolsRegistered.Swap(0),
+		TotalRequests:        take(&el.dailyRequests),
+		TotalLocksGranted:    take(&el.dailyLocksGranted),
+		TotalLocksExpired:    take(&el.dailyLocksExpired),
+		TotalLocksReleased:   take(&el.dailyLocksReleased),
+		TotalToolsRegistered: take(&el.dailyToolsRegistered),
 		AvgWaitTimeMs:        avgWait,
 		AvgHoldTimeMs:        avgHold,
-		MaxQueueLength:       int(el.dailyMaxQueueLength.Swap(0)),
-		MaxWaitTimeMs:        el.dailyMaxWaitTime.Swap(0),
-		MaxHoldTimeMs:        el.dailyMaxHoldTime.Swap(0),
-		ErrorCount:           el.dailyErrors.Swap(0),
-		TopTools:             el.getTopTools(10),
+		MaxQueueLength:       int(maxQueueLength),
+		MaxWaitTimeMs:        take(&el.dailyMaxWaitTime),
+		MaxHoldTimeMs:        take(&el.dailyMaxHoldTime),
+		ErrorCount:           take(&el.dailyErrors),
+		TopTools:             el.getTopTools(10, percentiles),
+		Distributions:        distributions,
 	}
-
-	// Reset tool usage
-	el.toolUsageMu.Lock()
-	el.toolUsage = make(map[string]*toolStats)
-	el.toolUsageMu.Unlock()
-
-	return summary
 }
 
 // Helper methods
@@ -434,66 +682,123 @@ func (el *EventLogger) updateMaxHoldTime(ms int64) {
 	}
 }
 
-func (el *EventLogger) updateToolUsage(toolID string, holdTime, waitTime int64) {
+func (el *EventLogger) updateToolUsage(toolID string, holdTime, extends int64) {
 	el.toolUsageMu.Lock()
 	defer el.toolUsageMu.Unlock()
 
-	stats, ok := el.toolUsage[toolID]
-	if !ok {
-		stats = &toolStats{}
-		el.toolUsage[toolID] = stats
-	}
-
+	stats := el.getToolStats(toolID)
 	stats.lockCount++
 	stats.totalHoldTime += holdTime
+	stats.hists.hold.record(holdTime)
+	stats.hists.extend.record(extends)
+}
+
+func (el *EventLogger) recordToolWait(toolID string, waitTime int64) {
+	el.toolUsageMu.Lock()
+	defer el.toolUsageMu.Unlock()
+
+	stats := el.getToolStats(toolID)
+	stats.grantCount++
 	stats.totalWaitTime += waitTime
+	stats.hists.wait.record(waitTime)
 }
 
-func (el *EventLogger) getTopTools(limit int) []model.ToolUsage {
+// recordToolExpiry records a held lock taken away from the tool: its hold
+// time, the longest ones, counts in the distribution but not in the average
+// of released locks
+func (el *EventLogger) recordToolExpiry(toolID string, holdTime, extends int64) {
 	el.toolUsageMu.Lock()
 	defer el.toolUsageMu.Unlock()
 
-	// Convert to slice for sorting
-	type toolEntry struct {
-		toolID string
-		stats  *toolStats
+	stats := el.getToolStats(toolID)
+	stats.hists.hold.record(holdTime)
+	stats.hists.extend.record(extends)
+}
+
+// observe records values in the minute and daily histograms
+func (el *EventLogger) observe(record func(*lockHistograms)) {
+	el.histMu.Lock()
+	defer el.histMu.Unlock()
+
+	record(&el.minuteHists)
+	record(&el.dailyHists)
+}
+
+// endLock returns how often the lock of a ticket was extended and forgets it
+func (el *EventLogger) endLock(ticketID string) int64 {
+	el.histMu.Lock()
+	defer el.histMu.Unlock()
+
+	extends := el.heldExtends[ticketID]
+	delete(el.heldExtends, ticketID)
+	return extends
+}
+
+// getToolStats returns the usage entry of a tool, creating it if needed.
+// Caller must hold toolUsageMu.
+func (el *EventLogger) getToolStats(toolID string) *toolStats {
+	stats, ok := el.toolUsage[toolID]
+	if !ok {
+		stats = &toolStats{}
+		el.toolUsage[toolID] = stats
 	}
+	return stats
+}
+
+func (el *EventLogger) getTopTools(limit int, percentiles []float64) []model.ToolUsage {
+	el.toolUsageMu.Lock()
+	defer el.toolUsageMu.Unlock()
 
-	entries := make([]toolEntry, 0, len(el.toolUsage))
+	usage := make([]model.ToolUsage, 0, len(el.toolUsage))
 	for id, stats := range el.toolUsage {
-		entries = append(entries, toolEntry{id, stats})
+		usage = append(usage, stats.usage(id, percentiles))
 	}
+	return topToolUsage(usage, limit)
+}
 
-	// Sort by lock count descending
-	for i := 0; i < len(entries)-1; i++ {
-		for j := i + 1; j < len(entries); j++ {
-			if entries[j].stats.lockCount > entries[i].stats.lockCount {
-				entries[i], entries[j] = entries[j], entries[i]
-			}
+// topToolUsage sorts usage by lock count descending, then tool ID, and keeps
+// the first limit entries
+func topToolUsage(usage []model.ToolUsage, limit int) []model.ToolUsage {
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].LockCount != usage[j].LockCount {
+			return usage[i].LockCount > usage[j].LockCount
 		}
+		return usage[i].ToolID < usage[j].ToolID
+	})
+	if len(usage) > limit {
+		usage = usage[:limit]
 	}
+	return usage
+}
 
-	// Take top N
-	if len(entries) > limit {
-		entries = entries[:limit]
-	}
+// GetToolUsage returns today's lock usage of a tool
+func (el *EventLogger) GetToolUsage(toolID string) model.ToolUsage {
+	percentiles := el.config.Get().MetricsPercentiles
 
-	// Convert to result
-	result := make([]model.ToolUsage, len(entries))
-	for i, e := range entries {
-		avgWait := int64(0)
-		avgHold := int64(0)
-		if e.stats.lockCount > 0 {
-			avgWait = e.stats.totalWaitTime / e.stats.lockCount
-			avgHold = e.stats.totalHoldTime / e.stats.lockCount
-		}
-		result[i] = model.ToolUsage{
-			ToolID:    e.toolID,
-			LockCount: e.stats.lockCount,
-			AvgWaitMs: avgWait,
-			AvgHoldMs: avgHold,
-		}
+	el.toolUsageMu.Lock()
+	defer el.toolUsageMu.Unlock()
+
+	stats, ok := el.toolUsage[toolID]
+	if !ok {
+		return model.ToolUsage{ToolID: toolID}
 	}
+	return stats.usage(toolID, percentiles)
+}
 
-	return result
+func (s *toolStats) usage(toolID string, percentiles []float64) model.ToolUsage {
+	avgWait := int64(0)
+	avgHold := int64(0)
+	if s.grantCount > 0 {
+		avgWait = s.totalWaitTime / s.grantCount
+	}
+	if s.lockCount > 0 {
+		avgHold = s.totalHoldTime / s.lockCount
+	}
+	return model.ToolUsage{
+		ToolID:        toolID,
+		LockCount:     s.lockCount,
+		AvgWaitMs:     avgWait,
+		AvgHoldMs:     avgHold,
+		Distributions: s.hists.distributions(percentiles),
+	}
 }
@@ -6,14 +6,22 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"clipboard-controller/model"
+
 	"github.com/rs/zerolog/log"
 )
 
+// Overflow policies for the log queue
+const (
+	OverflowBlock      = "block"       // Callers wait for room
+	OverflowDropOldest = "drop_oldest" // The oldest queued entry is discarded and counted
+)
+
 // LogFileManager manages log file creation, rotation, and cleanup
 type LogFileManager struct {
 	baseDir       string
@@ -22,17 +30,56 @@ type LogFileManager struct {
 	writers       map[string]*bufferedWriter
 	flushTicker   *time.Ticker
 	stopChan      chan struct{}
+
+	// Ordered write queue drained by a single writer goroutine. Audit
+	// entries have a queue of their own that never drops.
+	queue          chan queuedEntry
+	auditQueue     chan queuedEntry
+	overflowPolicy string
+	queueMu        sync.Mutex // Serializes Enqueue so seq matches queue order
+	seq            uint64
+	queueClosed    bool
+	dropped        atomic.Int64
+	writerDone     chan struct{}
+
+	// Size-based rotation, compression and disk budget (see SetRotation)
+	maxFileSize  int64
+	maxTotalSize int64
+	compress     bool
+	compressMu   sync.Mutex
+	compressing  map[string]bool
+	budgetMu     sync.Mutex
+	background   sync.WaitGroup
+
+	// Extra destinations of written entries (see SetSinks)
+	sinks []Sink
+
+	// Hash of the last written audit entry, owned by the writer goroutine
+	auditHead string
+}
+
+// auditQueueSize is the capacity of the audit queue; Enqueue blocks when it
+// is full, whatever the overflow policy
+const auditQueueSize = 256
+
+type queuedEntry struct {
+	logType string
+	entry   model.Sequenced
 }
 
 type bufferedWriter struct {
-	file   *os.File
-	writer *bufio.Writer
-	path   string
-	date   string
+	file    *os.File
+	writer  *bufio.Writer
+	path    string
+	date    string
+	segment int
+	size    int64
 }
 
-// NewLogFileManager creates a new log file manager
-func NewLogFileManager(baseDir string, retentionDays int) (*LogFileManager, error) {
+// NewLogFileManager creates a new log file manager. Entries passed to Enqueue
+// are buffered in a queue of queueSize entries; overflowPolicy decides what
+// happens when it is full. Audit entries are never dropped.
+func NewLogFileManager(baseDir string, retentionDays, queueSize int, overflowPolicy string) (*LogFileManager, error) {
 	// Create base directory and subdirectories
 	dirs := []string{
 		baseDir,
@@ -41,6 +88,7 @@ func NewLogFileManager(baseDir string, retentionDays int) (*LogFileManager, erro
 		filepath.Join(baseDir, "events", "tool"),
 		filepath.Join(baseDir, "metrics"),
 		filepath.Join(baseDir, "summary"),
+		filepath.Join(baseDir, "audit"),
 	}
 
 	for _, dir := range dirs {
@@ -55,19 +103,50 @@ func NewLogFileManager(baseDir string, retentionDays int) (*LogFileManager, erro
 		writers:       make(map[string]*bufferedWriter),
 		flushTicker:   time.NewTicker(5 * time.Second),
 		stopChan:      make(chan struct{}),
+
+		queue:          make(chan queuedEntry, queueSize),
+		auditQueue:     make(chan queuedEntry, auditQueueSize),
+		overflowPolicy: overflowPolicy,
+		writerDone:     make(chan struct{}),
+
+		compressing: make(map[string]bool),
+	}
+
+	// Continue the audit hash chain; a broken chain shows up in VerifyAudit
+	if err := lfm.loadAuditHead(); err != nil {
+		log.Warn().Err(err).Msg("Failed to read the last audit log entry, starting a new chain")
 	}
 
-	// Start background flush goroutine
+	// Start background flush and writer goroutines
 	go lfm.flushLoop()
+	go lfm.writeLoop()
 
 	log.Info().
 		Str("base_dir", baseDir).
 		Int("retention_days", retentionDays).
+		Int("queue_size", queueSize).
+		Str("overflow_policy", overflowPolicy).
 		Msg("Log file manager initialized")
 
 	return lfm, nil
 }
 
+// SetRotation sets the segment size limit, the total size limit of the log
+// files (both in MB, 0 = no limit) and whether closed files are gzipped. It
+// must be called before the first entry is written.
+func (lfm *LogFileManager) SetRotation(maxFileSizeMB, maxTotalSizeMB int, compress bool) {
+	lfm.maxFileSize = int64(maxFileSizeMB) * 1024 * 1024
+	lfm.maxTotalSize = int64(maxTotalSizeMB) * 1024 * 1024
+	lfm.compress = compress
+}
+
+// SetSinks sets the sinks that receive every written entry of the streams
+// they want. It must be called before the first entry is written; Close
+// closes them.
+func (lfm *LogFileManager) SetSinks(sinks []Sink) {
+	lfm.sinks = sinks
+}
+
 // flushLoop periodically flushes all buffered writers
 func (lfm *LogFileManager) flushLoop() {
 	for {
@@ -80,6 +159,109 @@ func (lfm *LogFileManager) flushLoop() {
 	}
 }
 
+// writeLoop writes queued entries, each queue in order, until both queues
+// are closed
+func (lfm *LogFileManager) writeLoop() {
+	defer close(lfm.writerDone)
+
+	queue, auditQueue := lfm.queue, lfm.auditQueue
+	for queue != nil || auditQueue != nil {
+		select {
+		case item, ok := <-auditQueue:
+			if !ok {
+				auditQueue = nil
+				continue
+			}
+			lfm.write(item)
+		case item, ok := <-queue:
+			if !ok {
+				queue = nil
+				continue
+			}
+			lfm.write(item)
+		}
+	}
+}
+
+// write writes one queued entry to its file and the sinks that want it
+func (lfm *LogFileManager) write(item queuedEntry) {
+	data, auditHash, err := lfm.encode(item)
+	if err != nil {
+		log.Warn().Err(err).Str("log_type", item.logType).Msg("Failed to marshal log entry")
+		return
+	}
+	if err := lfm.writeLine(item.logType, data); err != nil {
+		log.Warn().Err(err).Str("log_type", item.logType).Msg("Failed to write log entry")
+	} else if auditHash != "" {
+		// Audit entries go to disk right away
+		lfm.auditHead = auditHash
+		lfm.FlushAll()
+	}
+	for _, sink := range lfm.sinks {
+		if sink.WantsStream(item.logType) {
+			sink.Write(item.logType, data)
+		}
+	}
+}
+
+// Enqueue stamps entry with the next sequence number and queues it for the
+// writer. Entries are written in sequence order, audit entries in their own
+// queue. When the queue is full the call blocks or drops the oldest entry,
+// depending on the overflow policy; audit entries always block, so they are
+// never dropped. Entries enqueued after Close are dropped.
+func (lfm *LogFileManager) Enqueue(logType string, entry model.Sequenced) {
+	lfm.queueMu.Lock()
+	defer lfm.queueMu.Unlock()
+
+	if lfm.queueClosed {
+		lfm.dropped.Add(1)
+		return
+	}
+
+	lfm.seq++
+	entry.SetSeq(lfm.seq)
+	item := queuedEntry{logType: logType, entry: entry}
+
+	if logType == AuditLogType {
+		lfm.auditQueue <- item
+		return
+	}
+	if lfm.overflowPolicy != OverflowDropOldest {
+		lfm.queue <- item
+		return
+	}
+
+	for {
+		select {
+		case lfm.queue <- item:
+			return
+		default:
+		}
+
+		select {
+		case <-lfm.queue:
+			lfm.dropped.Add(1)
+		default:
+		}
+	}
+}
+
+// QueueStats returns the state of the write queue
+func (lfm *LogFileManager) QueueStats() map[string]interface{} {
+	lfm.queueMu.Lock()
+	seq := lfm.seq
+	lfm.queueMu.Unlock()
+
+	return map[string]interface{}{
+		"last_seq":        seq,
+		"queued":          len(lfm.queue),
+		"capacity":        cap(lfm.queue),
+		"audit_queued":    len(lfm.auditQueue),
+		"dropped":         lfm.dropped.Load(),
+		"overflow_policy": lfm.overflowPolicy,
+	}
+}
+
 // getWriter returns a buffered writer for the given log type and date
 func (lfm *LogFileManager) getWriter(logType string) (*bufferedWriter, error) {
 	lfm.mu.Lock()
@@ -99,44 +281,65 @@ func (lfm *LogFileManager) getWriter(logType string) (*bufferedWriter, error) {
 			bw.writer.Flush()
 			bw.file.Close()
 			delete(lfm.writers, k)
+			lfm.segmentClosed(bw.path)
 		}
 	}
 
-	// Create new file
-	var path string
-	switch logType {
-	case "requests":
-		path = filepath.Join(lfm.baseDir, "requests", today+".jsonl")
-	case "lock_events":
-		path = filepath.Join(lfm.baseDir, "events", "lock", today+".jsonl")
-	case "tool_events":
-		path = filepath.Join(lfm.baseDir, "events", "tool", today+".jsonl")
-	case "metrics":
-		path = filepath.Join(lfm.baseDir, "metrics", today+".jsonl")
-	case "summary":
-		path = filepath.Join(lfm.baseDir, "summary", today+".json")
-	default:
-		return nil, fmt.Errorf("unknown log type: %s", logType)
+	// Continue today's last segment, or start a new one
+	dir, err := lfm.typeDir(logType)
+	if err != nil {
+		return nil, err
 	}
+	bw, err := openSegment(dir, today, lfm.nextSegment(dir, today))
+	if err != nil {
+		return nil, err
+	}
+
+	lfm.writers[key] = bw
+	return bw, nil
+}
 
+// openSegment opens a day's segment for appending
+func openSegment(dir, date string, segment int) (*bufferedWriter, error) {
+	path := filepath.Join(dir, segmentName(date, segment))
 	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open log file %s: %w", path, err)
 	}
 
-	bw := &bufferedWriter{
-		file:   file,
-		writer: bufio.NewWriter(file),
-		path:   path,
-		date:   today,
+	var size int64
+	if info, err := file.Stat(); err == nil {
+		size = info.Size()
 	}
 
-	lfm.writers[key] = bw
-	return bw, nil
+	return &bufferedWriter{
+		file:    file,
+		writer:  bufio.NewWriter(file),
+		path:    path,
+		date:    date,
+		segment: segment,
+		size:    size,
+	}, nil
 }
 
-// WriteJSON writes a JSON object to the specified log type
-func (lfm *LogFileManager) WriteJSON(logType string, data interface{}) error {
+// rotate closes a full segment and continues in the next one.
+// Caller must hold mu.
+func (lfm *LogFileManager) rotate(logType string, bw *bufferedWriter) error {
+	bw.writer.Flush()
+	bw.file.Close()
+	delete(lfm.writers, logType+":"+bw.date)
+	lfm.segmentClosed(bw.path)
+
+	next, err := openSegment(filepath.Dir(bw.path), bw.date, bw.segment+1)
+	if err != nil {
+		return err
+	}
+	lfm.writers[logType+":"+bw.date] = next
+	return nil
+}
+
+// writeLine appends one JSON line to the current file of logType
+func (lfm *LogFileManager) writeLine(logType string, jsonData []byte) error {
 	bw, err := lfm.getWriter(logType)
 	if err != nil {
 		return err
@@ -145,11 +348,6 @@ func (lfm *LogFileManager) WriteJSON(logType string, data interface{}) error {
 	lfm.mu.Lock()
 	defer lfm.mu.Unlock()
 
-	jsonData, err := json.Marshal(data)
-	if err != nil {
-		return fmt.Errorf("failed to marshal log data: %w", err)
-	}
-
 	if _, err := bw.writer.Write(jsonData); err != nil {
 		return fmt.Errorf("failed to write log data: %w", err)
 	}
@@ -158,15 +356,22 @@ func (lfm *LogFileManager) WriteJSON(logType string, data interface{}) error {
 		return fmt.Errorf("failed to write newline: %w", err)
 	}
 
+	bw.size += int64(len(jsonData)) + 1
+	if lfm.maxFileSize > 0 && bw.size >= lfm.maxFileSize {
+		return lfm.rotate(logType, bw)
+	}
+
 	return nil
 }
 
-// WriteSummary writes the daily summary (overwrites the file)
-func (lfm *LogFileManager) WriteSummary(data interface{}) error {
-	today := time.Now().Format("2006-01-02")
-	path := filepath.Join(lfm.baseDir, "summary", today+".json")
+// WriteSummary writes the summary to the file of its date (overwrites the file)
+func (lfm *LogFileManager) WriteSummary(summary *model.DailySummaryLog) error {
+	if _, err := time.Parse("2006-01-02", summary.Date); err != nil {
+		return fmt.Errorf("invalid summary date %q: %w", summary.Date, err)
+	}
+	path := filepath.Join(lfm.baseDir, "summary", summary.Date+".json")
 
-	jsonData, err := json.MarshalIndent(data, "", "  ")
+	jsonData, err := json.MarshalIndent(summary, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal summary data: %w", err)
 	}
@@ -188,11 +393,34 @@ func (lfm *LogFileManager) FlushAll() {
 	}
 }
 
-// Close closes all open files
+// Close writes out everything queued, then closes all open files
 func (lfm *LogFileManager) Close() error {
+	lfm.queueMu.Lock()
+	if !lfm.queueClosed {
+		lfm.queueClosed = true
+		close(lfm.queue)
+		close(lfm.auditQueue)
+	}
+	lfm.queueMu.Unlock()
+	<-lfm.writerDone
+
+	if dropped := lfm.dropped.Load(); dropped > 0 {
+		log.Warn().Int64("dropped", dropped).Msg("Log entries were dropped")
+	}
+
 	lfm.flushTicker.Stop()
 	close(lfm.stopChan)
 
+	// Wait for closed segments to be compressed
+	lfm.background.Wait()
+
+	// Deliver or spool what the sinks still hold
+	for _, sink := range lfm.sinks {
+		if err := sink.Close(); err != nil {
+			log.Warn().Err(err).Str("sink", sink.Name()).Msg("Failed to close log sink")
+		}
+	}
+
 	lfm.mu.Lock()
 	defer lfm.mu.Unlock()
 
@@ -208,7 +436,9 @@ func (lfm *LogFileManager) Close() error {
 	return lastErr
 }
 
-// CleanupOldLogs removes log files older than retention days
+// CleanupOldLogs removes log files older than retention days, compresses
+// closed files of past days and enforces the disk budget. It returns the
+// number of files removed.
 func (lfm *LogFileManager) CleanupOldLogs() (int, error) {
 	cutoff := time.Now().AddDate(0, 0, -lfm.retentionDays)
 	removed := 0
@@ -232,13 +462,13 @@ func (lfm *LogFileManager) CleanupOldLogs() (int, error) {
 				continue
 			}
 
-			// Extract date from filename (e.g., "2025-12-25.jsonl")
+			// Extract date from filename (e.g., "2025-12-25.jsonl", "2025-12-25.1.jsonl.gz")
 			name := entry.Name()
-			dateStr := strings.TrimSuffix(strings.TrimSuffix(name, ".jsonl"), ".json")
-			fileDate, err := time.Parse("2006-01-02", dateStr)
-			if err != nil {
+			file, ok := parseLogFileName(name)
+			if !ok {
 				continue
 			}
+			fileDate, _ := time.Parse("2006-01-02", file.date)
 
 			if fileDate.Before(cutoff) {
 				path := filepath.Join(dir, name)
@@ -256,6 +486,11 @@ func (lfm *LogFileManager) CleanupOldLogs() (int, error) {
 		log.Info().Int("count", removed).Msg("Cleaned up old log files")
 	}
 
+	if compressed := lfm.compressClosedFiles(); compressed > 0 {
+		log.Info().Int("count", compressed).Msg("Compressed closed log files")
+	}
+	removed += lfm.enforceDiskBudget()
+
 	return removed, nil
 }
 
@@ -273,10 +508,12 @@ func (lfm *LogFileManager) GetStats() map[string]interface{} {
 		"tool":     filepath.Join(lfm.baseDir, "events", "tool"),
 		"metrics":  filepath.Join(lfm.baseDir, "metrics"),
 		"summary":  filepath.Join(lfm.baseDir, "summary"),
+		"audit":    filepath.Join(lfm.baseDir, "audit"),
 	}
 
 	var totalSize int64
 	filesCounts := make(map[string]int)
+	compressedCount := 0
 	var oldestDate, newestDate string
 
 	for name, dir := range dirs {
@@ -290,7 +527,14 @@ func (lfm *LogFileManager) GetStats() map[string]interface{} {
 			if entry.IsDir() {
 				continue
 			}
+			file, ok := parseLogFileName(entry.Name())
+			if !ok {
+				continue
+			}
 			count++
+			if file.compressed {
+				compressedCount++
+			}
 
 			info, err := entry.Info()
 			if err == nil {
@@ -298,7 +542,7 @@ func (lfm *LogFileManager) GetStats() map[string]interface{} {
 			}
 
 			// Track oldest/newest
-			dateStr := strings.TrimSuffix(strings.TrimSuffix(entry.Name(), ".jsonl"), ".json")
+			dateStr := file.date
 			if oldestDate == "" || dateStr < oldestDate {
 				oldestDate = dateStr
 			}
@@ -310,43 +554,58 @@ func (lfm *LogFileManager) GetStats() map[string]interface{} {
 	}
 
 	stats["files"] = filesCounts
+	stats["compressed_files"] = compressedCount
 	stats["total_size_mb"] = float64(totalSize) / 1024 / 1024
+	stats["max_file_size_mb"] = lfm.maxFileSize / 1024 / 1024
+	stats["max_total_size_mb"] = lfm.maxTotalSize / 1024 / 1024
 	stats["oldest_log"] = oldestDate
 	stats["newest_log"] = newestDate
+	stats["queue"] = lfm.QueueStats()
+
+	sinks := make([]map[string]interface{}, 0, len(lfm.sinks))
+	for _, sink := range lfm.sinks {
+		sinks = append(sinks, sink.Stats())
+	}
+	stats["sinks"] = sinks
 
 	return stats
 }
 
-// ListLogFiles returns a list of log files for a given type
-func (lfm *LogFileManager) ListLogFiles(logType string) ([]string, error) {
-	var dir string
+// typeDir returns the directory holding the files of a log type
+func (lfm *LogFileManager) typeDir(logType string) (string, error) {
 	switch logType {
 	case "requests":
-		dir = filepath.Join(lfm.baseDir, "requests")
+		return filepath.Join(lfm.baseDir, "requests"), nil
 	case "lock_events":
-		dir = filepath.Join(lfm.baseDir, "events", "lock")
+		return filepath.Join(lfm.baseDir, "events", "lock"), nil
 	case "tool_events":
-		dir = filepath.Join(lfm.baseDir, "events", "tool")
+		return filepath.Join(lfm.baseDir, "events", "tool"), nil
 	case "metrics":
-		dir = filepath.Join(lfm.baseDir, "metrics")
+		return filepath.Join(lfm.baseDir, "metrics"), nil
 	case "summary":
-		dir = filepath.Join(lfm.baseDir, "summary")
+		return filepath.Join(lfm.baseDir, "summary"), nil
+	case AuditLogType:
+		return filepath.Join(lfm.baseDir, "audit"), nil
 	default:
-		return nil, fmt.Errorf("unknown log type: %s", logType)
+		return "", fmt.Errorf("unknown log type: %s", logType)
 	}
+}
 
-	entries, err := os.ReadDir(dir)
+// ListLogFiles returns the file names of a log type in date and segment order
+func (lfm *LogFileManager) ListLogFiles(logType string) ([]string, error) {
+	dir, err := lfm.typeDir(logType)
 	if err != nil {
 		return nil, err
 	}
 
-	var files []string
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			files = append(files, entry.Name())
-		}
+	files, err := listLogFiles(dir)
+	if err != nil {
+		return nil, err
 	}
 
-	sort.Strings(files)
-	return files, nil
+	names := make([]string, len(files))
+	for i, f := range files {
+		names[i] = f.name
+	}
+	return names, nil
 }
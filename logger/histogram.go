@@ -0,0 +1,140 @@
+package logger
+
+import (
+	"math"
+	"math/bits"
+	"strconv"
+
+	"clipboard-controller/model"
+)
+
+// Histogram buckets: values below histExact get their own bucket, larger
+// values share histSubBuckets buckets per power of two, so a bucket spans at
+// most 12.5% of its values
+const (
+	histExact      = 16
+	histSubBuckets = 8
+	histBuckets    = histExact + (64-5)*histSubBuckets
+)
+
+// histogram is a fixed-size streaming histogram of non-negative values. It
+// is not safe for concurrent use.
+type histogram struct {
+	counts [histBuckets]int64
+	count  int64
+	min    int64
+	max    int64
+}
+
+func histBucket(v int64) int {
+	if v < histExact {
+		return int(v)
+	}
+	exp := bits.Len64(uint64(v)) - 1 // >= 4
+	sub := int(v>>(exp-3)) & (histSubBuckets - 1)
+	return histExact + (exp-4)*histSubBuckets + sub
+}
+
+// histBucketRange returns the smallest and largest value of a bucket
+func histBucketRange(i int) (int64, int64) {
+	if i < histExact {
+		return int64(i), int64(i)
+	}
+	k := i - histExact
+	exp := k/histSubBuckets + 4
+	sub := int64(k % histSubBuckets)
+	lower := (histSubBuckets + sub) << (exp - 3)
+	return lower, lower + 1<<(exp-3) - 1
+}
+
+// record adds a value; negative values count as 0
+func (h *histogram) record(v int64) {
+	v = max(v, 0)
+	if h.count == 0 || v < h.min {
+		h.min = v
+	}
+	h.max = max(h.max, v)
+	h.count++
+	h.counts[histBucket(v)]++
+}
+
+// merge adds the values of other
+func (h *histogram) merge(other *histogram) {
+	if other.count == 0 {
+		return
+	}
+	if h.count == 0 || other.min < h.min {
+		h.min = other.min
+	}
+	h.max = max(h.max, other.max)
+	h.count += other.count
+	for i, n := range other.counts {
+		h.counts[i] += n
+	}
+}
+
+// percentile returns the nearest-rank estimate of the p-th percentile
+// (0 < p <= 100): the middle of the bucket holding it, within the observed
+// range
+func (h *histogram) percentile(p float64) int64 {
+	if h.count == 0 {
+		return 0
+	}
+	rank := max(int64(math.Ceil(p/100*float64(h.count))), 1)
+
+	var seen int64
+	for i, n := range h.counts {
+		seen += n
+		if seen >= rank {
+			lower, upper := histBucketRange(i)
+			return min(max(lower+(upper-lower)/2, h.min), h.max)
+		}
+	}
+	return h.max
+}
+
+// distribution summarizes the histogram, nil when it is empty
+func (h *histogram) distribution(percentiles []float64) *model.Distribution {
+	if h.count == 0 {
+		return nil
+	}
+	d := &model.Distribution{
+		Count:       h.count,
+		Min:         h.min,
+		Max:         h.max,
+		Percentiles: make(map[string]int64, len(percentiles)),
+	}
+	for _, p := range percentiles {
+		d.Percentiles["p"+strconv.FormatFloat(p, 'f', -1, 64)] = h.percentile(p)
+	}
+	return d
+}
+
+// lockHistograms are the distributions tracked for a period
+type lockHistograms struct {
+	wait   histogram
+	hold   histogram
+	queue  histogram
+	extend histogram
+}
+
+func (l *lockHistograms) merge(other *lockHistograms) {
+	l.wait.merge(&other.wait)
+	l.hold.merge(&other.hold)
+	l.queue.merge(&other.queue)
+	l.extend.merge(&other.extend)
+}
+
+// distributions summarizes the histograms, nil when all are empty
+func (l *lockHistograms) distributions(percentiles []float64) *model.Distributions {
+	d := &model.Distributions{
+		WaitTimeMs:  l.wait.distribution(percentiles),
+		HoldTimeMs:  l.hold.distribution(percentiles),
+		QueueLength: l.queue.distribution(percentiles),
+		ExtendCount: l.extend.distribution(percentiles),
+	}
+	if d.WaitTimeMs == nil && d.HoldTimeMs == nil && d.QueueLength == nil && d.ExtendCount == nil {
+		return nil
+	}
+	return d
+}
@@ -0,0 +1,257 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor is returned when a query cursor cannot be parsed
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// QueryableLogTypes lists the log types that can be searched with Query
+var QueryableLogTypes = []string{"requests", "lock_events", "tool_events", "metrics", AuditLogType}
+
+// LogQuery selects entries from the daily JSONL files. Empty filters match
+// everything; StatusCode 0 means any.
+type LogQuery struct {
+	LogType    string
+	From       time.Time // Inclusive
+	To         time.Time // Exclusive
+	ToolID     string
+	ThreadID   string
+	TicketID   string
+	EventType  string
+	StatusCode int
+	Cursor     string // NextCursor of the previous page
+	Limit      int
+}
+
+// LogQueryResult is one page of matching entries, oldest first
+type LogQueryResult struct {
+	Entries      []json.RawMessage `json:"entries"`
+	NextCursor   string            `json:"next_cursor,omitempty"` // Empty when the range is exhausted
+	ScannedLines int64             `json:"scanned_lines"`
+}
+
+// logQueryFields are the fields a query filters on; the rest of the line is
+// passed through untouched
+type logQueryFields struct {
+	Timestamp  time.Time `json:"timestamp"`
+	ToolID     string    `json:"tool_id"`
+	ThreadID   string    `json:"thread_id"`
+	TicketID   string    `json:"ticket_id"`
+	EventType  string    `json:"event_type"`
+	StatusCode int       `json:"status_code"`
+	RequestID  string    `json:"request_id"`
+}
+
+func (q *LogQuery) matches(f *logQueryFields) bool {
+	switch {
+	case f.Timestamp.Before(q.From) || !f.Timestamp.Before(q.To):
+		return false
+	case q.ToolID != "" && f.ToolID != q.ToolID:
+		return false
+	case q.ThreadID != "" && f.ThreadID != q.ThreadID:
+		return false
+	case q.TicketID != "" && f.TicketID != q.TicketID:
+		return false
+	case q.EventType != "" && f.EventType != q.EventType:
+		return false
+	case q.StatusCode != 0 && f.StatusCode != q.StatusCode:
+		return false
+	}
+	return true
+}
+
+// Query scans the daily files of q.LogType that overlap [q.From, q.To) line by
+// line and returns up to q.Limit matching entries. Only one page is held in
+// memory, so large days can be searched page by page with NextCursor. The scan
+// stops early if ctx is cancelled.
+func (lfm *LogFileManager) Query(ctx context.Context, q LogQuery) (*LogQueryResult, error) {
+	start := logPosition{date: q.From.Local().Format("2006-01-02")}
+	if q.Cursor != "" {
+		var err error
+		start, err = parseQueryCursor(q.Cursor)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := &LogQueryResult{Entries: make([]json.RawMessage, 0)}
+	cursor, scanned, err := lfm.scan(ctx, q.LogType, start, q.To.Local().Format("2006-01-02"),
+		func(fields *logQueryFields, line []byte) bool {
+			if q.matches(fields) {
+				result.Entries = append(result.Entries, json.RawMessage(line))
+			}
+			return len(result.Entries) < q.Limit
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	result.NextCursor = cursor
+	result.ScannedLines = scanned
+	return result, nil
+}
+
+// logPosition is a place in the files of a log type: a day's segment and an
+// offset into its uncompressed content
+type logPosition struct {
+	date    string
+	segment int
+	offset  int64
+}
+
+// scan reads the files of logType from start up to endDate in order,
+// compressed or not, and passes every complete line to visit. When visit
+// returns false the scan stops and the returned cursor points at the next
+// unread line; it is empty when all files were read.
+func (lfm *LogFileManager) scan(ctx context.Context, logType string, start logPosition, endDate string, visit func(*logQueryFields, []byte) bool) (string, int64, error) {
+	dir, err := lfm.typeDir(logType)
+	if err != nil {
+		return "", 0, err
+	}
+
+	files, err := listLogFiles(dir)
+	if err != nil {
+		return "", 0, err
+	}
+
+	// Make today's buffered entries visible
+	lfm.FlushAll()
+
+	startFile := logFile{date: start.date, segment: start.segment}
+	var scanned int64
+	for _, file := range files {
+		if file.before(startFile) || file.date > endDate {
+			continue
+		}
+
+		offset := int64(0)
+		if file.date == start.date && file.segment == start.segment {
+			offset = start.offset
+		}
+
+		next, err := scanFile(ctx, filepath.Join(dir, file.name), file.compressed, offset, &scanned, visit)
+		if err != nil {
+			return "", scanned, err
+		}
+		if next >= 0 {
+			return file.stem() + ":" + strconv.FormatInt(next, 10), scanned, nil
+		}
+	}
+
+	return "", scanned, nil
+}
+
+// scanFile passes the lines of path from offset on to visit. It returns the
+// offset to resume from when visit stops the scan, or -1 when the file is done.
+// A plain file that was compressed since it was listed is read from its .gz.
+func scanFile(ctx context.Context, path string, compressed bool, offset int64, scanned *int64, visit func(*logQueryFields, []byte) bool) (int64, error) {
+	src, err := openLogFile(path, compressed)
+	if err != nil || src == nil {
+		return -1, err
+	}
+	defer src.Close()
+
+	if seeker, ok := src.(io.Seeker); ok {
+		_, err = seeker.Seek(offset, io.SeekStart)
+	} else if _, err = io.CopyN(io.Discard, src, offset); err == io.EOF {
+		err = nil
+	}
+	if err != nil {
+		return -1, fmt.Errorf("failed to seek log file %s: %w", path, err)
+	}
+
+	reader := bufio.NewReaderSize(src, 64*1024)
+	for {
+		if *scanned%1000 == 0 && ctx.Err() != nil {
+			return -1, ctx.Err()
+		}
+
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// A trailing line without newline is still being written
+			return -1, nil
+		}
+		if err != nil {
+			return -1, fmt.Errorf("failed to read log file %s: %w", path, err)
+		}
+		offset += int64(len(line))
+		*scanned++
+
+		var fields logQueryFields
+		if err := json.Unmarshal(line, &fields); err != nil {
+			continue // Skip corrupt lines
+		}
+		if !visit(&fields, bytes.TrimSpace(line)) {
+			return offset, nil
+		}
+	}
+}
+
+// openLogFile opens a log file for reading its uncompressed content. A plain
+// file that was compressed since it was listed is read from its .gz. It
+// returns nil without error when the file no longer exists.
+func openLogFile(path string, compressed bool) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err != nil && os.IsNotExist(err) && !compressed {
+		path, compressed = path+".gz", true
+		file, err = os.Open(path)
+	}
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open log file %s: %w", path, err)
+	}
+	if !compressed {
+		return file, nil
+	}
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read log file %s: %w", path, err)
+	}
+	return gzipFile{gz, file}, nil
+}
+
+// gzipFile closes both the gzip reader and the file under it
+type gzipFile struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (g gzipFile) Close() error {
+	g.Reader.Close()
+	return g.file.Close()
+}
+
+// parseQueryCursor splits a "YYYY-MM-DD[.segment]:offset" cursor
+func parseQueryCursor(cursor string) (logPosition, error) {
+	stem, rawOffset, ok := strings.Cut(cursor, ":")
+	if !ok {
+		return logPosition{}, ErrInvalidCursor
+	}
+	file, ok := parseLogFileName(stem + ".jsonl")
+	if !ok {
+		return logPosition{}, ErrInvalidCursor
+	}
+	offset, err := strconv.ParseInt(rawOffset, 10, 64)
+	if err != nil || offset < 0 {
+		return logPosition{}, ErrInvalidCursor
+	}
+	return logPosition{date: file.date, segment: file.segment, offset: offset}, nil
+}
@@ -0,0 +1,324 @@
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// logFile is one file of a log type. A day is split into numbered segments
+// when log_max_file_size_mb is set: 2024-01-15.jsonl, 2024-01-15.1.jsonl, ...
+// Closed segments are gzipped to 2024-01-15.jsonl.gz, 2024-01-15.1.jsonl.gz.
+type logFile struct {
+	name       string
+	date       string // YYYY-MM-DD
+	segment    int
+	compressed bool
+}
+
+// parseLogFileName parses a log, segment or summary file name
+func parseLogFileName(name string) (logFile, bool) {
+	f := logFile{name: name}
+	base := name
+	if strings.HasSuffix(base, ".gz") {
+		f.compressed = true
+		base = strings.TrimSuffix(base, ".gz")
+	}
+
+	switch {
+	case strings.HasSuffix(base, ".jsonl"):
+		base = strings.TrimSuffix(base, ".jsonl")
+	case strings.HasSuffix(base, ".json"):
+		base = strings.TrimSuffix(base, ".json")
+	default:
+		return f, false
+	}
+
+	date, segment, hasSegment := strings.Cut(base, ".")
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return f, false
+	}
+	if hasSegment {
+		n, err := strconv.Atoi(segment)
+		if err != nil || n < 1 {
+			return f, false
+		}
+		f.segment = n
+	}
+	f.date = date
+	return f, true
+}
+
+// stem is the file name without extensions, used in query cursors
+func (f logFile) stem() string {
+	if f.segment == 0 {
+		return f.date
+	}
+	return f.date + "." + strconv.Itoa(f.segment)
+}
+
+// before orders files by date, then segment
+func (f logFile) before(other logFile) bool {
+	if f.date != other.date {
+		return f.date < other.date
+	}
+	return f.segment < other.segment
+}
+
+// segmentName returns the uncompressed file name of a day's segment
+func segmentName(date string, segment int) string {
+	if segment == 0 {
+		return date + ".jsonl"
+	}
+	return fmt.Sprintf("%s.%d.jsonl", date, segment)
+}
+
+// listLogFiles returns the log files in dir in date and segment order. A
+// segment that exists both plain and gzipped is being compressed; the plain
+// file is listed.
+func listLogFiles(dir string) ([]logFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byStem := make(map[string]logFile)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		f, ok := parseLogFileName(entry.Name())
+		if !ok {
+			continue
+		}
+		if existing, ok := byStem[f.stem()]; ok && !existing.compressed {
+			continue
+		}
+		byStem[f.stem()] = f
+	}
+
+	files := make([]logFile, 0, len(byStem))
+	for _, f := range byStem {
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].before(files[j]) })
+	return files, nil
+}
+
+// nextSegment returns the segment of date to append to in dir: the last one,
+// unless it is already compressed or full
+func (lfm *LogFileManager) nextSegment(dir, date string) int {
+	files, err := listLogFiles(dir)
+	if err != nil {
+		return 0
+	}
+
+	segment := -1
+	var last logFile
+	for _, f := range files {
+		if f.date == date {
+			segment, last = f.segment, f
+		}
+	}
+	switch {
+	case segment < 0:
+		return 0
+	case last.compressed:
+		return segment + 1
+	}
+
+	if info, err := os.Stat(filepath.Join(dir, last.name)); err == nil && lfm.maxFileSize > 0 && info.Size() >= lfm.maxFileSize {
+		return segment + 1
+	}
+	return segment
+}
+
+// segmentClosed compresses a closed segment and enforces the disk budget in
+// the background. Close waits for it.
+func (lfm *LogFileManager) segmentClosed(path string) {
+	if !lfm.compress && lfm.maxTotalSize <= 0 {
+		return
+	}
+
+	lfm.background.Add(1)
+	go func() {
+		defer lfm.background.Done()
+		if lfm.compress {
+			if err := lfm.compressFile(path); err != nil {
+				log.Warn().Err(err).Str("path", path).Msg("Failed to compress log file")
+			}
+		}
+		lfm.enforceDiskBudget()
+	}()
+}
+
+// compressFile gzips path to path.gz and removes path. The archive is
+// written to a temporary name first so readers never see a partial file.
+func (lfm *LogFileManager) compressFile(path string) error {
+	lfm.compressMu.Lock()
+	if lfm.compressing[path] {
+		lfm.compressMu.Unlock()
+		return nil
+	}
+	lfm.compressing[path] = true
+	lfm.compressMu.Unlock()
+
+	defer func() {
+		lfm.compressMu.Lock()
+		delete(lfm.compressing, path)
+		lfm.compressMu.Unlock()
+	}()
+
+	src, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil // Already compressed or removed
+		}
+		return err
+	}
+	defer src.Close()
+
+	tmp := path + ".gz.tmp"
+	dst, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(dst)
+	_, err = io.Copy(gz, src)
+	if closeErr := gz.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path+".gz")
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	src.Close()
+	return os.Remove(path)
+}
+
+// compressClosedFiles gzips the plain files of past days that are not open
+func (lfm *LogFileManager) compressClosedFiles() int {
+	if !lfm.compress {
+		return 0
+	}
+
+	today := time.Now().Format("2006-01-02")
+	open := lfm.openPaths()
+	compressed := 0
+	for _, dir := range append(lfm.streamDirs(), filepath.Join(lfm.baseDir, "audit")) {
+		files, err := listLogFiles(dir)
+		if err != nil {
+			continue
+		}
+		for _, f := range files {
+			path := filepath.Join(dir, f.name)
+			if f.compressed || f.date >= today || open[path] {
+				continue
+			}
+			if err := lfm.compressFile(path); err != nil {
+				log.Warn().Err(err).Str("path", path).Msg("Failed to compress log file")
+				continue
+			}
+			compressed++
+		}
+	}
+	return compressed
+}
+
+// enforceDiskBudget deletes the oldest log files, across all streams, until
+// their total size fits log_max_total_size_mb. Open files and summaries are
+// never deleted.
+func (lfm *LogFileManager) enforceDiskBudget() int {
+	if lfm.maxTotalSize <= 0 {
+		return 0
+	}
+
+	lfm.budgetMu.Lock()
+	defer lfm.budgetMu.Unlock()
+
+	type candidate struct {
+		file logFile
+		path string
+		size int64
+	}
+
+	open := lfm.openPaths()
+	var candidates []candidate
+	var total int64
+	for _, dir := range lfm.streamDirs() {
+		files, err := listLogFiles(dir)
+		if err != nil {
+			continue
+		}
+		for _, f := range files {
+			path := filepath.Join(dir, f.name)
+			info, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			total += info.Size()
+			if !open[path] {
+				candidates = append(candidates, candidate{f, path, info.Size()})
+			}
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].file.before(candidates[j].file) })
+
+	removed := 0
+	for _, c := range candidates {
+		if total <= lfm.maxTotalSize {
+			break
+		}
+		if err := os.Remove(c.path); err != nil {
+			log.Warn().Err(err).Str("path", c.path).Msg("Failed to remove log file over disk budget")
+			continue
+		}
+		total -= c.size
+		removed++
+	}
+
+	if removed > 0 {
+		log.Info().Int("count", removed).Int64("total_size", total).Msg("Removed oldest log files over disk budget")
+	}
+	return removed
+}
+
+// openPaths returns the paths of the files currently being written
+func (lfm *LogFileManager) openPaths() map[string]bool {
+	lfm.mu.Lock()
+	defer lfm.mu.Unlock()
+
+	open := make(map[string]bool, len(lfm.writers))
+	for _, bw := range lfm.writers {
+		open[bw.path] = true
+	}
+	return open
+}
+
+// streamDirs returns the directories of the JSONL log streams, without the
+// audit stream, which the disk budget must not touch
+func (lfm *LogFileManager) streamDirs() []string {
+	return []string{
+		filepath.Join(lfm.baseDir, "requests"),
+		filepath.Join(lfm.baseDir, "events", "lock"),
+		filepath.Join(lfm.baseDir, "events", "tool"),
+		filepath.Join(lfm.baseDir, "metrics"),
+	}
+}
@@ -0,0 +1,282 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"clipboard-controller/config"
+	"clipboard-controller/model"
+)
+
+// Report groupings
+const (
+	ReportGroupTool   = "tool"
+	ReportGroupThread = "thread"
+)
+
+// Report buckets; an empty bucket reports the whole range as one period
+const (
+	ReportBucketHour = "hour"
+	ReportBucketDay  = "day"
+	ReportBucketWeek = "week"
+)
+
+// Lock-hogging thresholds
+const (
+	hogHoldShare       = 0.5 // Share of the period's hold time, with other groups holding locks too
+	hogMaxDurationRate = 0.9 // p95 hold time relative to the tool's lock_max_duration
+	hogForcedExpiry    = 0.1 // Locks taken away by max duration or grace period, per granted lock
+)
+
+// Lock-hogging reasons
+const (
+	HogReasonHoldShare    = "hold_share"
+	HogReasonNearMaxHold  = "near_max_duration"
+	HogReasonForcedExpiry = "forced_expiry"
+)
+
+// reportPercentile is the percentile reported for wait and hold times
+const reportPercentile = 95
+
+// ReportQuery selects the lock events of a usage report
+type ReportQuery struct {
+	From    time.Time
+	To      time.Time // Exclusive
+	GroupBy string    // tool or thread
+	Bucket  string    // hour, day, week or empty for the whole range
+}
+
+// ReportRow is the lock usage of one tool, or one thread of a tool, during
+// one period. Wait times come from grants and hold times from releases and
+// expiries, so a lock spanning two periods counts its hold in the later one.
+type ReportRow struct {
+	PeriodStart      time.Time        `json:"period_start"`
+	PeriodEnd        time.Time        `json:"period_end"`
+	ToolID           string           `json:"tool_id"`
+	ThreadID         string           `json:"thread_id,omitempty"`
+	LocksGranted     int64            `json:"locks_granted"`
+	LocksReleased    int64            `json:"locks_released"`
+	Expired          int64            `json:"expired"` // Tickets expired in the queue or while holding the lock
+	ExpiriesByReason map[string]int64 `json:"expiries_by_reason,omitempty"`
+	Extends          int64            `json:"extends"`
+	ExtendRate       float64          `json:"extend_rate"` // Extends per granted lock
+	WaitTotalMs      int64            `json:"wait_total_ms"`
+	WaitAvgMs        int64            `json:"wait_avg_ms"`
+	WaitP95Ms        int64            `json:"wait_p95_ms"`
+	HoldTotalMs      int64            `json:"hold_total_ms"`
+	HoldAvgMs        int64            `json:"hold_avg_ms"`
+	HoldP95Ms        int64            `json:"hold_p95_ms"`
+	HoldMaxMs        int64            `json:"hold_max_ms"`
+	HoldShare        float64          `json:"hold_share"` // Share of the period's hold time across all rows
+	Hog              bool             `json:"hog"`
+	HogReasons       []string         `json:"hog_reasons,omitempty"`
+}
+
+// UsageReport is the result of Report
+type UsageReport struct {
+	From         time.Time   `json:"from"`
+	To           time.Time   `json:"to"`
+	GroupBy      string      `json:"group_by"`
+	Bucket       string      `json:"bucket,omitempty"`
+	Rows         []ReportRow `json:"rows"`
+	ScannedLines int64       `json:"scanned_lines"`
+}
+
+// reportGroup accumulates one row
+type reportGroup struct {
+	row  ReportRow
+	wait histogram
+	hold histogram
+}
+
+type reportKey struct {
+	period   time.Time
+	toolID   string
+	threadID string
+}
+
+// Report aggregates the lock events logged in [From, To) by period and tool
+// or thread, and flags the groups hogging the lock. cfg supplies each tool's
+// lock_max_duration.
+func (lfm *LogFileManager) Report(ctx context.Context, q ReportQuery, cfg *config.Config) (*UsageReport, error) {
+	report := &UsageReport{From: q.From, To: q.To, GroupBy: q.GroupBy, Bucket: q.Bucket, Rows: make([]ReportRow, 0)}
+	startDate, endDate := q.From.Local().Format("2006-01-02"), q.To.Local().Format("2006-01-02")
+
+	groups := make(map[reportKey]*reportGroup)
+	_, scanned, err := lfm.scan(ctx, "lock_events", logPosition{date: startDate}, endDate, func(fields *logQueryFields, line []byte) bool {
+		switch fields.EventType {
+		case model.LockEventGranted, model.LockEventReleased, model.LockEventExpired, model.LockEventExtended:
+		default:
+			return true
+		}
+		if fields.Timestamp.Before(q.From) || !fields.Timestamp.Before(q.To) {
+			return true
+		}
+		var event model.LockEventLog
+		if json.Unmarshal(line, &event) != nil {
+			return true
+		}
+
+		key := reportKey{period: reportPeriodStart(event.Timestamp, q), toolID: event.ToolID}
+		if q.GroupBy == ReportGroupThread {
+			key.threadID = event.ThreadID
+		}
+		group, ok := groups[key]
+		if !ok {
+			group = &reportGroup{row: ReportRow{ToolID: key.toolID, ThreadID: key.threadID}}
+			groups[key] = group
+		}
+		group.add(&event)
+		return true
+	})
+	report.ScannedLines = scanned
+	if err != nil {
+		return nil, err
+	}
+
+	// Hold time per period, and how many groups held the lock in it
+	periodHold := make(map[time.Time]int64)
+	periodHolders := make(map[time.Time]int)
+	for key, group := range groups {
+		if group.hold.count > 0 {
+			periodHold[key.period] += group.row.HoldTotalMs
+			periodHolders[key.period]++
+		}
+	}
+
+	for key, group := range groups {
+		row := group.finish(key.period, q)
+		if total := periodHold[key.period]; total > 0 {
+			row.HoldShare = float64(row.HoldTotalMs) / float64(total)
+		}
+		row.flagHog(periodHolders[key.period], int64(cfg.ForTool(row.ToolID).LockMaxDuration)*1000)
+		report.Rows = append(report.Rows, row)
+	}
+
+	sort.Slice(report.Rows, func(i, j int) bool {
+		a, b := report.Rows[i], report.Rows[j]
+		if !a.PeriodStart.Equal(b.PeriodStart) {
+			return a.PeriodStart.Before(b.PeriodStart)
+		}
+		if a.ToolID != b.ToolID {
+			return a.ToolID < b.ToolID
+		}
+		return a.ThreadID < b.ThreadID
+	})
+	return report, nil
+}
+
+func (g *reportGroup) add(event *model.LockEventLog) {
+	switch event.EventType {
+	case model.LockEventGranted:
+		g.row.LocksGranted++
+		g.row.WaitTotalMs += event.WaitDurationMs
+		g.wait.record(event.WaitDurationMs)
+	case model.LockEventReleased:
+		g.row.LocksReleased++
+		g.recordHold(event.HoldDurationMs)
+	case model.LockEventExpired:
+		reason := event.Reason
+		if reason == "" {
+			reason = "expired"
+		}
+		g.row.Expired++
+		if g.row.ExpiriesByReason == nil {
+			g.row.ExpiriesByReason = make(map[string]int64)
+		}
+		g.row.ExpiriesByReason[reason]++
+		// Tickets expired in the queue never held the lock
+		if event.HoldDurationMs > 0 {
+			g.recordHold(event.HoldDurationMs)
+		}
+	case model.LockEventExtended:
+		g.row.Extends++
+	}
+}
+
+func (g *reportGroup) recordHold(ms int64) {
+	g.row.HoldTotalMs += ms
+	g.hold.record(ms)
+}
+
+// finish computes the derived fields of the row
+func (g *reportGroup) finish(period time.Time, q ReportQuery) ReportRow {
+	row := g.row
+	row.PeriodStart, row.PeriodEnd = period, reportPeriodEnd(period, q)
+	if row.LocksGranted > 0 {
+		row.ExtendRate = float64(row.Extends) / float64(row.LocksGranted)
+	}
+	if g.wait.count > 0 {
+		row.WaitAvgMs = row.WaitTotalMs / g.wait.count
+		row.WaitP95Ms = g.wait.percentile(reportPercentile)
+	}
+	if g.hold.count > 0 {
+		row.HoldAvgMs = row.HoldTotalMs / g.hold.count
+		row.HoldP95Ms = g.hold.percentile(reportPercentile)
+		row.HoldMaxMs = g.hold.max
+	}
+	return row
+}
+
+// flagHog marks a row whose group kept the lock from others: most of the
+// period's hold time while others held it too, holds close to the tool's
+// maximum, or locks regularly taken away by the server
+func (row *ReportRow) flagHog(holders int, maxHoldMs int64) {
+	if holders >= 2 && row.HoldShare >= hogHoldShare {
+		row.HogReasons = append(row.HogReasons, HogReasonHoldShare)
+	}
+	if maxHoldMs > 0 && row.HoldP95Ms > 0 && float64(row.HoldP95Ms) >= hogMaxDurationRate*float64(maxHoldMs) {
+		row.HogReasons = append(row.HogReasons, HogReasonNearMaxHold)
+	}
+	forced := row.ExpiriesByReason["max_duration_expired"] + row.ExpiriesByReason["grace_period_expired"]
+	if forced > 0 && float64(forced) >= hogForcedExpiry*float64(max(row.LocksGranted, 1)) {
+		row.HogReasons = append(row.HogReasons, HogReasonForcedExpiry)
+	}
+	row.Hog = len(row.HogReasons) > 0
+}
+
+// reportPeriodStart returns the start of the bucket holding ts, in local
+// time; weeks start on Monday. Periods never start before the range.
+func reportPeriodStart(ts time.Time, q ReportQuery) time.Time {
+	ts = ts.Local()
+	var start time.Time
+	switch q.Bucket {
+	case ReportBucketHour:
+		start = time.Date(ts.Year(), ts.Month(), ts.Day(), ts.Hour(), 0, 0, 0, time.Local)
+	case ReportBucketDay:
+		start = time.Date(ts.Year(), ts.Month(), ts.Day(), 0, 0, 0, 0, time.Local)
+	case ReportBucketWeek:
+		daysSinceMonday := (int(ts.Weekday()) + 6) % 7
+		start = time.Date(ts.Year(), ts.Month(), ts.Day()-daysSinceMonday, 0, 0, 0, 0, time.Local)
+	default:
+		return q.From
+	}
+	if start.Before(q.From) {
+		return q.From
+	}
+	return start
+}
+
+// reportPeriodEnd returns the end of the bucket starting at start, clamped to
+// the range
+func reportPeriodEnd(start time.Time, q ReportQuery) time.Time {
+	start = start.Local()
+	var end time.Time
+	switch q.Bucket {
+	case ReportBucketHour:
+		end = time.Date(start.Year(), start.Month(), start.Day(), start.Hour()+1, 0, 0, 0, time.Local)
+	case ReportBucketDay:
+		end = time.Date(start.Year(), start.Month(), start.Day()+1, 0, 0, 0, 0, time.Local)
+	case ReportBucketWeek:
+		daysToMonday := 7 - (int(start.Weekday())+6)%7
+		end = time.Date(start.Year(), start.Month(), start.Day()+daysToMonday, 0, 0, 0, 0, time.Local)
+	default:
+		return q.To
+	}
+	if end.After(q.To) {
+		return q.To
+	}
+	return end
+}
@@ -0,0 +1,24 @@
+package logger
+
+import (
+	"math/rand/v2"
+
+	"clipboard-controller/config"
+)
+
+// sampled decides whether an entry of stream identified by key (request
+// path or event type) is written, according to the log_sampling rules
+func sampled(cfg *config.Config, stream, key string) bool {
+	rate := cfg.LogSampleRate(stream, key)
+	return rate >= 1 || (rate > 0 && rand.Float64() < rate)
+}
+
+// ShouldLogRequest reports whether a request to path that ended with status
+// is written to the request log. Failed requests (status >= 400) are always
+// written while log_requests is on; others follow the sampling rules.
+func ShouldLogRequest(cfg *config.Config, path string, status int) bool {
+	if !cfg.LogRequests {
+		return false
+	}
+	return status >= 400 || sampled(cfg, config.LogStreamRequests, path)
+}
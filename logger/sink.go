@@ -0,0 +1,299 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"clipboard-controller/config"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Sink receives the entries written to the log files, as JSON lines. Write
+// is called from the log writer goroutine and must not block.
+type Sink interface {
+	Name() string
+	WantsStream(stream string) bool
+	Write(stream string, line []byte)
+	Stats() map[string]interface{}
+	Close() error
+}
+
+// errPermanent marks a delivery failure that retrying cannot fix; the batch
+// is dropped instead of spooled
+var errPermanent = errors.New("permanent delivery failure")
+
+// NewSinks creates the configured sinks. Remote sinks spool under
+// <logDir>/spool.
+func NewSinks(cfgs []config.LogSinkConfig, logDir string) ([]Sink, error) {
+	sinks := make([]Sink, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		var sink Sink
+		switch cfg.Type {
+		case config.LogSinkStdout:
+			sink = newStdoutSink(cfg, os.Stdout)
+		case config.LogSinkSyslog:
+			sink = newForwarder(cfg, logDir, newSyslogWriter(cfg.Address))
+		case config.LogSinkHTTP:
+			sink = newForwarder(cfg, logDir, newHTTPWriter(cfg.URL, cfg.Headers))
+		default:
+			return nil, fmt.Errorf("unknown log sink type: %s", cfg.Type)
+		}
+		sinks = append(sinks, sink)
+	}
+	return sinks, nil
+}
+
+// withStream returns the JSON object line with a leading "log_type" field,
+// so consumers of a mixed stream can tell entries apart
+func withStream(stream string, line []byte) []byte {
+	if len(line) < 2 || line[0] != '{' {
+		return line
+	}
+	var buf bytes.Buffer
+	buf.Grow(len(line) + len(stream) + 16)
+	buf.WriteString(`{"log_type":"`)
+	buf.WriteString(stream)
+	buf.WriteByte('"')
+	if !bytes.Equal(line, []byte("{}")) {
+		buf.WriteByte(',')
+	}
+	buf.Write(line[1:])
+	return buf.Bytes()
+}
+
+// stdoutSink writes one JSON object per line to stdout, for container log
+// collectors and journald
+type stdoutSink struct {
+	cfg     config.LogSinkConfig
+	out     io.Writer
+	written atomic.Int64
+	failed  atomic.Int64
+}
+
+func newStdoutSink(cfg config.LogSinkConfig, out io.Writer) *stdoutSink {
+	return &stdoutSink{cfg: cfg, out: out}
+}
+
+func (s *stdoutSink) Name() string { return s.cfg.Name }
+
+func (s *stdoutSink) WantsStream(stream string) bool { return s.cfg.WantsStream(stream) }
+
+func (s *stdoutSink) Write(stream string, line []byte) {
+	if _, err := s.out.Write(append(withStream(stream, line), '\n')); err != nil {
+		s.failed.Add(1)
+		return
+	}
+	s.written.Add(1)
+}
+
+func (s *stdoutSink) Stats() map[string]interface{} {
+	return map[string]interface{}{
+		"name":    s.cfg.Name,
+		"type":    s.cfg.Type,
+		"written": s.written.Load(),
+		"failed":  s.failed.Load(),
+	}
+}
+
+func (s *stdoutSink) Close() error { return nil }
+
+// sinkEntry is one log line queued or spooled for a remote sink
+type sinkEntry struct {
+	Stream string          `json:"stream"`
+	Line   json.RawMessage `json:"line"`
+}
+
+// batchWriter delivers a batch to a remote destination
+type batchWriter interface {
+	WriteBatch(batch []sinkEntry) error
+	Close() error
+}
+
+// forwarder batches entries for a remote sink. Failed batches are retried,
+// then spooled to disk; while the destination is down new entries are
+// spooled too, and the spool is replayed in order once delivery succeeds.
+type forwarder struct {
+	cfg    config.LogSinkConfig
+	writer batchWriter
+	queue  chan sinkEntry
+	spool  *sinkSpool
+	stop   chan struct{}
+	done   chan struct{}
+
+	// Owned by the run goroutine
+	backoff time.Duration
+	retryAt time.Time
+
+	sent      atomic.Int64
+	failures  atomic.Int64
+	dropped   atomic.Int64
+	down      atomic.Bool
+	lastError atomic.Value // string
+}
+
+const (
+	forwarderRetries    = 3
+	forwarderMaxBackoff = time.Minute
+)
+
+func newForwarder(cfg config.LogSinkConfig, logDir string, writer batchWriter) *forwarder {
+	f := &forwarder{
+		cfg:    cfg,
+		writer: writer,
+		queue:  make(chan sinkEntry, cfg.BatchSize*10),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	f.spool = newSinkSpool(filepath.Join(logDir, "spool", cfg.Name+".jsonl"), int64(cfg.SpoolMaxMB)*1024*1024, &f.dropped)
+	go f.run()
+	return f
+}
+
+func (f *forwarder) Name() string { return f.cfg.Name }
+
+func (f *forwarder) WantsStream(stream string) bool { return f.cfg.WantsStream(stream) }
+
+// Write queues an entry, spooling it when the queue is full
+func (f *forwarder) Write(stream string, line []byte) {
+	entry := sinkEntry{Stream: stream, Line: line}
+	select {
+	case f.queue <- entry:
+	default:
+		f.spool.append([]sinkEntry{entry})
+	}
+}
+
+func (f *forwarder) run() {
+	defer close(f.done)
+
+	ticker := time.NewTicker(time.Duration(f.cfg.FlushIntervalMs) * time.Millisecond)
+	defer ticker.Stop()
+
+	batch := make([]sinkEntry, 0, f.cfg.BatchSize)
+	for {
+		select {
+		case entry := <-f.queue:
+			batch = append(batch, entry)
+			if len(batch) >= f.cfg.BatchSize {
+				f.flush(batch)
+				batch = make([]sinkEntry, 0, f.cfg.BatchSize)
+			}
+		case <-ticker.C:
+			f.flush(batch)
+			batch = make([]sinkEntry, 0, f.cfg.BatchSize)
+		case <-f.stop:
+			for {
+				select {
+				case entry := <-f.queue:
+					batch = append(batch, entry)
+				default:
+					// Do not wait on a destination that is down
+					if f.down.Load() {
+						f.spool.append(batch)
+					} else {
+						f.flush(batch)
+					}
+					return
+				}
+			}
+		}
+	}
+}
+
+// flush delivers batch after anything spooled before it, keeping order
+func (f *forwarder) flush(batch []sinkEntry) {
+	if len(batch) > 0 && (f.spool.pending() || time.Now().Before(f.retryAt)) {
+		f.spool.append(batch)
+		batch = nil
+	}
+	if time.Now().Before(f.retryAt) {
+		return
+	}
+
+	if err := f.spool.drain(f.cfg.BatchSize, f.send); err != nil {
+		f.fail(err)
+		if len(batch) > 0 {
+			f.spool.append(batch)
+		}
+		return
+	}
+	if len(batch) > 0 {
+		if err := f.send(batch); err != nil {
+			f.fail(err)
+			f.spool.append(batch)
+			return
+		}
+	}
+	f.recover()
+}
+
+// send delivers one batch with a few quick retries. Permanent failures drop
+// the batch and count as delivered for ordering purposes.
+func (f *forwarder) send(batch []sinkEntry) error {
+	var err error
+	for attempt := 0; attempt < forwarderRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * 200 * time.Millisecond)
+		}
+		if err = f.writer.WriteBatch(batch); err == nil {
+			f.sent.Add(int64(len(batch)))
+			return nil
+		}
+		if errors.Is(err, errPermanent) {
+			f.dropped.Add(int64(len(batch)))
+			f.lastError.Store(err.Error())
+			log.Warn().Err(err).Str("sink", f.cfg.Name).Int("entries", len(batch)).Msg("Log sink rejected batch, dropped")
+			return nil
+		}
+	}
+	return err
+}
+
+// fail backs off before the next delivery attempt
+func (f *forwarder) fail(err error) {
+	f.failures.Add(1)
+	f.lastError.Store(err.Error())
+	f.backoff = min(max(2*f.backoff, time.Second), forwarderMaxBackoff)
+	f.retryAt = time.Now().Add(f.backoff)
+	if !f.down.Swap(true) {
+		log.Warn().Err(err).Str("sink", f.cfg.Name).Msg("Log sink unavailable, spooling entries")
+	}
+}
+
+func (f *forwarder) recover() {
+	f.backoff = 0
+	f.retryAt = time.Time{}
+	if f.down.Swap(false) {
+		log.Info().Str("sink", f.cfg.Name).Msg("Log sink available again")
+	}
+}
+
+func (f *forwarder) Stats() map[string]interface{} {
+	lastError, _ := f.lastError.Load().(string)
+	return map[string]interface{}{
+		"name":         f.cfg.Name,
+		"type":         f.cfg.Type,
+		"available":    !f.down.Load(),
+		"sent":         f.sent.Load(),
+		"failures":     f.failures.Load(),
+		"dropped":      f.dropped.Load(),
+		"queued":       len(f.queue),
+		"spooled_size": f.spool.sizeBytes(),
+		"last_error":   lastError,
+	}
+}
+
+// Close delivers or spools the queued entries and closes the connection
+func (f *forwarder) Close() error {
+	close(f.stop)
+	<-f.done
+	return f.writer.Close()
+}
@@ -0,0 +1,154 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	syslogAppName = "clipboard-controller"
+	syslogPri     = 16*8 + 6 // Facility local0, severity informational
+	remoteTimeout = 10 * time.Second
+)
+
+// syslogWriter sends entries as RFC 5424 messages, one per entry, with the
+// stream as MSGID. log/syslog is not available on Windows, so messages are
+// formatted here.
+type syslogWriter struct {
+	network  string // udp, tcp or unix
+	address  string
+	conn     net.Conn
+	stream   bool // Stream transport, messages need a delimiter
+	hostname string
+	pid      string
+}
+
+func newSyslogWriter(address string) *syslogWriter {
+	w := &syslogWriter{hostname: "-", pid: strconv.Itoa(os.Getpid())}
+	if u, err := url.Parse(address); err == nil {
+		w.network = u.Scheme
+		w.address = u.Host
+		if u.Scheme == "unix" {
+			w.address = u.Path
+		}
+	}
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		w.hostname = hostname
+	}
+	return w
+}
+
+func (w *syslogWriter) dial() error {
+	if w.conn != nil {
+		return nil
+	}
+
+	var err error
+	if w.network == "unix" {
+		// Local syslog daemons listen on a datagram socket, some on a stream one
+		if w.conn, err = net.DialTimeout("unixgram", w.address, remoteTimeout); err == nil {
+			w.stream = false
+			return nil
+		}
+		w.conn, err = net.DialTimeout("unix", w.address, remoteTimeout)
+		w.stream = true
+		return err
+	}
+	w.conn, err = net.DialTimeout(w.network, w.address, remoteTimeout)
+	w.stream = w.network == "tcp"
+	return err
+}
+
+func (w *syslogWriter) WriteBatch(batch []sinkEntry) error {
+	if err := w.dial(); err != nil {
+		return err
+	}
+
+	for _, entry := range batch {
+		msg := fmt.Sprintf("<%d>1 %s %s %s %s %s - %s",
+			syslogPri, time.Now().UTC().Format(time.RFC3339Nano), w.hostname, syslogAppName, w.pid, entry.Stream, entry.Line)
+		if w.stream {
+			msg += "\n"
+		}
+
+		w.conn.SetWriteDeadline(time.Now().Add(remoteTimeout))
+		if _, err := io.WriteString(w.conn, msg); err != nil {
+			// Reconnect on the next attempt
+			w.conn.Close()
+			w.conn = nil
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *syslogWriter) Close() error {
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// httpWriter POSTs each batch as a JSON array of entries, each with its
+// "log_type"
+type httpWriter struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func newHTTPWriter(url string, headers map[string]string) *httpWriter {
+	return &httpWriter{url: url, headers: headers, client: &http.Client{Timeout: remoteTimeout}}
+}
+
+func (w *httpWriter) WriteBatch(batch []sinkEntry) error {
+	var body bytes.Buffer
+	body.WriteByte('[')
+	for i, entry := range batch {
+		if i > 0 {
+			body.WriteByte(',')
+		}
+		body.Write(withStream(entry.Stream, entry.Line))
+	}
+	body.WriteByte(']')
+
+	req, err := http.NewRequest(http.MethodPost, w.url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range w.headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("collector returned %s", resp.Status)
+	default:
+		// The collector will reject this batch again
+		return fmt.Errorf("%w: collector returned %s", errPermanent, resp.Status)
+	}
+}
+
+func (w *httpWriter) Close() error {
+	w.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,204 @@
+package logger
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/rs/zerolog/log"
+)
+
+// sinkSpool is the on-disk backlog of a remote sink. New entries are
+// appended to path; a drain moves the file aside to path.draining so
+// appends can continue while it is being sent.
+type sinkSpool struct {
+	mu      sync.Mutex
+	path    string
+	maxSize int64
+	size    atomic.Int64 // Bytes in path and path.draining
+	dropped *atomic.Int64
+}
+
+func newSinkSpool(path string, maxSize int64, dropped *atomic.Int64) *sinkSpool {
+	s := &sinkSpool{path: path, maxSize: maxSize, dropped: dropped}
+	for _, p := range []string{path, s.drainingPath()} {
+		if info, err := os.Stat(p); err == nil {
+			s.size.Add(info.Size())
+		}
+	}
+	return s
+}
+
+func (s *sinkSpool) drainingPath() string { return s.path + ".draining" }
+
+// pending reports whether spooled entries wait to be sent
+func (s *sinkSpool) pending() bool { return s.size.Load() > 0 }
+
+func (s *sinkSpool) sizeBytes() int64 { return s.size.Load() }
+
+// append writes entries to the spool; entries over the size limit are
+// dropped and counted
+func (s *sinkSpool) append(entries []sinkEntry) {
+	if len(entries) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		s.dropped.Add(int64(len(entries)))
+		return
+	}
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Warn().Err(err).Str("path", s.path).Msg("Failed to open log sink spool")
+		s.dropped.Add(int64(len(entries)))
+		return
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	for _, entry := range entries {
+		data, err := json.Marshal(entry)
+		if err != nil || s.size.Load()+int64(len(data))+1 > s.maxSize {
+			s.dropped.Add(1)
+			continue
+		}
+		w.Write(data)
+		w.WriteByte('\n')
+		s.size.Add(int64(len(data)) + 1)
+	}
+	if err := w.Flush(); err != nil {
+		log.Warn().Err(err).Str("path", s.path).Msg("Failed to write log sink spool")
+	}
+}
+
+// drain sends the spooled entries in order, batchSize at a time. On failure
+// the unsent entries stay in the spool ahead of anything appended meanwhile.
+func (s *sinkSpool) drain(batchSize int, send func([]sinkEntry) error) error {
+	if !s.pending() {
+		return nil
+	}
+
+	// Move the spool aside unless a previous drain left one
+	s.mu.Lock()
+	draining := s.drainingPath()
+	if _, err := os.Stat(draining); os.IsNotExist(err) {
+		if err := os.Rename(s.path, draining); err != nil && !os.IsNotExist(err) {
+			s.mu.Unlock()
+			return fmt.Errorf("failed to move log sink spool: %w", err)
+		}
+	}
+	s.mu.Unlock()
+
+	file, err := os.Open(draining)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var drainSize int64
+	if info, err := file.Stat(); err == nil {
+		drainSize = info.Size()
+	}
+
+	reader := bufio.NewReader(file)
+	var offset, batchStart int64
+	batch := make([]sinkEntry, 0, batchSize)
+	for {
+		if len(batch) == 0 {
+			batchStart = offset
+		}
+
+		line, readErr := reader.ReadBytes('\n')
+		if readErr == nil {
+			offset += int64(len(line))
+			var entry sinkEntry
+			if json.Unmarshal(line, &entry) == nil {
+				batch = append(batch, entry)
+			}
+		}
+
+		if len(batch) > 0 && (len(batch) >= batchSize || readErr != nil) {
+			if err := send(batch); err != nil {
+				file.Close()
+				s.requeue(draining, batchStart)
+				return err
+			}
+			batch = batch[:0]
+		}
+
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			file.Close()
+			s.requeue(draining, batchStart)
+			return readErr
+		}
+	}
+
+	file.Close()
+	os.Remove(draining)
+	s.size.Add(-drainSize)
+	return nil
+}
+
+// requeue puts the unsent part of the draining file, from offset on, back in
+// front of the spool
+func (s *sinkSpool) requeue(draining string, offset int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tmp := s.path + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		log.Warn().Err(err).Str("path", tmp).Msg("Failed to rewrite log sink spool")
+		return // The draining file is kept and retried as is
+	}
+
+	var written int64
+	copyFrom := func(path string, from int64) error {
+		in, err := os.Open(path)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		if _, err := in.Seek(from, io.SeekStart); err != nil {
+			return err
+		}
+		n, err := io.Copy(out, in)
+		written += n
+		return err
+	}
+
+	err = copyFrom(draining, offset)
+	if err == nil {
+		err = copyFrom(s.path, 0)
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, s.path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		log.Warn().Err(err).Str("path", s.path).Msg("Failed to rewrite log sink spool")
+		return
+	}
+
+	os.Remove(draining)
+	s.size.Store(written)
+}
@@ -0,0 +1,276 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"clipboard-controller/config"
+)
+
+// collector is a stand-in HTTP log collector that rejects batches with 503
+// while down and records the entries it accepts
+type collector struct {
+	server *httptest.Server
+	down   atomic.Bool
+	mu     sync.Mutex
+	got    []int
+	header http.Header
+}
+
+func newCollector(t *testing.T) *collector {
+	c := &collector{}
+	c.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c.down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var batch []struct {
+			LogType string `json:"log_type"`
+			N       int    `json:"n"`
+		}
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &batch); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		c.mu.Lock()
+		for _, entry := range batch {
+			c.got = append(c.got, entry.N)
+		}
+		c.header = r.Header.Clone()
+		c.mu.Unlock()
+	}))
+	t.Cleanup(c.server.Close)
+	return c
+}
+
+// received returns the entry numbers accepted so far
+func (c *collector) received() []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]int(nil), c.got...)
+}
+
+func (c *collector) sinkConfig() config.LogSinkConfig {
+	return config.LogSinkConfig{
+		Name:            "collector",
+		Type:            config.LogSinkHTTP,
+		URL:             c.server.URL,
+		Headers:         map[string]string{"Authorization": "Bearer s3cret"},
+		BatchSize:       5,
+		FlushIntervalMs: 20,
+		SpoolMaxMB:      1,
+	}
+}
+
+// numbered returns entries from..to-1 with their number as the line
+func numbered(from, to int) []sinkEntry {
+	entries := make([]sinkEntry, 0, to-from)
+	for n := from; n < to; n++ {
+		entries = append(entries, sinkEntry{Stream: config.LogStreamRequests, Line: json.RawMessage(fmt.Sprintf(`{"n":%d}`, n))})
+	}
+	return entries
+}
+
+func entryNumbers(t *testing.T, entries []sinkEntry) []int {
+	t.Helper()
+	numbers := make([]int, 0, len(entries))
+	for _, entry := range entries {
+		var line struct{ N int }
+		if err := json.Unmarshal(entry.Line, &line); err != nil {
+			t.Fatal(err)
+		}
+		numbers = append(numbers, line.N)
+	}
+	return numbers
+}
+
+// checkSequence fails unless got is exactly 0..want-1
+func checkSequence(t *testing.T, got []int, want int) {
+	t.Helper()
+	if len(got) != want {
+		t.Fatalf("received %d entries, want %d: %v", len(got), want, got)
+	}
+	for i, n := range got {
+		if n != i {
+			t.Fatalf("entry %d is %d, want entries in order: %v", i, n, got)
+		}
+	}
+}
+
+func waitForSink(t *testing.T, timeout time.Duration, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// The HTTP writer posts a batch as one JSON array tagged with log_type and
+// tells retryable failures from permanent ones
+func TestHTTPWriterBatch(t *testing.T) {
+	c := newCollector(t)
+	w := newHTTPWriter(c.server.URL, map[string]string{"Authorization": "Bearer s3cret"})
+	defer w.Close()
+
+	if err := w.WriteBatch(numbered(0, 3)); err != nil {
+		t.Fatal(err)
+	}
+	checkSequence(t, c.received(), 3)
+	if got := c.header.Get("Authorization"); got != "Bearer s3cret" {
+		t.Errorf("Authorization = %q, want the configured header", got)
+	}
+
+	c.down.Store(true)
+	if err := w.WriteBatch(numbered(3, 4)); err == nil || errors.Is(err, errPermanent) {
+		t.Errorf("503: err = %v, want a retryable error", err)
+	}
+	c.down.Store(false)
+
+	// The collector answers 400 to a body it cannot parse
+	bad := []sinkEntry{{Stream: config.LogStreamRequests, Line: json.RawMessage(`"not an object"`)}}
+	if err := w.WriteBatch(bad); !errors.Is(err, errPermanent) {
+		t.Errorf("400: err = %v, want a permanent error", err)
+	}
+}
+
+// A failed drain puts the unsent entries back ahead of those appended while
+// it ran, and the next drain sends them all in order
+func TestSpoolRequeueKeepsOrder(t *testing.T) {
+	for _, failAt := range []int{0, 1, 2} {
+		t.Run(fmt.Sprintf("batch %d fails", failAt), func(t *testing.T) {
+			var dropped atomic.Int64
+			path := filepath.Join(t.TempDir(), "spool", "collector.jsonl")
+			s := newSinkSpool(path, 1<<20, &dropped)
+			s.append(numbered(0, 7))
+
+			var sent []int
+			batches := 0
+			err := s.drain(3, func(batch []sinkEntry) error {
+				if batches == failAt {
+					// Entries written while the spool is being drained
+					s.append(numbered(7, 10))
+					return fmt.Errorf("collector down")
+				}
+				batches++
+				sent = append(sent, entryNumbers(t, batch)...)
+				return nil
+			})
+			if err == nil {
+				t.Fatal("drain succeeded with a failing send")
+			}
+
+			if _, err := os.Stat(s.drainingPath()); !os.IsNotExist(err) {
+				t.Errorf("draining file left after requeue: %v", err)
+			}
+			if info, err := os.Stat(path); err != nil || info.Size() != s.sizeBytes() {
+				t.Errorf("spool size %d does not match the file: %v %v", s.sizeBytes(), info, err)
+			}
+
+			if err := s.drain(3, func(batch []sinkEntry) error {
+				sent = append(sent, entryNumbers(t, batch)...)
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+			checkSequence(t, sent, 10)
+			if s.pending() || dropped.Load() != 0 {
+				t.Errorf("after drain: pending %v, dropped %d", s.pending(), dropped.Load())
+			}
+		})
+	}
+}
+
+// Entries are dropped and counted once the spool reaches its size limit
+func TestSpoolSizeLimit(t *testing.T) {
+	var dropped atomic.Int64
+	entry, _ := json.Marshal(numbered(0, 1)[0])
+	s := newSinkSpool(filepath.Join(t.TempDir(), "collector.jsonl"), 3*int64(len(entry)+1), &dropped)
+
+	s.append(numbered(0, 5))
+	if dropped.Load() != 2 {
+		t.Errorf("dropped = %d, want 2", dropped.Load())
+	}
+
+	var sent []int
+	s.drain(10, func(batch []sinkEntry) error {
+		sent = append(sent, entryNumbers(t, batch)...)
+		return nil
+	})
+	checkSequence(t, sent, 3)
+}
+
+// While the collector is down entries are spooled; once it is back they all
+// arrive, in order, with none lost
+func TestForwarderRecoversInOrder(t *testing.T) {
+	c := newCollector(t)
+	c.down.Store(true)
+	f := newForwarder(c.sinkConfig(), t.TempDir(), newHTTPWriter(c.server.URL, nil))
+	defer f.Close()
+
+	write := func(from, to int) {
+		for _, entry := range numbered(from, to) {
+			f.Write(entry.Stream, entry.Line)
+		}
+	}
+
+	write(0, 12)
+	waitForSink(t, 5*time.Second, "a failed delivery", func() bool { return f.failures.Load() > 0 })
+	if f.Stats()["available"] != false {
+		t.Error("sink reported available while the collector is down")
+	}
+	write(12, 30)
+	waitForSink(t, 5*time.Second, "entries spooled", func() bool { return f.spool.pending() })
+
+	c.down.Store(false)
+	waitForSink(t, 10*time.Second, "every entry delivered", func() bool { return len(c.received()) >= 30 })
+	write(30, 35)
+	waitForSink(t, 5*time.Second, "entries after recovery", func() bool { return len(c.received()) >= 35 })
+
+	checkSequence(t, c.received(), 35)
+	waitForSink(t, time.Second, "sink available", func() bool { return f.Stats()["available"] == true })
+	if stats := f.Stats(); stats["dropped"] != int64(0) || stats["spooled_size"] != int64(0) {
+		t.Errorf("stats = %v", stats)
+	}
+}
+
+// Entries spooled by a forwarder closed while the collector is down are
+// delivered by the next one, ahead of new entries
+func TestForwarderSpoolSurvivesRestart(t *testing.T) {
+	c := newCollector(t)
+	c.down.Store(true)
+	dir := t.TempDir()
+
+	f := newForwarder(c.sinkConfig(), dir, newHTTPWriter(c.server.URL, nil))
+	for _, entry := range numbered(0, 8) {
+		f.Write(entry.Stream, entry.Line)
+	}
+	waitForSink(t, 5*time.Second, "a failed delivery", func() bool { return f.failures.Load() > 0 })
+	for _, entry := range numbered(8, 10) {
+		f.Write(entry.Stream, entry.Line)
+	}
+	f.Close()
+
+	c.down.Store(false)
+	f = newForwarder(c.sinkConfig(), dir, newHTTPWriter(c.server.URL, nil))
+	defer f.Close()
+	for _, entry := range numbered(10, 13) {
+		f.Write(entry.Stream, entry.Line)
+	}
+
+	waitForSink(t, 5*time.Second, "every entry delivered", func() bool { return len(c.received()) >= 13 })
+	checkSequence(t, c.received(), 13)
+}
@@ -0,0 +1,151 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"clipboard-controller/model"
+)
+
+// maxTimelineEntries bounds a timeline; a ticket polling /lock/check for its
+// whole TTL produces a few hundred request entries
+const maxTimelineEntries = 5000
+
+// Timeline entry sources
+const (
+	TimelineSourceRequest   = "request"
+	TimelineSourceLockEvent = "lock_event"
+)
+
+// TimelineEntry is one request log or lock event line of a ticket
+type TimelineEntry struct {
+	Timestamp time.Time       `json:"timestamp"`
+	Source    string          `json:"source"` // request or lock_event
+	Entry     json.RawMessage `json:"entry"`
+}
+
+// TicketTimeline is everything logged about one ticket, oldest first
+type TicketTimeline struct {
+	TicketID       string          `json:"ticket_id"`
+	ToolID         string          `json:"tool_id,omitempty"`
+	ThreadID       string          `json:"thread_id,omitempty"`
+	RequestedAt    *time.Time      `json:"requested_at,omitempty"`
+	GrantedAt      *time.Time      `json:"granted_at,omitempty"`
+	EndedAt        *time.Time      `json:"ended_at,omitempty"`
+	WaitDurationMs int64           `json:"wait_duration_ms,omitempty"`
+	HoldDurationMs int64           `json:"hold_duration_ms,omitempty"`
+	EndReason      string          `json:"end_reason,omitempty"` // released, or the expiry reason
+	Entries        []TimelineEntry `json:"entries"`
+	Truncated      bool            `json:"truncated,omitempty"` // More than maxTimelineEntries matched
+	ScannedLines   int64           `json:"scanned_lines"`
+}
+
+// TicketTimeline collects the lock events of ticketID logged in [from, to)
+// and the requests that carried the ticket ID or one of those events'
+// request IDs, and derives the ticket's wait/hold durations and end reason.
+func (lfm *LogFileManager) TicketTimeline(ctx context.Context, ticketID string, from, to time.Time) (*TicketTimeline, error) {
+	timeline := &TicketTimeline{TicketID: ticketID, Entries: make([]TimelineEntry, 0)}
+	startDate, endDate := from.Local().Format("2006-01-02"), to.Local().Format("2006-01-02")
+	inRange := func(ts time.Time) bool { return !ts.Before(from) && ts.Before(to) }
+
+	add := func(source string, fields *logQueryFields, line []byte) bool {
+		if len(timeline.Entries) >= maxTimelineEntries {
+			timeline.Truncated = true
+			return false
+		}
+		timeline.Entries = append(timeline.Entries, TimelineEntry{Timestamp: fields.Timestamp, Source: source, Entry: line})
+		return true
+	}
+
+	requestIDs := make(map[string]bool)
+	var events []model.LockEventLog
+	_, scanned, err := lfm.scan(ctx, "lock_events", logPosition{date: startDate}, endDate, func(fields *logQueryFields, line []byte) bool {
+		if fields.TicketID != ticketID || !inRange(fields.Timestamp) {
+			return true
+		}
+		var event model.LockEventLog
+		if json.Unmarshal(line, &event) == nil {
+			events = append(events, event)
+		}
+		if fields.RequestID != "" {
+			requestIDs[fields.RequestID] = true
+		}
+		return add(TimelineSourceLockEvent, fields, line)
+	})
+	timeline.ScannedLines += scanned
+	if err != nil {
+		return nil, err
+	}
+
+	_, scanned, err = lfm.scan(ctx, "requests", logPosition{date: startDate}, endDate, func(fields *logQueryFields, line []byte) bool {
+		if (fields.TicketID != ticketID && !requestIDs[fields.RequestID]) || !inRange(fields.Timestamp) {
+			return true
+		}
+		return add(TimelineSourceRequest, fields, line)
+	})
+	timeline.ScannedLines += scanned
+	if err != nil {
+		return nil, err
+	}
+
+	// Requests are logged with their start time, so on ties they come first
+	sort.SliceStable(timeline.Entries, func(i, j int) bool {
+		a, b := timeline.Entries[i], timeline.Entries[j]
+		if !a.Timestamp.Equal(b.Timestamp) {
+			return a.Timestamp.Before(b.Timestamp)
+		}
+		return a.Source == TimelineSourceRequest && b.Source != TimelineSourceRequest
+	})
+
+	timeline.summarize(events)
+	return timeline, nil
+}
+
+// summarize fills the ticket summary from its lock events, in seq order.
+// The writer numbers entries in the order they are logged and a ticket does
+// not outlive the process, so seq orders its events even when timestamps
+// tie; entries logged before seq existed keep their timestamp order.
+func (t *TicketTimeline) summarize(events []model.LockEventLog) {
+	sort.SliceStable(events, func(i, j int) bool {
+		a, b := events[i], events[j]
+		if a.Seq != 0 && b.Seq != 0 {
+			return a.Seq < b.Seq
+		}
+		return a.Timestamp.Before(b.Timestamp)
+	})
+
+	for _, event := range events {
+		ts := event.Timestamp
+		if t.ToolID == "" {
+			t.ToolID, t.ThreadID = event.ToolID, event.ThreadID
+		}
+
+		switch event.EventType {
+		case model.LockEventRequested:
+			t.RequestedAt = &ts
+		case model.LockEventGranted:
+			t.GrantedAt = &ts
+			t.WaitDurationMs = event.WaitDurationMs
+		case model.LockEventReleased:
+			t.EndedAt = &ts
+			t.HoldDurationMs = event.HoldDurationMs
+			t.EndReason = "released"
+		case model.LockEventExpired:
+			t.EndedAt = &ts
+			t.HoldDurationMs = event.HoldDurationMs
+			t.EndReason = event.Reason
+			if t.EndReason == "" {
+				t.EndReason = "expired"
+			}
+		}
+	}
+
+	if t.WaitDurationMs == 0 && t.RequestedAt != nil && t.GrantedAt != nil {
+		t.WaitDurationMs = t.GrantedAt.Sub(*t.RequestedAt).Milliseconds()
+	}
+	if t.HoldDurationMs == 0 && t.GrantedAt != nil && t.EndedAt != nil {
+		t.HoldDurationMs = t.EndedAt.Sub(*t.GrantedAt).Milliseconds()
+	}
+}
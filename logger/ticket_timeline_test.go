@@ -0,0 +1,31 @@
+package logger
+
+import (
+	"testing"
+	"time"
+
+	"clipboard-controller/model"
+)
+
+// Events with the same timestamp are summarized in seq order
+func TestSummarizeOrdersBySeq(t *testing.T) {
+	at := time.Now()
+	events := []model.LockEventLog{
+		{Seq: 4, Timestamp: at, EventType: model.LockEventExpired, Reason: "admin_cancel"},
+		{Seq: 2, Timestamp: at, EventType: model.LockEventGranted, WaitDurationMs: 5},
+		{Seq: 3, Timestamp: at, EventType: model.LockEventReleased, HoldDurationMs: 40},
+		{Seq: 1, Timestamp: at, EventType: model.LockEventRequested},
+	}
+
+	var timeline TicketTimeline
+	timeline.summarize(events)
+
+	if timeline.EndReason != "admin_cancel" {
+		t.Errorf("end reason = %q, want admin_cancel", timeline.EndReason)
+	}
+	for i, event := range events {
+		if event.Seq != uint64(i+1) {
+			t.Fatalf("events not in seq order: %v", events)
+		}
+	}
+}
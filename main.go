@@ -4,6 +4,7 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"os/signal"
@@ -14,8 +15,10 @@ import (
 	"clipboard-controller/handler"
 	"clipboard-controller/logger"
 	"clipboard-controller/middleware"
+	"clipboard-controller/model"
 	"clipboard-controller/service"
 	"clipboard-controller/tray"
+	"clipboard-controller/webhook"
 
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog"
@@ -27,18 +30,27 @@ var (
 	StartTime time.Time
 )
 
-// Command line flags
+// Command line flags. Every config key also gets its own flag
+// (e.g. --lock-max-duration), see config.RegisterFlags.
 var (
-	configPath = flag.String("config", "config.yaml", "Path to config file")
-	port       = flag.Int("port", 0, "Server port (overrides config)")
-	logLevel   = flag.String("log-level", "", "Log level: debug, info, warn, error (overrides config)")
-	logDir     = flag.String("log-dir", "", "Log directory (overrides config)")
-	noTray     = flag.Bool("no-tray", false, "Disable system tray (run as console only)")
+	configPath  = flag.String("config", "config.yaml", "Path to config file")
+	noTray      = flag.Bool("no-tray", false, "Disable system tray (run as console only)")
+	configFlags = config.RegisterFlags(flag.CommandLine)
 )
 
 func main() {
 	StartTime = time.Now()
 
+	// Subcommands
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "summarize":
+			os.Exit(runSummarize(os.Args[2:]))
+		case "verify-audit":
+			os.Exit(runVerifyAudit(os.Args[2:]))
+		}
+	}
+
 	// Parse command line flags
 	flag.Parse()
 
@@ -48,25 +60,14 @@ func main() {
 	}
 
 	// Setup zerolog (basic setup first)
-	setupLogger()
+	setupLogger(os.Stdout)
 
-	// Load config
-	cfg, err := config.Load(*configPath)
+	// Load config: defaults -> YAML -> environment -> command line
+	cfg, cfgSources, err := config.Load(*configPath, configFlags.Values())
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to load config")
 	}
 
-	// Apply command line overrides
-	if *port > 0 {
-		cfg.Port = *port
-	}
-	if *logLevel != "" {
-		cfg.LogLevel = *logLevel
-	}
-	if *logDir != "" {
-		cfg.LogDir = *logDir
-	}
-
 	// Validate config
 	if err := cfg.Validate(); err != nil {
 		log.Fatal().Err(err).Msg("Invalid config")
@@ -75,6 +76,14 @@ func main() {
 	// Set log level from config
 	setLogLevel(cfg.LogLevel)
 
+	// A stdout sink owns stdout; console logs move to stderr
+	for _, sink := range cfg.LogSinks {
+		if sink.Type == config.LogSinkStdout {
+			setupLogger(os.Stderr)
+			break
+		}
+	}
+
 	log.Info().
 		Int("port", cfg.Port).
 		Str("log_level", cfg.LogLevel).
@@ -82,18 +91,34 @@ func main() {
 		Msg("Config loaded")
 
 	// Initialize log file manager
-	logFileManager, err := logger.NewLogFileManager(cfg.LogDir, cfg.LogRetentionDays)
+	logFileManager, err := logger.NewLogFileManager(cfg.LogDir, cfg.LogRetentionDays, cfg.LogQueueSize, cfg.LogOverflowPolicy)
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to initialize log file manager")
 	}
+	logFileManager.SetRotation(cfg.LogMaxFileSizeMB, cfg.LogMaxTotalSizeMB, cfg.LogCompress)
+
+	// Forward log entries to the configured sinks
+	sinks, err := logger.NewSinks(cfg.Sinks(), cfg.LogDir)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to initialize log sinks")
+	}
+	logFileManager.SetSinks(sinks)
+
+	// Publish config as an immutable snapshot shared by all services
+	cfgStore := config.NewStore(cfg, cfgSources)
 
 	// Initialize event logger
-	eventLogger := logger.NewEventLogger(logFileManager)
-	eventLogger.SetLogHeartbeats(cfg.LogHeartbeats)
+	eventLogger := logger.NewEventLogger(logFileManager, cfgStore)
+
+	// Restore today's summary counters from the log files after a restart
+	if err := eventLogger.RestoreDailyCounters(context.Background()); err != nil {
+		log.Warn().Err(err).Msg("Failed to restore daily counters from log files")
+	}
 
 	// Initialize services
-	toolRegistry := service.NewToolRegistry(cfg)
-	lockManager := service.NewLockManager(cfg, toolRegistry)
+	toolRegistry := service.NewToolRegistry(cfgStore)
+	lockManager := service.NewLockManager(cfgStore, toolRegistry)
+	toolLifecycle := service.NewToolLifecycle(toolRegistry, lockManager)
 
 	// Set event logger on services
 	toolRegistry.SetEventLogger(eventLogger)
@@ -104,41 +129,79 @@ func main() {
 		return toolRegistry.CountOnlineTools(), lockManager.QueueLength(), lockManager.GetCurrentLockHolder()
 	}
 
+	// Watch config file for changes
+	cfgReloader := config.NewReloader(*configPath, cfgStore, configFlags.Values())
+	cfgReloader.OnWatchReload(func(changes []config.Change) {
+		if len(changes) > 0 {
+			eventLogger.LogConfigReload(model.AuditActorConfigFile, changes)
+		}
+	})
+	cfgReloader.Start()
+
 	// Start service background jobs
-	bgJobs := service.NewBackgroundJobs(cfg, toolRegistry, lockManager)
+	bgJobs := service.NewBackgroundJobs(cfgStore, toolRegistry, toolLifecycle, lockManager)
 	bgJobs.Start()
 
 	// Start logging background jobs
 	ctx, cancelCtx := context.WithCancel(context.Background())
-	logBgJobs := logger.NewBackgroundJobs(logFileManager, eventLogger, metricsProvider)
+	logBgJobs := logger.NewBackgroundJobs(logFileManager, eventLogger, cfgStore, metricsProvider)
+
+	// Alert rules are evaluated by the logging background jobs
+	alertManager := logger.NewAlertManager(eventLogger, cfgStore, logger.AlertSources{
+		QueueLength:  lockManager.QueueLength,
+		OfflineTools: offlineTools(toolRegistry),
+	})
+	logBgJobs.SetAlertManager(alertManager)
 	logBgJobs.Start(ctx)
 
+	// Deliver lock and tool events to the configured webhooks
+	webhookDispatcher, err := webhook.NewDispatcher(cfgStore, webhook.DeadLetterPath(cfg.LogDir))
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to initialize webhook dispatcher")
+	}
+	webhookLockEvents, unsubscribeWebhookLocks := eventLogger.Subscribe(1024)
+	webhookToolEvents, unsubscribeWebhookTools := eventLogger.SubscribeTools(256)
+	webhookDispatcher.Start(webhookLockEvents, webhookToolEvents)
+
 	// Setup Gin
 	if cfg.LogLevel != "debug" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
 	router := gin.New()
-	router.Use(gin.Recovery())
+	router.Use(middleware.RequestID(), handler.Recovery())
+	handler.RegisterErrorHandlers(router)
 
-	// Add request logging middleware
-	if cfg.LogRequests {
-		router.Use(middleware.RequestLogger(logFileManager))
-	}
+	// Add request logging middleware (log_requests is checked per request)
+	router.Use(middleware.RequestLogger(logFileManager, eventLogger, cfgStore))
 	router.Use(consoleRequestLogger())
 
-	// Register handlers
+	// Register handlers (handler tests build the same router in newTestServer)
 	handler.RegisterHealthHandler(router, Version, &StartTime)
-	handler.RegisterToolHandler(router, toolRegistry, cfg)
-	handler.RegisterLockHandler(router, lockManager, cfg)
-	handler.RegisterConfigHandler(router, cfg)
-	handler.RegisterDebugHandler(router, eventLogger, logFileManager)
+	handler.RegisterToolHandler(router, toolRegistry, toolLifecycle, cfgStore, eventLogger)
+	handler.RegisterToolsHandler(router, toolRegistry, lockManager, eventLogger)
+	handler.RegisterLockHandler(router, lockManager, cfgStore)
+	handler.RegisterConfigHandler(router, cfgStore, cfgReloader, eventLogger)
+	handler.RegisterDebugHandler(router, eventLogger, logFileManager, lockManager)
+	handler.RegisterReportHandler(router, logFileManager, cfgStore)
+	handler.RegisterAlertHandler(router, alertManager)
+	handler.RegisterWebhookHandler(router, webhookDispatcher, cfgStore, cfgReloader, eventLogger)
+	handler.RegisterAdminHandler(router, lockManager, toolLifecycle, cfgStore, eventLogger)
+	dashboard := handler.RegisterDashboardHandler(router, toolRegistry, lockManager, eventLogger, cfgStore)
+	handler.RegisterOpenAPIHandler(router, Version)
+
+	// Every route must be described in the OpenAPI document
+	if missing := handler.UndocumentedRoutes(router.Routes()); len(missing) > 0 {
+		log.Warn().Strs("routes", missing).Msg("Routes missing from OpenAPI spec")
+	}
 
 	// Create HTTP server
 	srv := &http.Server{
 		Addr:    fmt.Sprintf(":%d", cfg.Port),
 		Handler: router,
 	}
+	// End dashboard event streams so Shutdown does not wait on them
+	srv.RegisterOnShutdown(dashboard.Close)
 
 	// Start server in goroutine
 	go func() {
@@ -170,6 +233,26 @@ func main() {
 		doShutdown()
 	}()
 
+	// Reload config on SIGHUP
+	go func() {
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		for {
+			select {
+			case <-shutdown:
+				return
+			case <-hup:
+				log.Info().Msg("SIGHUP received, reloading config")
+				changes, err := cfgReloader.Reload()
+				if err != nil {
+					log.Error().Err(err).Msg("Config reload failed, keeping current config")
+					continue
+				}
+				eventLogger.LogConfigReload(model.AuditActorSignal, changes)
+			}
+		}
+	}()
+
 	// Run with or without system tray
 	if *noTray {
 		// Console mode - wait for shutdown signal
@@ -177,6 +260,7 @@ func main() {
 	} else {
 		// System tray mode
 		trayApp := tray.New(cfg.Port, doShutdown)
+		alertManager.SetTrayNotifier(trayApp.Notify)
 
 		// Update tray status periodically
 		go func() {
@@ -188,6 +272,7 @@ func main() {
 					return
 				case <-ticker.C:
 					trayApp.UpdateStatus(toolRegistry.CountOnlineTools(), lockManager.QueueLength())
+					trayApp.UpdateAlerts(alertManager.FiringCount())
 				}
 			}
 		}()
@@ -198,31 +283,51 @@ func main() {
 
 	log.Info().Msg("Shutting down server...")
 
+	// Graceful shutdown with timeout; in-flight requests still log
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Error().Err(err).Msg("Server forced to shutdown")
+	}
+
 	// Stop background jobs
+	cfgReloader.Stop()
 	bgJobs.Stop()
 	logBgJobs.Stop()
 	cancelCtx()
 
-	// Close log file manager
+	// Pending webhook deliveries go to the dead-letter file
+	unsubscribeWebhookLocks()
+	unsubscribeWebhookTools()
+	webhookDispatcher.Stop()
+
+	// Close log file manager last so queued entries are written
 	if err := logFileManager.Close(); err != nil {
 		log.Error().Err(err).Msg("Error closing log file manager")
 	}
 
-	// Graceful shutdown with timeout
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	log.Info().Msg("Server exited")
+}
 
-	if err := srv.Shutdown(shutdownCtx); err != nil {
-		log.Error().Err(err).Msg("Server forced to shutdown")
+// offlineTools lists the registered tools that went offline by heartbeat
+// timeout, for the tool_offline alert rules. Unregistered tools left cleanly.
+func offlineTools(tr *service.ToolRegistry) func() []string {
+	return func() []string {
+		var ids []string
+		for _, tool := range tr.ListTools(nil) {
+			if tool.Status == model.ToolStatusOffline && tool.OfflineReason == model.OfflineReasonHeartbeatTimeout {
+				ids = append(ids, tool.ToolID)
+			}
+		}
+		return ids
 	}
-
-	log.Info().Msg("Server exited")
 }
 
-func setupLogger() {
+func setupLogger(out io.Writer) {
 	zerolog.TimeFieldFormat = time.RFC3339Nano
 	log.Logger = log.Output(zerolog.ConsoleWriter{
-		Out:        os.Stdout,
+		Out:        out,
 		TimeFormat: "2006-01-02 15:04:05.000",
 	})
 }
@@ -258,6 +363,7 @@ func consoleRequestLogger() gin.HandlerFunc {
 			Int("status", status).
 			Dur("latency", latency).
 			Str("client_ip", c.ClientIP()).
+			Str("request_id", c.GetString("request_id")).
 			Msg("Request")
 	}
 }
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"regexp"
+
+	"clipboard-controller/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader carries the request ID in requests and responses
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID limits accepted incoming request IDs so they are safe to log
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:@-]{1,128}$`)
+
+// RequestID assigns every request an ID, reusing a valid incoming
+// X-Request-ID header. The ID is stored as "request_id" in the gin context,
+// carried by the request context (see model.RequestIDFromContext) and echoed
+// in the X-Request-ID response header.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = uuid.New().String()
+		}
+
+		c.Set("request_id", requestID)
+		c.Request = c.Request.WithContext(model.WithRequestID(c.Request.Context(), requestID))
+		c.Header(RequestIDHeader, requestID)
+
+		c.Next()
+	}
+}
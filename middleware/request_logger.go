@@ -6,19 +6,19 @@ import (
 	"io"
 	"time"
 
+	"clipboard-controller/config"
 	"clipboard-controller/logger"
 	"clipboard-controller/model"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 )
 
-// RequestLogger creates a middleware that logs all HTTP requests
-func RequestLogger(lfm *logger.LogFileManager) gin.HandlerFunc {
+// RequestLogger creates a middleware that counts every request in the daily
+// summary and logs it, honoring log_requests and the request sampling rules
+// at request time. It expects RequestID to run first.
+func RequestLogger(lfm *logger.LogFileManager, el *logger.EventLogger, cfg *config.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Generate request ID
-		requestID := uuid.New().String()
-		c.Set("request_id", requestID)
+		requestID := c.GetString("request_id")
 
 		// Record start time
 		startTime := time.Now()
@@ -41,6 +41,16 @@ func RequestLogger(lfm *logger.LogFileManager) gin.HandlerFunc {
 		// Calculate duration
 		duration := time.Since(startTime)
 
+		// Count before sampling so the summary sees every request
+		el.IncrementRequests()
+		if c.Writer.Status() >= 400 {
+			el.IncrementErrors()
+		}
+
+		if !logger.ShouldLogRequest(cfg.Get(), c.Request.URL.Path, c.Writer.Status()) {
+			return
+		}
+
 		// Get error if any
 		var errorMsg string
 		if len(c.Errors) > 0 {
@@ -74,12 +84,7 @@ func RequestLogger(lfm *logger.LogFileManager) gin.HandlerFunc {
 		}
 
 		// Write to log file asynchronously
-		go func() {
-			if err := lfm.WriteJSON("requests", logEntry); err != nil {
-				// Don't use zerolog here to avoid circular logging
-				// Just silently ignore errors
-			}
-		}()
+		lfm.Enqueue("requests", &logEntry)
 	}
 }
 
@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// Alert states
+const (
+	AlertStatePending  = "pending"  // Condition holds, waiting for the rule's duration
+	AlertStateFiring   = "firing"   // Notified
+	AlertStateResolved = "resolved" // Condition no longer holds
+)
+
+// Alert is the state of one alert rule, or of one tool for per-tool rules.
+// An alert is notified when it starts firing and when it resolves.
+type Alert struct {
+	Rule       string     `json:"rule"`
+	Metric     string     `json:"metric"`
+	Severity   string     `json:"severity"`
+	ToolID     string     `json:"tool_id,omitempty"` // Per-tool rules
+	State      string     `json:"state"`
+	Value      float64    `json:"value"` // Latest evaluated value
+	Op         string     `json:"op"`
+	Threshold  float64    `json:"threshold"`
+	Message    string     `json:"message"`
+	StartedAt  time.Time  `json:"started_at"` // Condition first held
+	FiredAt    *time.Time `json:"fired_at,omitempty"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
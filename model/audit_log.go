@@ -0,0 +1,62 @@
+package model
+
+import "time"
+
+// Audit actions
+const (
+	AuditConfigUpdate   = "config_update"
+	AuditConfigReload   = "config_reload"
+	AuditLockRelease    = "lock_release"
+	AuditTicketCancel   = "ticket_cancel"
+	AuditToolUnregister = "tool_unregister"
+	AuditAuthFailure    = "auth_failure"
+	AuditWebhookCreate  = "webhook_create"
+	AuditWebhookUpdate  = "webhook_update"
+	AuditWebhookDelete  = "webhook_delete"
+)
+
+// Audit results
+const (
+	AuditResultSuccess = "success"
+	AuditResultDenied  = "denied"
+)
+
+// Audit actors that are not a caller of the HTTP API
+const (
+	AuditActorAnonymous  = "anonymous"   // Endpoint without authentication
+	AuditActorAdminToken = "admin_token" // Caller presented the admin token
+	AuditActorLocalhost  = "localhost"   // Loopback caller, no admin token configured
+	AuditActorTool       = "tool"        // A tool acting on its own registration
+	AuditActorConfigFile = "config_file" // The config file watcher
+	AuditActorSignal     = "signal"      // SIGHUP
+)
+
+// AuditLog records an administrative or configuration action. Entries are
+// hash-chained: Hash covers the entry as written without the hash field,
+// including PrevHash, the hash of the entry before it.
+type AuditLog struct {
+	Timestamp time.Time     `json:"timestamp"`
+	Seq       uint64        `json:"seq,omitempty"`
+	Action    string        `json:"action"`
+	Actor     string        `json:"actor"`
+	ClientIP  string        `json:"client_ip,omitempty"`
+	RequestID string        `json:"request_id,omitempty"`
+	Target    string        `json:"target,omitempty"`
+	Changes   []AuditChange `json:"changes,omitempty"`
+	Result    string        `json:"result"`
+	Reason    string        `json:"reason,omitempty"`
+	Count     int           `json:"count,omitempty"` // Repeated auth failures this entry stands for
+	PrevHash  string        `json:"prev_hash"`
+	Hash      string        `json:"hash,omitempty"` // Set by the writer
+}
+
+// AuditChange is one config value changed by an audited action
+type AuditChange struct {
+	Key             string      `json:"key"`
+	Old             interface{} `json:"old"`
+	New             interface{} `json:"new"`
+	RestartRequired bool        `json:"restart_required,omitempty"`
+}
+
+// SetSeq implements Sequenced
+func (l *AuditLog) SetSeq(seq uint64) { l.Seq = seq }
@@ -0,0 +1,19 @@
+package model
+
+// Distribution summarizes the values recorded over a period. Percentiles are
+// keyed "p50", "p99.9", ... and are estimates within 12.5% of the true value.
+type Distribution struct {
+	Count       int64            `json:"count"`
+	Min         int64            `json:"min"`
+	Max         int64            `json:"max"`
+	Percentiles map[string]int64 `json:"percentiles"`
+}
+
+// Distributions groups the distributions of a period. A distribution with no
+// values is omitted.
+type Distributions struct {
+	WaitTimeMs  *Distribution `json:"wait_time_ms,omitempty"` // Time from request to grant
+	HoldTimeMs  *Distribution `json:"hold_time_ms,omitempty"` // Time from grant to release or expiry
+	QueueLength *Distribution `json:"queue_length,omitempty"` // Queue length seen by each lock request
+	ExtendCount *Distribution `json:"extend_count,omitempty"` // Extends of each released or expired lock
+}
@@ -5,6 +5,7 @@ import "time"
 // RequestLog records every HTTP request
 type RequestLog struct {
 	Timestamp  time.Time `json:"timestamp"`
+	Seq        uint64    `json:"seq,omitempty"`
 	RequestID  string    `json:"request_id"`
 	Method     string    `json:"method"`
 	Path       string    `json:"path"`
@@ -20,6 +21,7 @@ type RequestLog struct {
 // LockEventLog records lock lifecycle events
 type LockEventLog struct {
 	Timestamp      time.Time `json:"timestamp"`
+	Seq            uint64    `json:"seq,omitempty"`
 	EventType      string    `json:"event_type"` // lock_requested, lock_granted, lock_released, lock_expired, lock_extended
 	RequestID      string    `json:"request_id,omitempty"` // Correlation with HTTP request
 	TicketID       string    `json:"ticket_id"`
@@ -30,6 +32,7 @@ type LockEventLog struct {
 	WaitDurationMs int64     `json:"wait_duration_ms,omitempty"`
 	HoldDurationMs int64     `json:"hold_duration_ms,omitempty"`
 	Reason         string    `json:"reason,omitempty"`
+	ToolInfo       *ToolInfo `json:"tool_info,omitempty"` // Metadata the tool registered with
 }
 
 // Lock event types
@@ -44,9 +47,11 @@ const (
 // ToolEventLog records tool lifecycle events
 type ToolEventLog struct {
 	Timestamp time.Time `json:"timestamp"`
-	EventType string    `json:"event_type"` // tool_registered, tool_heartbeat, tool_offline, tool_unregistered
+	Seq       uint64    `json:"seq,omitempty"`
+	EventType string    `json:"event_type"` // tool_registered, tool_heartbeat, tool_offline, tool_unregistered, tool_removed
 	ToolID    string    `json:"tool_id"`
 	Reason    string    `json:"reason,omitempty"`
+	ToolInfo  *ToolInfo `json:"tool_info,omitempty"`
 }
 
 // Tool event types
@@ -55,42 +60,64 @@ const (
 	ToolEventHeartbeat    = "tool_heartbeat"
 	ToolEventOffline      = "tool_offline"
 	ToolEventUnregistered = "tool_unregistered"
+	ToolEventRemoved      = "tool_removed"
 )
 
 // SystemMetricsLog records system metrics at regular intervals
 type SystemMetricsLog struct {
-	Timestamp           time.Time `json:"timestamp"`
-	ActiveTools         int       `json:"active_tools"`
-	QueueLength         int       `json:"queue_length"`
-	CurrentLockHolder   string    `json:"current_lock_holder,omitempty"`
-	LocksGrantedLastMin int64     `json:"locks_granted_last_min"`
-	AvgWaitTimeMs       int64     `json:"avg_wait_time_ms"`
-	AvgHoldTimeMs       int64     `json:"avg_hold_time_ms"`
-	ExpiredTickets      int64     `json:"expired_tickets_last_min"`
-	FailedRequests      int64     `json:"failed_requests_last_min"`
+	Timestamp           time.Time      `json:"timestamp"`
+	Seq                 uint64         `json:"seq,omitempty"`
+	ActiveTools         int            `json:"active_tools"`
+	QueueLength         int            `json:"queue_length"`
+	CurrentLockHolder   string         `json:"current_lock_holder,omitempty"`
+	LocksGrantedLastMin int64          `json:"locks_granted_last_min"`
+	AvgWaitTimeMs       int64          `json:"avg_wait_time_ms"`
+	AvgHoldTimeMs       int64          `json:"avg_hold_time_ms"`
+	ExpiredTickets      int64          `json:"expired_tickets_last_min"`
+	FailedRequests      int64          `json:"failed_requests_last_min"`
+	Distributions       *Distributions `json:"distributions,omitempty"`
 }
 
 // DailySummaryLog records daily statistics
 type DailySummaryLog struct {
-	Date                 string      `json:"date"` // YYYY-MM-DD
-	TotalRequests        int64       `json:"total_requests"`
-	TotalLocksGranted    int64       `json:"total_locks_granted"`
-	TotalLocksExpired    int64       `json:"total_locks_expired"`
-	TotalLocksReleased   int64       `json:"total_locks_released"`
-	TotalToolsRegistered int64       `json:"total_tools_registered"`
-	AvgWaitTimeMs        int64       `json:"avg_wait_time_ms"`
-	AvgHoldTimeMs        int64       `json:"avg_hold_time_ms"`
-	MaxQueueLength       int         `json:"max_queue_length"`
-	MaxWaitTimeMs        int64       `json:"max_wait_time_ms"`
-	MaxHoldTimeMs        int64       `json:"max_hold_time_ms"`
-	ErrorCount           int64       `json:"error_count"`
-	TopTools             []ToolUsage `json:"top_tools"`
+	Date                 string         `json:"date"` // YYYY-MM-DD
+	TotalRequests        int64          `json:"total_requests"`
+	TotalLocksGranted    int64          `json:"total_locks_granted"`
+	TotalLocksExpired    int64          `json:"total_locks_expired"`
+	TotalLocksReleased   int64          `json:"total_locks_released"`
+	TotalToolsRegistered int64          `json:"total_tools_registered"`
+	AvgWaitTimeMs        int64          `json:"avg_wait_time_ms"`
+	AvgHoldTimeMs        int64          `json:"avg_hold_time_ms"`
+	MaxQueueLength       int            `json:"max_queue_length"`
+	MaxWaitTimeMs        int64          `json:"max_wait_time_ms"`
+	MaxHoldTimeMs        int64          `json:"max_hold_time_ms"`
+	ErrorCount           int64          `json:"error_count"`
+	TopTools             []ToolUsage    `json:"top_tools"`
+	Distributions        *Distributions `json:"distributions,omitempty"`
 }
 
 // ToolUsage records usage statistics for a single tool
 type ToolUsage struct {
-	ToolID    string `json:"tool_id"`
-	LockCount int64  `json:"lock_count"`
-	AvgWaitMs int64  `json:"avg_wait_ms"`
-	AvgHoldMs int64  `json:"avg_hold_ms"`
+	ToolID        string         `json:"tool_id"`
+	LockCount     int64          `json:"lock_count"`
+	AvgWaitMs     int64          `json:"avg_wait_ms"`
+	AvgHoldMs     int64          `json:"avg_hold_ms"`
+	Distributions *Distributions `json:"distributions,omitempty"`
 }
+
+// Sequenced is a log entry that records its position in the write order
+type Sequenced interface {
+	SetSeq(seq uint64)
+}
+
+// SetSeq implements Sequenced
+func (l *RequestLog) SetSeq(seq uint64) { l.Seq = seq }
+
+// SetSeq implements Sequenced
+func (l *LockEventLog) SetSeq(seq uint64) { l.Seq = seq }
+
+// SetSeq implements Sequenced
+func (l *ToolEventLog) SetSeq(seq uint64) { l.Seq = seq }
+
+// SetSeq implements Sequenced
+func (l *SystemMetricsLog) SetSeq(seq uint64) { l.Seq = seq }
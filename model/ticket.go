@@ -21,6 +21,7 @@ type Ticket struct {
 	TicketID    string       `json:"ticket_id"`
 	ToolID      string       `json:"tool_id"`
 	ThreadID    string       `json:"thread_id"`
+	SessionID   string       `json:"-"` // Session of the tool instance that requested it
 	RequestedAt time.Time    `json:"requested_at"`
 	Status      TicketStatus `json:"status"`
 	GrantedAt   time.Time    `json:"granted_at,omitempty"`
@@ -30,12 +31,13 @@ type Ticket struct {
 }
 
 // NewTicket creates a new waiting ticket
-func NewTicket(toolID, threadID string) *Ticket {
+func NewTicket(toolID, threadID, sessionID string) *Ticket {
 	now := time.Now()
 	return &Ticket{
 		TicketID:    uuid.New().String(),
 		ToolID:      toolID,
 		ThreadID:    threadID,
+		SessionID:   sessionID,
 		RequestedAt: now,
 		Status:      TicketStatusWaiting,
 		LastPollAt:  now,
@@ -2,6 +2,8 @@ package model
 
 import (
 	"time"
+
+	"github.com/google/uuid"
 )
 
 // ToolStatus represents the status of a tool
@@ -12,23 +14,74 @@ const (
 	ToolStatusOffline ToolStatus = "offline"
 )
 
+// Why a tool went offline
+const (
+	OfflineReasonHeartbeatTimeout = "heartbeat_timeout"
+	OfflineReasonUnregistered     = "unregistered"
+)
+
 // Tool represents a registered automation tool (BAS, Go+Rod, etc.)
 type Tool struct {
 	ToolID        string     `json:"tool_id"`
 	RegisteredAt  time.Time  `json:"registered_at"`
 	LastHeartbeat time.Time  `json:"last_heartbeat"`
 	Status        ToolStatus `json:"status"`
+	SessionID     string     `json:"-"` // Issued on register, required on every call
+	OfflineAt     time.Time  `json:"-"` // When the tool last went offline
+	OfflineReason string     `json:"-"` // Why it went offline, an OfflineReason* value
+	ToolInfo
 }
 
-// NewTool creates a new Tool with online status
-func NewTool(toolID string) *Tool {
-	now := time.Now()
-	return &Tool{
-		ToolID:        toolID,
-		RegisteredAt:  now,
-		LastHeartbeat: now,
-		Status:        ToolStatusOnline,
+// ToolInfo is the metadata a tool instance reports when it registers
+type ToolInfo struct {
+	Hostname    string            `json:"hostname,omitempty"`
+	PID         int               `json:"pid,omitempty"`
+	Version     string            `json:"version,omitempty"`
+	Machine     string            `json:"machine,omitempty"`
+	Owner       string            `json:"owner,omitempty"`
+	ToolType    string            `json:"tool_type,omitempty"` // bas, rod, ...
+	ThreadCount int               `json:"thread_count,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+}
+
+// Clone returns a copy of the info that does not share the labels map
+func (i ToolInfo) Clone() ToolInfo {
+	if i.Labels != nil {
+		labels := make(map[string]string, len(i.Labels))
+		for k, v := range i.Labels {
+			labels[k] = v
+		}
+		i.Labels = labels
+	}
+	return i
+}
+
+// HasLabels reports whether every key=value in selector is set on the info
+func (i ToolInfo) HasLabels(selector map[string]string) bool {
+	for k, v := range selector {
+		if value, ok := i.Labels[k]; !ok || value != v {
+			return false
+		}
 	}
+	return true
+}
+
+// NewTool creates a new Tool with online status and a fresh session
+func NewTool(toolID string, info ToolInfo) *Tool {
+	tool := &Tool{ToolID: toolID}
+	tool.StartSession(info)
+	return tool
+}
+
+// StartSession issues a new session ID for a (re)started tool instance and
+// marks the tool online
+func (t *Tool) StartSession(info ToolInfo) {
+	now := time.Now()
+	t.SessionID = uuid.New().String()
+	t.ToolInfo = info.Clone()
+	t.RegisteredAt = now
+	t.LastHeartbeat = now
+	t.Status = ToolStatusOnline
 }
 
 // IsOnline returns true if the tool is online
@@ -42,9 +95,16 @@ func (t *Tool) UpdateHeartbeat() {
 	t.Status = ToolStatusOnline
 }
 
-// MarkOffline marks the tool as offline
-func (t *Tool) MarkOffline() {
+// MarkOffline marks the tool as offline for reason
+func (t *Tool) MarkOffline(reason string) {
 	t.Status = ToolStatusOffline
+	t.OfflineAt = time.Now()
+	t.OfflineReason = reason
+}
+
+// IsOfflineLongerThan checks if the tool has been offline for over d
+func (t *Tool) IsOfflineLongerThan(d time.Duration) bool {
+	return !t.IsOnline() && time.Since(t.OfflineAt) > d
 }
 
 // IsHeartbeatExpired checks if the heartbeat has expired
@@ -59,5 +119,13 @@ func (t *Tool) ToJSON() map[string]interface{} {
 		"registered_at":  t.RegisteredAt,
 		"last_heartbeat": t.LastHeartbeat,
 		"status":         t.Status,
+		"hostname":       t.Hostname,
+		"pid":            t.PID,
+		"version":        t.Version,
+		"machine":        t.Machine,
+		"owner":          t.Owner,
+		"tool_type":      t.ToolType,
+		"thread_count":   t.ThreadCount,
+		"labels":         t.Labels,
 	}
 }
@@ -11,8 +11,9 @@ import (
 
 // BackgroundJobs manages all background goroutines
 type BackgroundJobs struct {
-	config       *config.Config
+	config       *config.Store
 	toolRegistry *ToolRegistry
+	lifecycle    *ToolLifecycle
 	lockManager  *LockManager
 
 	stopChan chan struct{}
@@ -20,10 +21,11 @@ type BackgroundJobs struct {
 }
 
 // NewBackgroundJobs creates a new BackgroundJobs manager
-func NewBackgroundJobs(cfg *config.Config, tr *ToolRegistry, lm *LockManager) *BackgroundJobs {
+func NewBackgroundJobs(cfg *config.Store, tr *ToolRegistry, tl *ToolLifecycle, lm *LockManager) *BackgroundJobs {
 	return &BackgroundJobs{
 		config:       cfg,
 		toolRegistry: tr,
+		lifecycle:    tl,
 		lockManager:  lm,
 		stopChan:     make(chan struct{}),
 	}
@@ -49,6 +51,10 @@ func (bg *BackgroundJobs) Start() {
 	bg.wg.Add(1)
 	go bg.runGracePeriodChecker()
 
+	// Offline tool collector - every 1 minute
+	bg.wg.Add(1)
+	go bg.runOfflineToolCollector()
+
 	log.Info().Msg("Background jobs started")
 }
 
@@ -82,25 +88,17 @@ func (bg *BackgroundJobs) runHeartbeatChecker() {
 	}
 }
 
-// checkHeartbeats marks tools as offline if heartbeat expired
+// checkHeartbeats marks tools as offline if heartbeat expired and releases
+// their tickets
 func (bg *BackgroundJobs) checkHeartbeats() {
-	offlineTools := bg.toolRegistry.CheckAndMarkOffline()
-
-	if len(offlineTools) > 0 {
-		log.Warn().
-			Int("count", len(offlineTools)).
-			Strs("tools", offlineTools).
-			Msg("Tools marked offline due to heartbeat timeout")
-
-		// Remove tickets for offline tools
-		for _, toolID := range offlineTools {
-			removed := bg.lockManager.RemoveToolTickets(toolID)
-			if len(removed) > 0 {
-				log.Info().
-					Str("tool_id", toolID).
-					Int("count", len(removed)).
-					Msg("Removed tickets for offline tool")
-			}
+	released := bg.lifecycle.CheckHeartbeats()
+
+	for toolID, tickets := range released {
+		if len(tickets) > 0 {
+			log.Info().
+				Str("tool_id", toolID).
+				Int("count", len(tickets)).
+				Msg("Removed tickets for offline tool")
 		}
 	}
 }
@@ -199,3 +197,40 @@ func (bg *BackgroundJobs) checkGracePeriod() {
 			Msg("Lock expired due to grace period")
 	}
 }
+
+// runOfflineToolCollector removes long-offline tools every 1 minute
+func (bg *BackgroundJobs) runOfflineToolCollector() {
+	defer bg.wg.Done()
+
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	log.Debug().Msg("Offline tool collector started")
+
+	for {
+		select {
+		case <-bg.stopChan:
+			log.Debug().Msg("Offline tool collector stopped")
+			return
+		case <-ticker.C:
+			bg.collectOfflineTools()
+		}
+	}
+}
+
+// collectOfflineTools removes tools offline longer than offline_tool_retention
+func (bg *BackgroundJobs) collectOfflineTools() {
+	retention := bg.config.Get().OfflineToolRetention
+	if retention == 0 {
+		return
+	}
+
+	removed := bg.toolRegistry.RemoveOfflineTools(time.Duration(retention) * time.Second)
+
+	if len(removed) > 0 {
+		log.Info().
+			Int("count", len(removed)).
+			Strs("tools", removed).
+			Msg("Removed offline tools")
+	}
+}
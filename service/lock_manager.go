@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"sync"
 	"time"
@@ -20,18 +21,17 @@ var (
 
 // EventLogger interface for logging lock events
 type EventLogger interface {
-	LogLockRequested(ticketID, toolID, threadID string, queuePosition int)
-	LogLockGranted(ticketID, toolID, threadID string, waitDurationMs int64)
-	LogLockReleased(ticketID, toolID, threadID string, holdDurationMs int64)
+	LogLockRequestedWithContext(requestID, ticketID, toolID, threadID string, queuePosition, queueLength int)
+	LogLockGrantedWithContext(requestID, ticketID, toolID, threadID string, waitDurationMs int64, queueLength int)
+	LogLockReleasedWithContext(requestID, ticketID, toolID, threadID string, holdDurationMs int64, queueLength int)
 	LogLockExpired(ticketID, toolID, threadID, reason string, holdDurationMs int64)
-	LogLockExtended(ticketID, toolID, threadID string, extendCount int)
+	LogLockExtendedWithContext(requestID, ticketID, toolID, threadID string, extendCount int)
 	LogTicketExpired(ticketID, toolID, threadID, reason string)
-	LogToolRegistered(toolID string)
+	LogToolRegistered(toolID string, info model.ToolInfo)
 	LogToolHeartbeat(toolID string)
 	LogToolOffline(toolID, reason string)
 	LogToolUnregistered(toolID string)
-	IncrementRequests()
-	IncrementErrors()
+	LogToolRemoved(toolID string)
 }
 
 // LockManager manages the lock queue and current lock
@@ -41,13 +41,13 @@ type LockManager struct {
 	currentLock  *model.Ticket            // Currently granted ticket
 	tickets      map[string]*model.Ticket // Quick lookup by ticket_id
 	threadKeys   map[string]string        // Map of tool:thread -> ticket_id
-	config       *config.Config
+	config       *config.Store
 	toolRegistry *ToolRegistry
 	eventLogger  EventLogger
 }
 
 // NewLockManager creates a new LockManager
-func NewLockManager(cfg *config.Config, tr *ToolRegistry) *LockManager {
+func NewLockManager(cfg *config.Store, tr *ToolRegistry) *LockManager {
 	return &LockManager{
 		queue:        make([]*model.Ticket, 0),
 		tickets:      make(map[string]*model.Ticket),
@@ -62,24 +62,32 @@ func (lm *LockManager) SetEventLogger(el EventLogger) {
 	lm.eventLogger = el
 }
 
-// RequestLock creates a new ticket for a lock request
-func (lm *LockManager) RequestLock(toolID, threadID string) (*model.Ticket, int, error) {
+// RequestLock creates a new ticket for a lock request. The request ID carried
+// by ctx is recorded on the lock events it causes.
+func (lm *LockManager) RequestLock(ctx context.Context, toolID, threadID, sessionID string) (*model.Ticket, int, error) {
 	lm.mu.Lock()
 	defer lm.mu.Unlock()
 
-	// Check if tool is online
-	if !lm.toolRegistry.IsOnline(toolID) {
+	// Check if tool is online with this session
+	if err := lm.toolRegistry.CheckSession(toolID, sessionID); err != nil {
 		log.Debug().
 			Str("tool_id", toolID).
-			Msg("Lock request from offline tool")
-		return nil, 0, ErrToolOffline
+			Err(err).
+			Msg("Lock request rejected")
+		return nil, 0, err
 	}
+	lm.touchTool(toolID, sessionID)
 
-	// Check for existing ticket for this tool+thread
+	// Check for existing ticket for this tool+thread. A ticket left by an
+	// earlier session of the tool is replaced, not handed to this one.
 	key := toolID + ":" + threadID
 	if existingTicketID, ok := lm.threadKeys[key]; ok {
 		if ticket, exists := lm.tickets[existingTicketID]; exists {
-			if ticket.IsWaiting() || ticket.IsGranted() {
+			if ticket.SessionID != sessionID {
+				lm.removeTickets(toolID, ReasonSessionTakeover, func(t *model.Ticket) bool {
+					return t.TicketID == existingTicketID
+				})
+			} else if ticket.IsWaiting() || ticket.IsGranted() {
 				// Return existing ticket
 				position := lm.getQueuePosition(ticket.TicketID)
 				log.Debug().
@@ -88,13 +96,13 @@ func (lm *LockManager) RequestLock(toolID, threadID string) (*model.Ticket, int,
 					Str("thread_id", threadID).
 					Int("position", position).
 					Msg("Returning existing ticket")
-				return ticket, position, nil
+				return copyTicket(ticket), position, nil
 			}
 		}
 	}
 
 	// Create new ticket
-	ticket := model.NewTicket(toolID, threadID)
+	ticket := model.NewTicket(toolID, threadID, sessionID)
 	lm.tickets[ticket.TicketID] = ticket
 	lm.threadKeys[key] = ticket.TicketID
 	lm.queue = append(lm.queue, ticket)
@@ -110,30 +118,32 @@ func (lm *LockManager) RequestLock(toolID, threadID string) (*model.Ticket, int,
 
 	// Log event
 	if lm.eventLogger != nil {
-		lm.eventLogger.LogLockRequested(ticket.TicketID, toolID, threadID, position)
+		lm.eventLogger.LogLockRequestedWithContext(model.RequestIDFromContext(ctx), ticket.TicketID, toolID, threadID, position, len(lm.queue))
 	}
 
 	// Try to grant immediately if no current lock
-	lm.tryGrantNext()
+	lm.tryGrantNext(model.RequestIDFromContext(ctx))
 
 	// Recalculate position after potential grant
 	position = lm.getQueuePosition(ticket.TicketID)
 
-	return ticket, position, nil
+	return copyTicket(ticket), position, nil
 }
 
-// CheckLock checks the status of a ticket and updates poll time
-func (lm *LockManager) CheckLock(ticketID string) (*model.Ticket, int, error) {
+// CheckLock checks the status of a ticket and updates poll time. The
+// returned ticket is a copy.
+func (lm *LockManager) CheckLock(ctx context.Context, ticketID, sessionID string) (*model.Ticket, int, error) {
 	lm.mu.Lock()
 	defer lm.mu.Unlock()
 
-	ticket, ok := lm.tickets[ticketID]
-	if !ok {
-		return nil, 0, ErrTicketNotFound
+	ticket, err := lm.getSessionTicket(ticketID, sessionID)
+	if err != nil {
+		return nil, 0, err
 	}
+	lm.touchTool(ticket.ToolID, sessionID)
 
 	// Update poll time (for TTL reset if enabled)
-	if lm.config.TicketTTLOnPoll && ticket.IsWaiting() {
+	if lm.config.Get().TicketTTLOnPoll && ticket.IsWaiting() {
 		ticket.UpdatePollTime()
 	}
 
@@ -150,18 +160,19 @@ func (lm *LockManager) CheckLock(ticketID string) (*model.Ticket, int, error) {
 		Int("position", position).
 		Msg("Lock check")
 
-	return ticket, position, nil
+	return copyTicket(ticket), position, nil
 }
 
 // ReleaseLock releases the current lock
-func (lm *LockManager) ReleaseLock(ticketID string) (*model.Ticket, error) {
+func (lm *LockManager) ReleaseLock(ctx context.Context, ticketID, sessionID string) (*model.Ticket, error) {
 	lm.mu.Lock()
 	defer lm.mu.Unlock()
 
-	ticket, ok := lm.tickets[ticketID]
-	if !ok {
-		return nil, ErrTicketNotFound
+	ticket, err := lm.getSessionTicket(ticketID, sessionID)
+	if err != nil {
+		return nil, err
 	}
+	lm.touchTool(ticket.ToolID, sessionID)
 
 	if lm.currentLock == nil || lm.currentLock.TicketID != ticketID {
 		return nil, ErrNotLockHolder
@@ -183,38 +194,41 @@ func (lm *LockManager) ReleaseLock(ticketID string) (*model.Ticket, error) {
 
 	// Log event
 	if lm.eventLogger != nil {
-		lm.eventLogger.LogLockReleased(ticketID, ticket.ToolID, ticket.ThreadID, holdDuration.Milliseconds())
+		lm.eventLogger.LogLockReleasedWithContext(model.RequestIDFromContext(ctx), ticketID, ticket.ToolID, ticket.ThreadID, holdDuration.Milliseconds(), len(lm.queue))
 	}
 
 	// Try to grant next in queue
-	lm.tryGrantNext()
+	lm.tryGrantNext(model.RequestIDFromContext(ctx))
 
 	return ticket, nil
 }
 
 // ExtendLock extends the current lock duration
-func (lm *LockManager) ExtendLock(ticketID string) (*model.Ticket, error) {
+func (lm *LockManager) ExtendLock(ctx context.Context, ticketID, sessionID string) (*model.Ticket, error) {
 	lm.mu.Lock()
 	defer lm.mu.Unlock()
 
-	if !lm.config.LockExtendable {
+	cfg := lm.config.Get()
+	if !cfg.LockExtendable {
 		return nil, ErrExtendDisabled
 	}
 
-	ticket, ok := lm.tickets[ticketID]
-	if !ok {
-		return nil, ErrTicketNotFound
+	ticket, err := lm.getSessionTicket(ticketID, sessionID)
+	if err != nil {
+		return nil, err
 	}
+	lm.touchTool(ticket.ToolID, sessionID)
 
 	if lm.currentLock == nil || lm.currentLock.TicketID != ticketID {
 		return nil, ErrNotLockHolder
 	}
 
-	if ticket.ExtendCount >= lm.config.LockExtendMax {
+	settings := cfg.ForTool(ticket.ToolID)
+	if ticket.ExtendCount >= settings.LockExtendMax {
 		return nil, ErrMaxExtendReached
 	}
 
-	extendDuration := time.Duration(lm.config.LockMaxDuration) * time.Second
+	extendDuration := time.Duration(settings.LockMaxDuration) * time.Second
 	ticket.Extend(extendDuration)
 
 	log.Info().
@@ -225,10 +239,10 @@ func (lm *LockManager) ExtendLock(ticketID string) (*model.Ticket, error) {
 
 	// Log event
 	if lm.eventLogger != nil {
-		lm.eventLogger.LogLockExtended(ticketID, ticket.ToolID, ticket.ThreadID, ticket.ExtendCount)
+		lm.eventLogger.LogLockExtendedWithContext(model.RequestIDFromContext(ctx), ticketID, ticket.ToolID, ticket.ThreadID, ticket.ExtendCount)
 	}
 
-	return ticket, nil
+	return copyTicket(ticket), nil
 }
 
 // ExpireCurrentLock force expires the current lock
@@ -260,7 +274,7 @@ func (lm *LockManager) ExpireCurrentLock(reason string) *model.Ticket {
 	}
 
 	// Try to grant next
-	lm.tryGrantNext()
+	lm.tryGrantNext("")
 
 	return ticket
 }
@@ -270,13 +284,14 @@ func (lm *LockManager) ExpireWaitingTickets() []*model.Ticket {
 	lm.mu.Lock()
 	defer lm.mu.Unlock()
 
-	ttl := time.Duration(lm.config.TicketTTL) * time.Second
+	cfg := lm.config.Get()
 	expired := make([]*model.Ticket, 0)
 
 	// Create new queue without expired tickets
 	newQueue := make([]*model.Ticket, 0, len(lm.queue))
 
 	for _, ticket := range lm.queue {
+		ttl := time.Duration(cfg.ForTool(ticket.ToolID).TicketTTL) * time.Second
 		if ticket.IsTTLExpired(ttl) {
 			ticket.Expire()
 			lm.cleanupTicket(ticket)
@@ -311,7 +326,7 @@ func (lm *LockManager) CheckGracePeriod() *model.Ticket {
 		return nil
 	}
 
-	gracePeriod := time.Duration(lm.config.LockGracePeriod) * time.Second
+	gracePeriod := time.Duration(lm.config.Get().ForTool(lm.currentLock.ToolID).LockGracePeriod) * time.Second
 
 	if lm.currentLock.IsGracePeriodExpired(gracePeriod) {
 		ticket := lm.currentLock
@@ -331,7 +346,7 @@ func (lm *LockManager) CheckGracePeriod() *model.Ticket {
 			lm.eventLogger.LogLockExpired(ticket.TicketID, ticket.ToolID, ticket.ThreadID, "grace_period_expired", holdDuration.Milliseconds())
 		}
 
-		lm.tryGrantNext()
+		lm.tryGrantNext("")
 
 		return ticket
 	}
@@ -365,7 +380,7 @@ func (lm *LockManager) CheckLockExpiry() *model.Ticket {
 			lm.eventLogger.LogLockExpired(ticket.TicketID, ticket.ToolID, ticket.ThreadID, "max_duration_expired", holdDuration.Milliseconds())
 		}
 
-		lm.tryGrantNext()
+		lm.tryGrantNext("")
 
 		return ticket
 	}
@@ -373,50 +388,100 @@ func (lm *LockManager) CheckLockExpiry() *model.Ticket {
 	return nil
 }
 
-// RemoveToolTickets removes all tickets for a specific tool
-func (lm *LockManager) RemoveToolTickets(toolID string) []string {
+// CancelTicket removes a single ticket, waiting or holding the lock
+func (lm *LockManager) CancelTicket(ticketID, reason string) (*model.Ticket, error) {
 	lm.mu.Lock()
 	defer lm.mu.Unlock()
 
-	removed := make([]string, 0)
+	ticket, ok := lm.tickets[ticketID]
+	if !ok {
+		return nil, ErrTicketNotFound
+	}
 
-	// Check current lock
+	lm.removeTickets(ticket.ToolID, reason, func(t *model.Ticket) bool {
+		return t.TicketID == ticketID
+	})
+
+	return ticket, nil
+}
+
+// RemoveSessionTickets removes the tickets requested by one session of a
+// tool. The reason (tool_offline, tool_unregistered, session_takeover, ...)
+// is recorded on the lock events.
+func (lm *LockManager) RemoveSessionTickets(toolID, sessionID, reason string) []string {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+
+	return lm.removeTickets(toolID, reason, func(ticket *model.Ticket) bool {
+		return ticket.ToolID == toolID && ticket.SessionID == sessionID
+	})
+}
+
+// GetToolTickets returns status info for a tool's tickets, including the
+// queue position (0 = holding the lock)
+func (lm *LockManager) GetToolTickets(toolID string) []map[string]interface{} {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+
+	result := make([]map[string]interface{}, 0)
 	if lm.currentLock != nil && lm.currentLock.ToolID == toolID {
-		removed = append(removed, lm.currentLock.TicketID)
-		lm.cleanupTicket(lm.currentLock)
-		lm.currentLock = nil
+		info := lm.currentLock.ToJSON()
+		info["position"] = 0
+		result = append(result, info)
 	}
-
-	// Remove from queue
-	newQueue := make([]*model.Ticket, 0, len(lm.queue))
-	for _, ticket := range lm.queue {
+	for i, ticket := range lm.queue {
 		if ticket.ToolID == toolID {
-			removed = append(removed, ticket.TicketID)
-			lm.cleanupTicket(ticket)
-		} else {
-			newQueue = append(newQueue, ticket)
+			info := ticket.ToJSON()
+			info["position"] = i + 1
+			result = append(result, info)
 		}
 	}
-	lm.queue = newQueue
 
-	if len(removed) > 0 {
-		log.Info().
-			Str("tool_id", toolID).
-			Strs("tickets", removed).
-			Msg("Removed tickets for offline tool")
+	return result
+}
 
-		lm.tryGrantNext()
+// GetTicket returns a snapshot of a live ticket (the current lock or a queued
+// ticket) with its queue position, or nil if it is not live
+func (lm *LockManager) GetTicket(ticketID string) map[string]interface{} {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+
+	ticket, ok := lm.tickets[ticketID]
+	if !ok {
+		return nil
 	}
 
-	return removed
+	info := ticket.ToJSON()
+	info["position"] = lm.getQueuePosition(ticketID)
+	return info
+}
+
+// TicketsByTool returns a snapshot of the current lock and the queued
+// tickets, grouped by tool ID in queue order
+func (lm *LockManager) TicketsByTool() map[string][]model.Ticket {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+
+	result := make(map[string][]model.Ticket)
+	if lm.currentLock != nil {
+		result[lm.currentLock.ToolID] = append(result[lm.currentLock.ToolID], *lm.currentLock)
+	}
+	for _, ticket := range lm.queue {
+		result[ticket.ToolID] = append(result[ticket.ToolID], *ticket)
+	}
+
+	return result
 }
 
-// GetCurrentLock returns the current lock holder
+// GetCurrentLock returns a copy of the current lock, or nil
 func (lm *LockManager) GetCurrentLock() *model.Ticket {
 	lm.mu.Lock()
 	defer lm.mu.Unlock()
 
-	return lm.currentLock
+	if lm.currentLock == nil {
+		return nil
+	}
+	return copyTicket(lm.currentLock)
 }
 
 // GetCurrentLockHolder returns the tool_id of current lock holder
@@ -461,6 +526,7 @@ func (lm *LockManager) GetQueueStatus() map[string]interface{} {
 	for i, ticket := range lm.queue {
 		queueInfo = append(queueInfo, map[string]interface{}{
 			"position":   i + 1,
+			"ticket_id":  ticket.TicketID,
 			"tool_id":    ticket.ToolID,
 			"thread_id":  ticket.ThreadID,
 			"waiting_ms": ticket.WaitDuration().Milliseconds(),
@@ -476,13 +542,15 @@ func (lm *LockManager) EstimateWaitTime(position int) time.Duration {
 	if position <= 0 {
 		return 0
 	}
-	avgLockTime := time.Duration(lm.config.LockMaxDuration/2) * time.Second
+	avgLockTime := time.Duration(lm.config.Get().LockMaxDuration/2) * time.Second
 	return time.Duration(position) * avgLockTime
 }
 
 // Internal helper methods
 
-func (lm *LockManager) tryGrantNext() {
+// tryGrantNext grants the lock to the head of the queue if it is free.
+// requestID is the request that freed the lock, if any.
+func (lm *LockManager) tryGrantNext(requestID string) {
 	// Already has a lock
 	if lm.currentLock != nil {
 		return
@@ -498,7 +566,8 @@ func (lm *LockManager) tryGrantNext() {
 	lm.queue = lm.queue[1:]
 
 	// Grant the lock
-	lockDuration := time.Duration(lm.config.LockMaxDuration) * time.Second
+	settings := lm.config.Get().ForTool(ticket.ToolID)
+	lockDuration := time.Duration(settings.LockMaxDuration) * time.Second
 	waitDuration := ticket.WaitDuration()
 	ticket.Grant(lockDuration)
 	lm.currentLock = ticket
@@ -509,11 +578,12 @@ func (lm *LockManager) tryGrantNext() {
 		Str("thread_id", ticket.ThreadID).
 		Dur("wait_duration", waitDuration).
 		Time("expires_at", ticket.ExpiresAt).
+		Str("profile", settings.Profile).
 		Msg("Lock granted")
 
 	// Log event
 	if lm.eventLogger != nil {
-		lm.eventLogger.LogLockGranted(ticket.TicketID, ticket.ToolID, ticket.ThreadID, waitDuration.Milliseconds())
+		lm.eventLogger.LogLockGrantedWithContext(requestID, ticket.TicketID, ticket.ToolID, ticket.ThreadID, waitDuration.Milliseconds(), len(lm.queue))
 	}
 }
 
@@ -533,7 +603,86 @@ func (lm *LockManager) getQueuePosition(ticketID string) int {
 	return -1 // Not found
 }
 
+// touchTool counts lock traffic from a tool session as a heartbeat when
+// lock_refreshes_heartbeat is enabled
+func (lm *LockManager) touchTool(toolID, sessionID string) {
+	if lm.config.Get().LockRefreshesHeartbeat {
+		lm.toolRegistry.Touch(toolID, sessionID)
+	}
+}
+
+// getSessionTicket looks up a ticket and checks it belongs to sessionID
+func (lm *LockManager) getSessionTicket(ticketID, sessionID string) (*model.Ticket, error) {
+	ticket, ok := lm.tickets[ticketID]
+	if !ok {
+		return nil, ErrTicketNotFound
+	}
+	if ticket.SessionID != sessionID {
+		return nil, ErrSessionMismatch
+	}
+	return ticket, nil
+}
+
+// removeTickets expires and removes the current lock and queued tickets
+// matching match, then grants the next ticket
+func (lm *LockManager) removeTickets(toolID, reason string, match func(*model.Ticket) bool) []string {
+	removed := make([]string, 0)
+
+	// Check current lock
+	if lm.currentLock != nil && match(lm.currentLock) {
+		ticket := lm.currentLock
+		holdDuration := ticket.HoldDuration()
+		ticket.Expire()
+		removed = append(removed, ticket.TicketID)
+		lm.cleanupTicket(ticket)
+		lm.currentLock = nil
+
+		// Log event
+		if lm.eventLogger != nil {
+			lm.eventLogger.LogLockExpired(ticket.TicketID, ticket.ToolID, ticket.ThreadID, reason, holdDuration.Milliseconds())
+		}
+	}
+
+	// Remove from queue
+	newQueue := make([]*model.Ticket, 0, len(lm.queue))
+	for _, ticket := range lm.queue {
+		if match(ticket) {
+			ticket.Expire()
+			removed = append(removed, ticket.TicketID)
+			lm.cleanupTicket(ticket)
+
+			// Log event
+			if lm.eventLogger != nil {
+				lm.eventLogger.LogTicketExpired(ticket.TicketID, ticket.ToolID, ticket.ThreadID, reason)
+			}
+		} else {
+			newQueue = append(newQueue, ticket)
+		}
+	}
+	lm.queue = newQueue
+
+	if len(removed) > 0 {
+		log.Info().
+			Str("tool_id", toolID).
+			Str("reason", reason).
+			Strs("tickets", removed).
+			Msg("Removed tickets for tool")
+
+		lm.tryGrantNext("")
+	}
+
+	return removed
+}
+
 func (lm *LockManager) cleanupTicket(ticket *model.Ticket) {
 	delete(lm.tickets, ticket.TicketID)
 	delete(lm.threadKeys, ticket.Key())
 }
+
+// copyTicket copies a live ticket so it can be read after lm.mu is released;
+// the ticket itself keeps being granted, polled and extended under the lock.
+// Callers hold lm.mu.
+func copyTicket(ticket *model.Ticket) *model.Ticket {
+	copied := *ticket
+	return &copied
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"clipboard-controller/config"
+	"clipboard-controller/model"
+)
+
+// A ticket left by an earlier session of the tool is replaced by a new
+// ticket of the requesting session, not returned to it
+func TestRequestLockReplacesOtherSessionTicket(t *testing.T) {
+	store := config.NewStore(config.Default(), config.Sources{})
+	tr := NewToolRegistry(store)
+	lm := NewLockManager(store, tr)
+	ctx := context.Background()
+
+	first, _, err := tr.Register("tool_a", model.ToolInfo{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale, _, err := lm.RequestLock(ctx, "tool_a", "thread_1", first.SessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Re-register through the registry only, so the old ticket stays
+	second, _, err := tr.Register("tool_a", model.ToolInfo{}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ticket, position, err := lm.RequestLock(ctx, "tool_a", "thread_1", second.SessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ticket.TicketID == stale.TicketID {
+		t.Fatal("ticket of the earlier session returned to the new session")
+	}
+	if ticket.SessionID != second.SessionID {
+		t.Errorf("ticket session = %s, want %s", ticket.SessionID, second.SessionID)
+	}
+	if !ticket.IsGranted() || position != 0 {
+		t.Errorf("new ticket status %s at position %d, want granted at 0", ticket.Status, position)
+	}
+	if _, _, err := lm.CheckLock(ctx, stale.TicketID, first.SessionID); !errors.Is(err, ErrTicketNotFound) {
+		t.Errorf("earlier session's ticket still live: %v", err)
+	}
+
+	// The same session still gets its own ticket back
+	again, _, err := lm.RequestLock(ctx, "tool_a", "thread_1", second.SessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.TicketID != ticket.TicketID {
+		t.Errorf("repeated request created ticket %s, want %s", again.TicketID, ticket.TicketID)
+	}
+}
+
+// Unregistering releases only the tickets of the session taken offline, so
+// tickets of another instance of the tool are not cancelled with it
+func TestUnregisterReleasesSessionTickets(t *testing.T) {
+	store := config.NewStore(config.Default(), config.Sources{})
+	tr := NewToolRegistry(store)
+	lm := NewLockManager(store, tr)
+	tl := NewToolLifecycle(tr, lm)
+	ctx := context.Background()
+
+	first, _, err := tr.Register("tool_a", model.ToolInfo{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _, err := lm.RequestLock(ctx, "tool_a", "thread_1", first.SessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Re-register through the registry only, so the first session's ticket
+	// stays as another instance's would
+	second, _, err := tr.Register("tool_a", model.ToolInfo{}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	own, _, err := lm.RequestLock(ctx, "tool_a", "thread_2", second.SessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, released, err := tl.Unregister("tool_a", second.SessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(released) != 1 || released[0] != own.TicketID {
+		t.Errorf("released %v, want [%s]", released, own.TicketID)
+	}
+	if lm.GetTicket(other.TicketID) == nil {
+		t.Error("ticket of another session released")
+	}
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"clipboard-controller/model"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Reasons recorded on lock events when a tool's tickets are released
+const (
+	ReasonToolOffline      = "tool_offline"
+	ReasonToolUnregistered = "tool_unregistered"
+	ReasonSessionTakeover  = "session_takeover"
+	ReasonAdminUnregister  = "admin_unregister"
+	ReasonAdminCancel      = "admin_cancel"
+	ReasonAdminRelease     = "admin_release"
+)
+
+// ToolLifecycle coordinates the tool registry and the lock manager so that a
+// tool leaving (unregister or heartbeat timeout) also releases its tickets.
+//
+// The tool is marked offline before its tickets are removed: RequestLock
+// calls CheckSession while holding the lock manager mutex, so a request
+// racing with the removal is either rejected or its ticket is removed with
+// the rest. Only the tickets of the session taken offline are removed, so a
+// new instance registering in between keeps its own.
+type ToolLifecycle struct {
+	toolRegistry *ToolRegistry
+	lockManager  *LockManager
+}
+
+// NewToolLifecycle creates a new ToolLifecycle
+func NewToolLifecycle(tr *ToolRegistry, lm *LockManager) *ToolLifecycle {
+	return &ToolLifecycle{
+		toolRegistry: tr,
+		lockManager:  lm,
+	}
+}
+
+// Register registers the tool under a new session. When takeover replaces an
+// online session, that session's tickets are cancelled and returned.
+func (tl *ToolLifecycle) Register(toolID string, info model.ToolInfo, takeover bool) (*model.Tool, []string, error) {
+	tool, previousSession, err := tl.toolRegistry.Register(toolID, info, takeover)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	released := make([]string, 0)
+	if previousSession != "" {
+		released = tl.lockManager.RemoveSessionTickets(toolID, previousSession, ReasonSessionTakeover)
+	}
+
+	return tool, released, nil
+}
+
+// Heartbeat refreshes the tool session and returns its current tickets so
+// the client can resync its view of the queue
+func (tl *ToolLifecycle) Heartbeat(toolID, sessionID string) (*model.Tool, []map[string]interface{}, error) {
+	tool, err := tl.toolRegistry.Heartbeat(toolID, sessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return tool, tl.lockManager.GetToolTickets(toolID), nil
+}
+
+// Unregister marks the tool offline and releases the tickets of its session.
+// Returns the released ticket IDs.
+func (tl *ToolLifecycle) Unregister(toolID, sessionID string) (*model.Tool, []string, error) {
+	tool, err := tl.toolRegistry.Unregister(toolID, sessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	released := tl.lockManager.RemoveSessionTickets(toolID, tool.SessionID, ReasonToolUnregistered)
+
+	return tool, released, nil
+}
+
+// ForceUnregister marks the tool offline regardless of its session and
+// releases the tickets of the session it had (admin action)
+func (tl *ToolLifecycle) ForceUnregister(toolID string) (*model.Tool, []string, error) {
+	tool, err := tl.toolRegistry.ForceUnregister(toolID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	released := tl.lockManager.RemoveSessionTickets(toolID, tool.SessionID, ReasonAdminUnregister)
+
+	return tool, released, nil
+}
+
+// CheckHeartbeats marks tools with an expired heartbeat offline and releases
+// their tickets. Returns the released ticket IDs keyed by tool ID.
+func (tl *ToolLifecycle) CheckHeartbeats() map[string][]string {
+	offlineTools := tl.toolRegistry.CheckAndMarkOffline()
+	if len(offlineTools) == 0 {
+		return nil
+	}
+
+	toolIDs := make([]string, 0, len(offlineTools))
+	released := make(map[string][]string, len(offlineTools))
+	for toolID, sessionID := range offlineTools {
+		toolIDs = append(toolIDs, toolID)
+		released[toolID] = tl.lockManager.RemoveSessionTickets(toolID, sessionID, ReasonToolOffline)
+	}
+
+	log.Warn().
+		Int("count", len(offlineTools)).
+		Strs("tools", toolIDs).
+		Msg("Tools marked offline due to heartbeat timeout")
+
+	return released
+}
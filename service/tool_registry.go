@@ -2,6 +2,7 @@ package service
 
 import (
 	"errors"
+	"sort"
 	"sync"
 	"time"
 
@@ -15,18 +16,19 @@ var (
 	ErrToolAlreadyRegistered = errors.New("tool already registered and online")
 	ErrToolNotFound          = errors.New("tool not found")
 	ErrToolOffline           = errors.New("tool is offline")
+	ErrSessionMismatch       = errors.New("session does not match the tool's current session")
 )
 
 // ToolRegistry manages registered tools
 type ToolRegistry struct {
 	mu          sync.RWMutex
 	tools       map[string]*model.Tool
-	config      *config.Config
+	config      *config.Store
 	eventLogger EventLogger
 }
 
 // NewToolRegistry creates a new ToolRegistry
-func NewToolRegistry(cfg *config.Config) *ToolRegistry {
+func NewToolRegistry(cfg *config.Store) *ToolRegistry {
 	return &ToolRegistry{
 		tools:  make(map[string]*model.Tool),
 		config: cfg,
@@ -38,51 +40,76 @@ func (tr *ToolRegistry) SetEventLogger(el EventLogger) {
 	tr.eventLogger = el
 }
 
-// Register registers a new tool or reactivates an offline one
-func (tr *ToolRegistry) Register(toolID string) (*model.Tool, error) {
+// Register registers a new tool or reactivates an offline one, issuing a new
+// session. An online tool is only replaced when takeover is set; the previous
+// session ID is then returned so its tickets can be cancelled.
+func (tr *ToolRegistry) Register(toolID string, info model.ToolInfo, takeover bool) (*model.Tool, string, error) {
 	tr.mu.Lock()
 	defer tr.mu.Unlock()
 
 	if existing, ok := tr.tools[toolID]; ok {
+		previousSession := ""
 		if existing.IsOnline() {
-			log.Debug().
-				Str("tool_id", toolID).
-				Msg("Tool already registered and online")
-			return nil, ErrToolAlreadyRegistered
+			if !takeover {
+				log.Debug().
+					Str("tool_id", toolID).
+					Str("hostname", existing.Hostname).
+					Int("pid", existing.PID).
+					Msg("Tool already registered and online")
+				return nil, "", ErrToolAlreadyRegistered
+			}
+			previousSession = existing.SessionID
 		}
 
-		// Reactivate offline tool
-		existing.UpdateHeartbeat()
-		log.Info().
-			Str("tool_id", toolID).
-			Msg("Tool reactivated")
+		// Reactivate offline tool or take over the online session
+		oldHostname, oldPID := existing.Hostname, existing.PID
+		existing.StartSession(info)
+		if previousSession != "" {
+			log.Warn().
+				Str("tool_id", toolID).
+				Str("previous_hostname", oldHostname).
+				Int("previous_pid", oldPID).
+				Str("hostname", info.Hostname).
+				Int("pid", info.PID).
+				Msg("Tool session taken over")
+		} else {
+			log.Info().
+				Str("tool_id", toolID).
+				Str("hostname", info.Hostname).
+				Int("pid", info.PID).
+				Msg("Tool reactivated")
+		}
 
 		// Log event
 		if tr.eventLogger != nil {
-			tr.eventLogger.LogToolRegistered(toolID)
+			tr.eventLogger.LogToolRegistered(toolID, info)
 		}
 
-		return existing, nil
+		return copyTool(existing), previousSession, nil
 	}
 
 	// Create new tool
-	tool := model.NewTool(toolID)
+	tool := model.NewTool(toolID, info)
 	tr.tools[toolID] = tool
 
 	log.Info().
 		Str("tool_id", toolID).
+		Str("hostname", info.Hostname).
+		Int("pid", info.PID).
+		Str("tool_type", info.ToolType).
+		Str("version", info.Version).
 		Msg("Tool registered")
 
 	// Log event
 	if tr.eventLogger != nil {
-		tr.eventLogger.LogToolRegistered(toolID)
+		tr.eventLogger.LogToolRegistered(toolID, info)
 	}
 
-	return tool, nil
+	return copyTool(tool), "", nil
 }
 
-// Heartbeat updates the heartbeat for a tool
-func (tr *ToolRegistry) Heartbeat(toolID string) (*model.Tool, error) {
+// Heartbeat updates the heartbeat for a tool session
+func (tr *ToolRegistry) Heartbeat(toolID, sessionID string) (*model.Tool, error) {
 	tr.mu.Lock()
 	defer tr.mu.Unlock()
 
@@ -94,6 +121,21 @@ func (tr *ToolRegistry) Heartbeat(toolID string) (*model.Tool, error) {
 		return nil, ErrToolNotFound
 	}
 
+	if tool.SessionID != sessionID {
+		log.Debug().
+			Str("tool_id", toolID).
+			Msg("Heartbeat from stale session")
+		return nil, ErrSessionMismatch
+	}
+
+	// An unregistered session stays offline; the tool registers again
+	if !tool.IsOnline() && tool.OfflineReason == model.OfflineReasonUnregistered {
+		log.Debug().
+			Str("tool_id", toolID).
+			Msg("Heartbeat from unregistered session")
+		return nil, ErrToolOffline
+	}
+
 	tool.UpdateHeartbeat()
 
 	log.Debug().
@@ -106,11 +148,36 @@ func (tr *ToolRegistry) Heartbeat(toolID string) (*model.Tool, error) {
 		tr.eventLogger.LogToolHeartbeat(toolID)
 	}
 
-	return tool, nil
+	return copyTool(tool), nil
 }
 
-// Unregister removes a tool from the registry
-func (tr *ToolRegistry) Unregister(toolID string) (*model.Tool, error) {
+// Touch refreshes the heartbeat of an online tool session without logging a
+// heartbeat event. Used when lock traffic counts as a heartbeat.
+func (tr *ToolRegistry) Touch(toolID, sessionID string) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	tool, ok := tr.tools[toolID]
+	if !ok || !tool.IsOnline() || tool.SessionID != sessionID {
+		return
+	}
+
+	tool.LastHeartbeat = time.Now()
+}
+
+// Unregister marks a tool session offline
+func (tr *ToolRegistry) Unregister(toolID, sessionID string) (*model.Tool, error) {
+	return tr.unregister(toolID, func(tool *model.Tool) bool {
+		return tool.SessionID == sessionID
+	})
+}
+
+// ForceUnregister marks a tool offline whatever its session (admin action)
+func (tr *ToolRegistry) ForceUnregister(toolID string) (*model.Tool, error) {
+	return tr.unregister(toolID, func(*model.Tool) bool { return true })
+}
+
+func (tr *ToolRegistry) unregister(toolID string, allowed func(*model.Tool) bool) (*model.Tool, error) {
 	tr.mu.Lock()
 	defer tr.mu.Unlock()
 
@@ -119,7 +186,11 @@ func (tr *ToolRegistry) Unregister(toolID string) (*model.Tool, error) {
 		return nil, ErrToolNotFound
 	}
 
-	tool.MarkOffline()
+	if !allowed(tool) {
+		return nil, ErrSessionMismatch
+	}
+
+	tool.MarkOffline(model.OfflineReasonUnregistered)
 
 	log.Info().
 		Str("tool_id", toolID).
@@ -130,10 +201,27 @@ func (tr *ToolRegistry) Unregister(toolID string) (*model.Tool, error) {
 		tr.eventLogger.LogToolUnregistered(toolID)
 	}
 
-	return tool, nil
+	return copyTool(tool), nil
+}
+
+// CheckSession verifies that the tool is online and sessionID is its
+// current session
+func (tr *ToolRegistry) CheckSession(toolID, sessionID string) error {
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+
+	tool, ok := tr.tools[toolID]
+	if !ok || !tool.IsOnline() {
+		return ErrToolOffline
+	}
+	if tool.SessionID != sessionID {
+		return ErrSessionMismatch
+	}
+
+	return nil
 }
 
-// GetTool returns a tool by ID
+// GetTool returns a copy of the tool with the given ID
 func (tr *ToolRegistry) GetTool(toolID string) (*model.Tool, error) {
 	tr.mu.RLock()
 	defer tr.mu.RUnlock()
@@ -143,7 +231,7 @@ func (tr *ToolRegistry) GetTool(toolID string) (*model.Tool, error) {
 		return nil, ErrToolNotFound
 	}
 
-	return tool, nil
+	return copyTool(tool), nil
 }
 
 // IsOnline checks if a tool is online
@@ -159,19 +247,19 @@ func (tr *ToolRegistry) IsOnline(toolID string) bool {
 	return tool.IsOnline()
 }
 
-// CheckAndMarkOffline checks all tools and marks expired ones as offline
-// Returns list of tool IDs that were marked offline
-func (tr *ToolRegistry) CheckAndMarkOffline() []string {
+// CheckAndMarkOffline checks all tools and marks expired ones as offline.
+// Returns the session taken offline by tool ID.
+func (tr *ToolRegistry) CheckAndMarkOffline() map[string]string {
 	tr.mu.Lock()
 	defer tr.mu.Unlock()
 
-	timeout := time.Duration(tr.config.HeartbeatTimeout) * time.Second
-	offlineTools := make([]string, 0)
+	timeout := time.Duration(tr.config.Get().HeartbeatTimeout) * time.Second
+	offlineTools := make(map[string]string)
 
 	for toolID, tool := range tr.tools {
 		if tool.IsOnline() && tool.IsHeartbeatExpired(timeout) {
-			tool.MarkOffline()
-			offlineTools = append(offlineTools, toolID)
+			tool.MarkOffline(model.OfflineReasonHeartbeatTimeout)
+			offlineTools[toolID] = tool.SessionID
 
 			log.Warn().
 				Str("tool_id", toolID).
@@ -181,7 +269,7 @@ func (tr *ToolRegistry) CheckAndMarkOffline() []string {
 
 			// Log event
 			if tr.eventLogger != nil {
-				tr.eventLogger.LogToolOffline(toolID, "heartbeat_timeout")
+				tr.eventLogger.LogToolOffline(toolID, model.OfflineReasonHeartbeatTimeout)
 			}
 		}
 	}
@@ -204,19 +292,66 @@ func (tr *ToolRegistry) CountOnlineTools() int {
 	return count
 }
 
-// GetAllTools returns all tools (for debug/status)
+// GetAllTools returns copies of all tools (for debug/status)
 func (tr *ToolRegistry) GetAllTools() []*model.Tool {
 	tr.mu.RLock()
 	defer tr.mu.RUnlock()
 
 	result := make([]*model.Tool, 0, len(tr.tools))
 	for _, tool := range tr.tools {
-		result = append(result, tool)
+		result = append(result, copyTool(tool))
 	}
 
 	return result
 }
 
+// RemoveOfflineTools deletes tools that have been offline longer than
+// retention. Returns the removed tool IDs.
+func (tr *ToolRegistry) RemoveOfflineTools(retention time.Duration) []string {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	removed := make([]string, 0)
+	for toolID, tool := range tr.tools {
+		if tool.IsOfflineLongerThan(retention) {
+			delete(tr.tools, toolID)
+			removed = append(removed, toolID)
+
+			log.Info().
+				Str("tool_id", toolID).
+				Time("offline_at", tool.OfflineAt).
+				Msg("Offline tool removed from registry")
+
+			// Log event
+			if tr.eventLogger != nil {
+				tr.eventLogger.LogToolRemoved(toolID)
+			}
+		}
+	}
+
+	return removed
+}
+
+// ListTools returns a snapshot of the tools whose labels match selector,
+// sorted by tool ID
+func (tr *ToolRegistry) ListTools(selector map[string]string) []model.Tool {
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+
+	result := make([]model.Tool, 0, len(tr.tools))
+	for _, tool := range tr.tools {
+		if tool.HasLabels(selector) {
+			result = append(result, *copyTool(tool))
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ToolID < result[j].ToolID
+	})
+
+	return result
+}
+
 // GetHeartbeatDeadline returns the next heartbeat deadline for a tool
 func (tr *ToolRegistry) GetHeartbeatDeadline(toolID string) (time.Time, error) {
 	tr.mu.RLock()
@@ -227,6 +362,14 @@ func (tr *ToolRegistry) GetHeartbeatDeadline(toolID string) (time.Time, error) {
 		return time.Time{}, ErrToolNotFound
 	}
 
-	timeout := time.Duration(tr.config.HeartbeatTimeout) * time.Second
+	timeout := time.Duration(tr.config.Get().HeartbeatTimeout) * time.Second
 	return tool.LastHeartbeat.Add(timeout), nil
 }
+
+// snapshot copies a tool so it can be read after tr.mu is released; the
+// registered tool keeps being updated under the lock. Callers hold tr.mu.
+func copyTool(tool *model.Tool) *model.Tool {
+	copied := *tool
+	copied.ToolInfo = tool.ToolInfo.Clone()
+	return &copied
+}
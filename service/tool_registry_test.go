@@ -0,0 +1,177 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"clipboard-controller/config"
+	"clipboard-controller/model"
+)
+
+// readTool reads every field of a tool returned by the registry
+func readTool(tool *model.Tool) string {
+	return fmt.Sprint(tool.ToolID, tool.SessionID, tool.Status, tool.RegisteredAt, tool.LastHeartbeat,
+		tool.OfflineAt, tool.Hostname, tool.PID, tool.Version, tool.Labels)
+}
+
+// Run with go test -race: tools returned by the registry are read while
+// heartbeats, lock traffic and re-registrations update the registered ones.
+// Readers yield between getting a tool and reading it, as a handler does
+// before serializing it.
+func TestToolRegistryConcurrentAccess(t *testing.T) {
+	tr := NewToolRegistry(config.NewStore(config.Default(), config.Sources{}))
+	info := model.ToolInfo{Hostname: "host", Labels: map[string]string{"team": "qa"}}
+	tool, _, err := tr.Register("tool_a", info, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionID := tool.SessionID
+
+	const iterations = 500
+	var wg sync.WaitGroup
+	wg.Add(4)
+
+	go func() {
+		defer wg.Done()
+		for i := 0; i < iterations; i++ {
+			tr.Touch("tool_a", sessionID)
+			tr.Heartbeat("tool_a", sessionID)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < iterations/10; i++ {
+			tr.Register("tool_a", info, true)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < iterations; i++ {
+			tool, err := tr.GetTool("tool_a")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			runtime.Gosched()
+			readTool(tool)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < iterations; i++ {
+			tools := tr.ListTools(nil)
+			all := tr.GetAllTools()
+			runtime.Gosched()
+			for i := range tools {
+				readTool(&tools[i])
+			}
+			for _, tool := range all {
+				readTool(tool)
+			}
+		}
+	}()
+
+	wg.Wait()
+}
+
+// A copy returned by the registry does not change with the registered tool
+func TestGetToolReturnsCopy(t *testing.T) {
+	tr := NewToolRegistry(config.NewStore(config.Default(), config.Sources{}))
+	registered, _, err := tr.Register("tool_a", model.ToolInfo{Labels: map[string]string{"team": "qa"}}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tool, err := tr.GetTool("tool_a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tr.Unregister("tool_a", registered.SessionID); err != nil {
+		t.Fatal(err)
+	}
+	tool.Labels["team"] = "dev"
+
+	if tool.Status != model.ToolStatusOnline {
+		t.Errorf("copy status = %s, want %s", tool.Status, model.ToolStatusOnline)
+	}
+	current, _ := tr.GetTool("tool_a")
+	if current.Status != model.ToolStatusOffline {
+		t.Errorf("registered status = %s, want %s", current.Status, model.ToolStatusOffline)
+	}
+	if current.Labels["team"] != "qa" {
+		t.Errorf("registered labels changed through a copy: %v", current.Labels)
+	}
+}
+
+// A tool records whether it timed out or unregistered
+func TestOfflineReason(t *testing.T) {
+	cfg := config.Default()
+	cfg.HeartbeatTimeout = 1
+	tr := NewToolRegistry(config.NewStore(cfg, config.Sources{}))
+	timedOut, _, err := tr.Register("tool_a", model.ToolInfo{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	left, _, err := tr.Register("tool_b", model.ToolInfo{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tr.Unregister("tool_b", left.SessionID); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(1100 * time.Millisecond)
+	if offline := tr.CheckAndMarkOffline(); len(offline) != 1 || offline[timedOut.ToolID] != timedOut.SessionID {
+		t.Fatalf("timed out tools = %v, want tool_a with its session", offline)
+	}
+
+	for toolID, want := range map[string]string{"tool_a": model.OfflineReasonHeartbeatTimeout, "tool_b": model.OfflineReasonUnregistered} {
+		tool, err := tr.GetTool(toolID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tool.OfflineReason != want {
+			t.Errorf("%s offline reason = %q, want %q", toolID, tool.OfflineReason, want)
+		}
+	}
+}
+
+// A late heartbeat does not bring an unregistered session back online, but
+// one that timed out comes back
+func TestHeartbeatAfterUnregister(t *testing.T) {
+	cfg := config.Default()
+	cfg.HeartbeatTimeout = 1
+	tr := NewToolRegistry(config.NewStore(cfg, config.Sources{}))
+
+	left, _, err := tr.Register("tool_a", model.ToolInfo{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tr.Unregister("tool_a", left.SessionID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tr.Heartbeat("tool_a", left.SessionID); !errors.Is(err, ErrToolOffline) {
+		t.Errorf("heartbeat after unregister: %v, want %v", err, ErrToolOffline)
+	}
+	if tool, _ := tr.GetTool("tool_a"); tool.IsOnline() {
+		t.Error("unregistered tool back online")
+	}
+
+	timedOut, _, err := tr.Register("tool_b", model.ToolInfo{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(1100 * time.Millisecond)
+	tr.CheckAndMarkOffline()
+	tool, err := tr.Heartbeat("tool_b", timedOut.SessionID)
+	if err != nil {
+		t.Fatalf("heartbeat after timeout: %v", err)
+	}
+	if !tool.IsOnline() {
+		t.Error("timed out tool not back online after a heartbeat")
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"clipboard-controller/config"
+	"clipboard-controller/logger"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// runSummarize implements the "summarize" subcommand: it rebuilds the daily
+// summary of --date from the log files, prints it and, with --write, saves
+// it to the summary directory. It returns the process exit code.
+func runSummarize(args []string) int {
+	fs := flag.NewFlagSet("summarize", flag.ContinueOnError)
+	path := fs.String("config", "config.yaml", "Path to config file")
+	date := fs.String("date", time.Now().AddDate(0, 0, -1).Format("2006-01-02"), "Day to summarize (YYYY-MM-DD), default yesterday")
+	write := fs.Bool("write", false, "Write the summary to <log_dir>/summary/<date>.json")
+	flags := config.RegisterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	// Keep stdout for the summary
+	setupLogger(os.Stderr)
+	zerolog.SetGlobalLevel(zerolog.WarnLevel)
+
+	cfg, _, err := config.Load(*path, flags.Values())
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load config")
+		return 1
+	}
+
+	lfm, err := logger.NewLogFileManager(cfg.LogDir, cfg.LogRetentionDays, 1, logger.OverflowBlock)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to open log directory")
+		return 1
+	}
+	defer lfm.Close()
+
+	summary, err := lfm.ComputeDailySummary(context.Background(), *date, cfg.MetricsPercentiles)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to compute daily summary")
+		return 1
+	}
+
+	if *write {
+		if err := lfm.WriteSummary(summary); err != nil {
+			log.Error().Err(err).Msg("Failed to write daily summary")
+			return 1
+		}
+	}
+
+	data, _ := json.MarshalIndent(summary, "", "  ")
+	fmt.Println(string(data))
+	return 0
+}
@@ -0,0 +1,64 @@
+package tray
+
+import (
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// balloonScript shows a Windows balloon notification; {title} and {message}
+// are replaced with single-quoted PowerShell strings
+const balloonScript = `Add-Type -AssemblyName System.Windows.Forms
+$n = New-Object System.Windows.Forms.NotifyIcon
+$n.Icon = [System.Drawing.SystemIcons]::Warning
+$n.Visible = $true
+$n.ShowBalloonTip(10000, {title}, {message}, [System.Windows.Forms.ToolTipIcon]::Warning)
+Start-Sleep -Seconds 10
+$n.Dispose()`
+
+// Notify shows a desktop notification without blocking
+func (t *TrayApp) Notify(title, message string) {
+	var cmd *exec.Cmd
+
+	switch runtime.GOOS {
+	case "windows":
+		script := strings.NewReplacer("{title}", psQuote(title), "{message}", psQuote(message)).Replace(balloonScript)
+		cmd = exec.Command("powershell", "-NoProfile", "-NonInteractive", "-WindowStyle", "Hidden", "-Command", script)
+	case "darwin":
+		cmd = exec.Command("osascript", "-e", "display notification "+asQuote(message)+" with title "+asQuote(title))
+	default: // Linux and others
+		cmd = exec.Command("notify-send", title, message)
+	}
+
+	if err := cmd.Start(); err != nil {
+		log.Warn().Err(err).Str("title", title).Msg("Failed to show notification")
+		return
+	}
+	go cmd.Wait()
+}
+
+// UpdateAlerts shows the number of firing alerts in the menu and tooltip
+func (t *TrayApp) UpdateAlerts(firing int) {
+	if t.menuAlerts == nil {
+		return
+	}
+	if firing == 0 {
+		t.menuAlerts.SetTitle("Alerts: none")
+		t.setTooltip("")
+		return
+	}
+	t.menuAlerts.SetTitle("Alerts: " + itoa(firing) + " firing")
+	t.setTooltip(" - " + itoa(firing) + " alert(s) firing")
+}
+
+// psQuote quotes s as a PowerShell single-quoted string
+func psQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// asQuote quotes s as an AppleScript string
+func asQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
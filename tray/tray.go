@@ -13,6 +13,7 @@ type TrayApp struct {
 	onExit     func()
 	ready      chan struct{}
 	menuStatus *systray.MenuItem
+	menuAlerts *systray.MenuItem
 	menuShow   *systray.MenuItem
 	isRunning  bool
 	mu         sync.Mutex
@@ -63,15 +64,18 @@ func (t *TrayApp) onReady() {
 	// Set icon and tooltip
 	systray.SetIcon(getIcon())
 	systray.SetTitle("Clipboard Controller")
-	systray.SetTooltip("Clipboard Controller - Running on port " + itoa(t.port))
+	t.setTooltip("")
 
 	// Add menu items
 	t.menuStatus = systray.AddMenuItem("Tools: 0 | Queue: 0", "Current status")
 	t.menuStatus.Disable()
+	t.menuAlerts = systray.AddMenuItem("Alerts: none", "Firing alerts, see /alerts")
+	t.menuAlerts.Disable()
 
 	systray.AddSeparator()
 
 	t.menuShow = systray.AddMenuItem("Hide Console", "Show/hide console window")
+	menuDashboard := systray.AddMenuItem("Open Dashboard", "Open the web dashboard in a browser")
 	menuOpen := systray.AddMenuItem("Open Logs Folder", "Open logs folder")
 
 	systray.AddSeparator()
@@ -95,6 +99,8 @@ func (t *TrayApp) onReady() {
 					ShowConsole()
 					t.menuShow.SetTitle("Hide Console")
 				}
+			case <-menuDashboard.ClickedCh:
+				openURL("http://localhost:" + itoa(t.port) + "/ui")
 			case <-menuOpen.ClickedCh:
 				openLogsFolder()
 			case <-menuExit.ClickedCh:
@@ -121,6 +127,11 @@ func (t *TrayApp) Quit() {
 	systray.Quit()
 }
 
+// setTooltip sets the tooltip, with suffix after the port
+func (t *TrayApp) setTooltip(suffix string) {
+	systray.SetTooltip("Clipboard Controller - Running on port " + itoa(t.port) + suffix)
+}
+
 // SetMenuShowTitle updates the show/hide menu item title
 func (t *TrayApp) SetMenuShowTitle(title string) {
 	if t.menuShow != nil {
@@ -7,6 +7,24 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+// openURL opens url in the default browser
+func openURL(url string) {
+	var cmd *exec.Cmd
+
+	switch runtime.GOOS {
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	case "darwin":
+		cmd = exec.Command("open", url)
+	default: // Linux and others
+		cmd = exec.Command("xdg-open", url)
+	}
+
+	if err := cmd.Start(); err != nil {
+		log.Error().Err(err).Str("url", url).Msg("Failed to open browser")
+	}
+}
+
 // openLogsFolder opens the logs folder in file explorer
 func openLogsFolder() {
 	var cmd *exec.Cmd
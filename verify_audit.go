@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"clipboard-controller/config"
+	"clipboard-controller/logger"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// runVerifyAudit implements the "verify-audit" subcommand: it checks the hash
+// chain of the audit log and prints the result. It returns 0 when the chain
+// is intact, 3 when it is broken and 1 on errors.
+func runVerifyAudit(args []string) int {
+	fs := flag.NewFlagSet("verify-audit", flag.ContinueOnError)
+	path := fs.String("config", "config.yaml", "Path to config file")
+	flags := config.RegisterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	// Keep stdout for the result
+	setupLogger(os.Stderr)
+	zerolog.SetGlobalLevel(zerolog.WarnLevel)
+
+	cfg, _, err := config.Load(*path, flags.Values())
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load config")
+		return 1
+	}
+
+	lfm, err := logger.NewLogFileManager(cfg.LogDir, cfg.LogRetentionDays, 1, logger.OverflowBlock)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to open log directory")
+		return 1
+	}
+	defer lfm.Close()
+
+	result, err := lfm.VerifyAudit(context.Background())
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to verify audit log")
+		return 1
+	}
+
+	data, _ := json.MarshalIndent(result, "", "  ")
+	fmt.Println(string(data))
+	if !result.Valid {
+		return 3
+	}
+	return 0
+}
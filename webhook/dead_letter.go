@@ -0,0 +1,92 @@
+package webhook
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DeadLetter is a delivery given up on, as written to the dead-letter file
+type DeadLetter struct {
+	FailedAt   time.Time       `json:"failed_at"`
+	Webhook    string          `json:"webhook"`
+	URL        string          `json:"url"`
+	Attempts   int             `json:"attempts"`
+	LastStatus int             `json:"last_status,omitempty"`
+	Error      string          `json:"error"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+// deadLetterFile appends failed deliveries to a JSONL file
+type deadLetterFile struct {
+	path string
+	mu   sync.Mutex
+}
+
+func newDeadLetterFile(path string) (*deadLetterFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create dead-letter directory: %w", err)
+	}
+	return &deadLetterFile{path: path}, nil
+}
+
+func (f *deadLetterFile) append(entry DeadLetter) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
+
+// read returns the last limit entries, newest first, and the total count
+func (f *deadLetterFile) read(limit int) ([]DeadLetter, int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	entries := make([]DeadLetter, 0)
+	file, err := os.Open(f.path)
+	if os.IsNotExist(err) {
+		return entries, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	total := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry DeadLetter
+		if json.Unmarshal(scanner.Bytes(), &entry) != nil {
+			continue
+		}
+		total++
+		entries = append(entries, entry)
+		if len(entries) > limit {
+			entries = entries[1:]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to read dead-letter file: %w", err)
+	}
+
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, total, nil
+}
@@ -0,0 +1,98 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Delivery headers
+const (
+	HeaderID        = "X-Webhook-ID"        // Delivery ID, the same for every attempt
+	HeaderEvent     = "X-Webhook-Event"     // Event type
+	HeaderAttempt   = "X-Webhook-Attempt"   // 1 for the first attempt
+	HeaderTimestamp = "X-Webhook-Timestamp" // Unix seconds, part of the signed content
+	HeaderSignature = "X-Webhook-Signature" // sha256=<hex HMAC of "<timestamp>.<body>">
+)
+
+// TestEventType is the event type of test deliveries
+const TestEventType = "webhook_test"
+
+// Payload is the JSON body of a delivery
+type Payload struct {
+	ID        string      `json:"id"`
+	Webhook   string      `json:"webhook"`
+	EventType string      `json:"event_type"`
+	Timestamp time.Time   `json:"timestamp"`
+	Event     interface{} `json:"event"` // The lock or tool event as logged
+}
+
+// Sign returns the signature header value of body sent at timestamp
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// attemptResult is the outcome of one HTTP attempt
+type attemptResult struct {
+	status   int
+	err      error
+	duration time.Duration
+}
+
+// ok reports whether the receiver accepted the delivery
+func (r attemptResult) ok() bool {
+	return r.err == nil && r.status >= 200 && r.status < 300
+}
+
+// permanent reports whether retrying cannot help: the receiver rejected the
+// request itself (4xx other than 408 and 429)
+func (r attemptResult) permanent() bool {
+	return r.err == nil && r.status >= 400 && r.status < 500 && r.status != http.StatusRequestTimeout && r.status != http.StatusTooManyRequests
+}
+
+// describe returns the error to record for a failed attempt
+func (r attemptResult) describe() string {
+	if r.err != nil {
+		return r.err.Error()
+	}
+	return fmt.Sprintf("unexpected status %d", r.status)
+}
+
+// post sends one attempt of a delivery
+func post(ctx context.Context, client *http.Client, url, secret, id, eventType string, attempt int, body []byte) attemptResult {
+	start := time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return attemptResult{err: err}
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "clipboard-controller-webhook")
+	req.Header.Set(HeaderID, id)
+	req.Header.Set(HeaderEvent, eventType)
+	req.Header.Set(HeaderAttempt, strconv.Itoa(attempt))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	if secret != "" {
+		req.Header.Set(HeaderSignature, Sign(secret, timestamp, body))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return attemptResult{err: err, duration: time.Since(start)}
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	resp.Body.Close()
+	return attemptResult{status: resp.StatusCode, duration: time.Since(start)}
+}
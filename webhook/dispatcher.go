@@ -0,0 +1,349 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"clipboard-controller/config"
+	"clipboard-controller/model"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// Dispatcher settings
+const (
+	queueSize      = 1000
+	workerCount    = 4
+	requestTimeout = 10 * time.Second
+	baseBackoff    = time.Second
+	maxBackoff     = 5 * time.Minute
+)
+
+// DeadLetterPath returns the dead-letter file under the log directory
+func DeadLetterPath(logDir string) string {
+	return filepath.Join(logDir, "webhooks", "dead_letter.jsonl")
+}
+
+// Stats counts the deliveries of one webhook since startup
+type Stats struct {
+	Delivered     int64      `json:"delivered"`
+	FailedAttempt int64      `json:"failed_attempts"`
+	DeadLettered  int64      `json:"dead_lettered"`
+	LastStatus    int        `json:"last_status,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastAttemptAt *time.Time `json:"last_attempt_at,omitempty"`
+	LastSuccessAt *time.Time `json:"last_success_at,omitempty"`
+}
+
+// TestResult is the outcome of a test delivery
+type TestResult struct {
+	ID         string `json:"id"`
+	Delivered  bool   `json:"delivered"`
+	StatusCode int    `json:"status_code,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// delivery is one event for one webhook, across its attempts
+type delivery struct {
+	id        string
+	webhook   string
+	eventType string
+	body      []byte
+	attempt   int // Attempts made so far
+	lastState attemptResult
+}
+
+// Dispatcher delivers lock and tool events to the configured webhooks.
+// Failed attempts are retried with exponential backoff; deliveries that
+// still fail, or are pending at shutdown, go to the dead-letter file.
+type Dispatcher struct {
+	config     *config.Store
+	client     *http.Client
+	deadLetter *deadLetterFile
+	queue      chan *delivery
+
+	mu      sync.Mutex
+	stats   map[string]*Stats
+	retries map[*delivery]*time.Timer // Waiting for their next attempt
+	stopped bool
+
+	ctx    context.Context // Canceled by Stop, aborts attempts in progress
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewDispatcher creates a dispatcher writing failed deliveries to
+// deadLetterPath
+func NewDispatcher(cfg *config.Store, deadLetterPath string) (*Dispatcher, error) {
+	deadLetter, err := newDeadLetterFile(deadLetterPath)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Dispatcher{
+		config:     cfg,
+		client:     &http.Client{Timeout: requestTimeout},
+		deadLetter: deadLetter,
+		queue:      make(chan *delivery, queueSize),
+		stats:      make(map[string]*Stats),
+		retries:    make(map[*delivery]*time.Timer),
+		ctx:        ctx,
+		cancel:     cancel,
+	}, nil
+}
+
+// Start delivers the events received on lockEvents and toolEvents until Stop
+func (d *Dispatcher) Start(lockEvents <-chan model.LockEventLog, toolEvents <-chan model.ToolEventLog) {
+	for i := 0; i < workerCount; i++ {
+		d.wg.Add(1)
+		go d.worker()
+	}
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		for {
+			select {
+			case <-d.ctx.Done():
+				return
+			case event := <-lockEvents:
+				d.dispatch(event.EventType, event.Timestamp, event)
+			case event := <-toolEvents:
+				d.dispatch(event.EventType, event.Timestamp, event)
+			}
+		}
+	}()
+}
+
+// Stop aborts the attempts in progress and writes the deliveries still
+// queued or waiting for a retry to the dead-letter file
+func (d *Dispatcher) Stop() {
+	d.mu.Lock()
+	d.stopped = true
+	pending := make([]*delivery, 0, len(d.retries))
+	for dl, timer := range d.retries {
+		timer.Stop()
+		pending = append(pending, dl)
+	}
+	d.retries = make(map[*delivery]*time.Timer)
+	d.mu.Unlock()
+
+	d.cancel()
+	d.wg.Wait()
+
+	for drained := false; !drained; {
+		select {
+		case dl := <-d.queue:
+			pending = append(pending, dl)
+		default:
+			drained = true
+		}
+	}
+	for _, dl := range pending {
+		d.giveUp(dl, "server shut down before delivery")
+	}
+}
+
+// Stats returns the delivery counters by webhook name
+func (d *Dispatcher) Stats() map[string]Stats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	stats := make(map[string]Stats, len(d.stats))
+	for name, s := range d.stats {
+		stats[name] = *s
+	}
+	return stats
+}
+
+// DeadLetters returns the last limit dead-lettered deliveries, newest first,
+// and the number in the file
+func (d *Dispatcher) DeadLetters(limit int) ([]DeadLetter, int, error) {
+	return d.deadLetter.read(limit)
+}
+
+// Test sends a test event to webhook once, without retries
+func (d *Dispatcher) Test(ctx context.Context, webhook config.WebhookConfig) TestResult {
+	id := uuid.New().String()
+	body, _ := json.Marshal(Payload{
+		ID:        id,
+		Webhook:   webhook.Name,
+		EventType: TestEventType,
+		Timestamp: time.Now(),
+		Event:     map[string]string{"message": "Test delivery from clipboard-controller"},
+	})
+
+	result := post(ctx, d.client, webhook.URL, webhook.Secret, id, TestEventType, 1, body)
+	test := TestResult{ID: id, Delivered: result.ok(), StatusCode: result.status, DurationMs: result.duration.Milliseconds()}
+	if !result.ok() {
+		test.Error = result.describe()
+	}
+	return test
+}
+
+// dispatch queues event for every webhook subscribed to its type
+func (d *Dispatcher) dispatch(eventType string, ts time.Time, event interface{}) {
+	for _, webhook := range d.config.Get().Webhooks {
+		if !webhook.WantsEvent(eventType) {
+			continue
+		}
+
+		id := uuid.New().String()
+		body, err := json.Marshal(Payload{ID: id, Webhook: webhook.Name, EventType: eventType, Timestamp: ts, Event: event})
+		if err != nil {
+			continue
+		}
+		d.enqueue(&delivery{id: id, webhook: webhook.Name, eventType: eventType, body: body})
+	}
+}
+
+// enqueue queues a delivery for its next attempt; a full queue dead-letters
+// it so the event source is never blocked
+func (d *Dispatcher) enqueue(dl *delivery) {
+	select {
+	case d.queue <- dl:
+	default:
+		d.giveUp(dl, "delivery queue full")
+	}
+}
+
+func (d *Dispatcher) worker() {
+	defer d.wg.Done()
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case dl := <-d.queue:
+			d.attempt(dl)
+		}
+	}
+}
+
+// attempt makes the next attempt of a delivery and schedules a retry or
+// gives up when it fails
+func (d *Dispatcher) attempt(dl *delivery) {
+	// Use the current settings; removed and disabled webhooks get nothing
+	webhook, ok := d.config.Get().Webhook(dl.webhook)
+	if !ok || webhook.Disabled {
+		return
+	}
+
+	dl.attempt++
+	result := post(d.ctx, d.client, webhook.URL, webhook.Secret, dl.id, dl.eventType, dl.attempt, dl.body)
+	dl.lastState = result
+	d.record(dl.webhook, result)
+	if result.ok() {
+		return
+	}
+
+	if result.permanent() || dl.attempt >= webhook.Attempts() {
+		d.giveUp(dl, result.describe())
+		return
+	}
+	d.scheduleRetry(dl)
+}
+
+// scheduleRetry queues the delivery again after an exponential backoff with
+// jitter: about 1s, 2s, 4s, ... up to maxBackoff
+func (d *Dispatcher) scheduleRetry(dl *delivery) {
+	backoff := min(baseBackoff<<(dl.attempt-1), maxBackoff)
+	backoff += time.Duration(rand.Int63n(int64(backoff) / 5))
+
+	d.mu.Lock()
+	stopped := d.stopped
+	if !stopped {
+		d.retries[dl] = time.AfterFunc(backoff, func() { d.retry(dl) })
+	}
+	d.mu.Unlock()
+
+	if stopped {
+		d.giveUp(dl, "server shut down before delivery")
+	}
+}
+
+// retry queues a delivery whose backoff ended. The stopped check and the
+// send happen under d.mu, so the delivery is either queued before Stop
+// drains the queue or dead-lettered here.
+func (d *Dispatcher) retry(dl *delivery) {
+	d.mu.Lock()
+	if _, pending := d.retries[dl]; !pending {
+		// Stop took it over
+		d.mu.Unlock()
+		return
+	}
+	delete(d.retries, dl)
+
+	reason := "server shut down before delivery"
+	if !d.stopped {
+		select {
+		case d.queue <- dl:
+			d.mu.Unlock()
+			return
+		default:
+			reason = "delivery queue full"
+		}
+	}
+	d.mu.Unlock()
+
+	d.giveUp(dl, reason)
+}
+
+// giveUp writes a delivery to the dead-letter file
+func (d *Dispatcher) giveUp(dl *delivery, reason string) {
+	entry := DeadLetter{
+		FailedAt:   time.Now(),
+		Webhook:    dl.webhook,
+		Attempts:   dl.attempt,
+		LastStatus: dl.lastState.status,
+		Error:      reason,
+		Payload:    dl.body,
+	}
+	if webhook, ok := d.config.Get().Webhook(dl.webhook); ok {
+		entry.URL = webhook.URL
+	}
+
+	d.mu.Lock()
+	d.statsFor(dl.webhook).DeadLettered++
+	d.mu.Unlock()
+
+	log.Warn().Str("webhook", dl.webhook).Str("event_type", dl.eventType).Int("attempts", dl.attempt).Str("error", reason).Msg("Webhook delivery dead-lettered")
+	if err := d.deadLetter.append(entry); err != nil {
+		log.Error().Err(err).Str("webhook", dl.webhook).Msg("Failed to write webhook dead letter")
+	}
+}
+
+// record updates the stats of webhook after an attempt
+func (d *Dispatcher) record(webhook string, result attemptResult) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	stats := d.statsFor(webhook)
+	stats.LastAttemptAt = &now
+	stats.LastStatus = result.status
+	if result.ok() {
+		stats.Delivered++
+		stats.LastSuccessAt = &now
+		stats.LastError = ""
+		return
+	}
+	stats.FailedAttempt++
+	stats.LastError = result.describe()
+}
+
+// statsFor returns the stats of webhook. Callers hold d.mu.
+func (d *Dispatcher) statsFor(webhook string) *Stats {
+	stats, ok := d.stats[webhook]
+	if !ok {
+		stats = &Stats{}
+		d.stats[webhook] = stats
+	}
+	return stats
+}
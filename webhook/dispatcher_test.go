@@ -0,0 +1,249 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"clipboard-controller/config"
+	"clipboard-controller/model"
+
+	"github.com/rs/zerolog"
+)
+
+func TestMain(m *testing.M) {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	m.Run()
+}
+
+// receiver is a stand-in webhook endpoint answering with the statuses of
+// respond in turn (the last one repeats) and recording every request
+type receiver struct {
+	server  *httptest.Server
+	mu      sync.Mutex
+	respond []int
+	got     []received
+}
+
+type received struct {
+	at     time.Time
+	header http.Header
+	body   []byte
+}
+
+func newReceiver(t *testing.T, respond ...int) *receiver {
+	r := &receiver{respond: respond}
+	r.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		status := r.respond[min(len(r.got), len(r.respond)-1)]
+		r.got = append(r.got, received{at: time.Now(), header: req.Header.Clone(), body: body})
+		r.mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(r.server.Close)
+	return r
+}
+
+// requests returns the requests received so far
+func (r *receiver) requests() []received {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]received(nil), r.got...)
+}
+
+// newTestDispatcher starts a dispatcher for webhooks and returns it with its
+// lock event channel
+func newTestDispatcher(t *testing.T, webhooks ...config.WebhookConfig) (*Dispatcher, chan model.LockEventLog) {
+	cfg := config.Default()
+	cfg.Webhooks = webhooks
+	d, err := NewDispatcher(config.NewStore(cfg, config.Sources{}), filepath.Join(t.TempDir(), "dead_letter.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lockEvents := make(chan model.LockEventLog, 16)
+	d.Start(lockEvents, make(chan model.ToolEventLog))
+	t.Cleanup(d.Stop)
+	return d, lockEvents
+}
+
+// waitFor polls cond until it holds or the timeout passes
+func waitFor(t *testing.T, timeout time.Duration, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// deadLetters waits for n dead letters and returns them, newest first
+func deadLetters(t *testing.T, d *Dispatcher, n int) []DeadLetter {
+	t.Helper()
+	var entries []DeadLetter
+	waitFor(t, 5*time.Second, strconv.Itoa(n)+" dead letters", func() bool {
+		var err error
+		entries, _, err = d.DeadLetters(100)
+		return err == nil && len(entries) >= n
+	})
+	return entries
+}
+
+func granted(ticketID string) model.LockEventLog {
+	return model.LockEventLog{Timestamp: time.Now(), EventType: model.LockEventGranted, TicketID: ticketID, ToolID: "tool_a"}
+}
+
+// Deliveries carry the event and an HMAC signature of timestamp and body
+func TestDeliverySigned(t *testing.T) {
+	r := newReceiver(t, http.StatusOK)
+	d, events := newTestDispatcher(t, config.WebhookConfig{Name: "hook", URL: r.server.URL, Secret: "s3cret"})
+
+	events <- granted("ticket_1")
+	waitFor(t, 5*time.Second, "delivery", func() bool { return len(r.requests()) == 1 })
+
+	req := r.requests()[0]
+	timestamp, err := strconv.ParseInt(req.header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		t.Fatalf("timestamp header: %v", err)
+	}
+	if got, want := req.header.Get(HeaderSignature), Sign("s3cret", timestamp, req.body); got != want {
+		t.Errorf("signature = %s, want %s", got, want)
+	}
+	if req.header.Get(HeaderEvent) != model.LockEventGranted || req.header.Get(HeaderAttempt) != "1" {
+		t.Errorf("event %q attempt %q, want %s and 1", req.header.Get(HeaderEvent), req.header.Get(HeaderAttempt), model.LockEventGranted)
+	}
+
+	var payload struct {
+		Payload
+		Event model.LockEventLog `json:"event"`
+	}
+	if err := json.Unmarshal(req.body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.ID != req.header.Get(HeaderID) || payload.Webhook != "hook" || payload.Event.TicketID != "ticket_1" {
+		t.Errorf("payload = %+v", payload)
+	}
+
+	waitFor(t, time.Second, "stats", func() bool { return d.Stats()["hook"].Delivered == 1 })
+}
+
+// Failed attempts are retried with growing backoff under the same ID
+func TestDeliveryRetriesWithBackoff(t *testing.T) {
+	r := newReceiver(t, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK)
+	d, events := newTestDispatcher(t, config.WebhookConfig{Name: "hook", URL: r.server.URL})
+
+	events <- granted("ticket_1")
+	waitFor(t, 10*time.Second, "third attempt", func() bool { return len(r.requests()) == 3 })
+
+	reqs := r.requests()
+	for i, req := range reqs {
+		if req.header.Get(HeaderAttempt) != strconv.Itoa(i+1) || req.header.Get(HeaderID) != reqs[0].header.Get(HeaderID) {
+			t.Errorf("attempt %d: header attempt %s, id %s", i+1, req.header.Get(HeaderAttempt), req.header.Get(HeaderID))
+		}
+	}
+	if gap := reqs[1].at.Sub(reqs[0].at); gap < baseBackoff {
+		t.Errorf("first retry after %v, want at least %v", gap, baseBackoff)
+	}
+	if gap := reqs[2].at.Sub(reqs[1].at); gap < 2*baseBackoff {
+		t.Errorf("second retry after %v, want at least %v", gap, 2*baseBackoff)
+	}
+
+	waitFor(t, time.Second, "stats", func() bool {
+		stats := d.Stats()["hook"]
+		return stats.Delivered == 1 && stats.FailedAttempt == 2
+	})
+	if entries, _, _ := d.DeadLetters(10); len(entries) != 0 {
+		t.Errorf("delivered event dead-lettered: %+v", entries)
+	}
+}
+
+// A 4xx other than 408 and 429 is not retried; other failures are retried
+// until max_attempts. Both end in the dead-letter file.
+func TestFailedDeliveriesDeadLettered(t *testing.T) {
+	for _, tc := range []struct {
+		status   int
+		attempts int
+	}{
+		{http.StatusBadRequest, 1},
+		{http.StatusNotFound, 1},
+		{http.StatusTooManyRequests, 2},
+		{http.StatusInternalServerError, 2},
+	} {
+		t.Run(strconv.Itoa(tc.status), func(t *testing.T) {
+			t.Parallel()
+			r := newReceiver(t, tc.status)
+			d, events := newTestDispatcher(t, config.WebhookConfig{Name: "hook", URL: r.server.URL, MaxAttempts: 2})
+
+			events <- granted("ticket_1")
+			entry := deadLetters(t, d, 1)[0]
+
+			if got := len(r.requests()); got != tc.attempts {
+				t.Errorf("%d attempts, want %d", got, tc.attempts)
+			}
+			if entry.Attempts != tc.attempts || entry.LastStatus != tc.status || entry.URL != r.server.URL {
+				t.Errorf("dead letter = %+v", entry)
+			}
+			var payload Payload
+			if err := json.Unmarshal(entry.Payload, &payload); err != nil || payload.EventType != model.LockEventGranted {
+				t.Errorf("dead letter payload %s: %v", entry.Payload, err)
+			}
+		})
+	}
+}
+
+// Deliveries waiting for a retry at shutdown go to the dead-letter file
+func TestStopDeadLettersPending(t *testing.T) {
+	r := newReceiver(t, http.StatusServiceUnavailable)
+	d, events := newTestDispatcher(t, config.WebhookConfig{Name: "hook", URL: r.server.URL})
+
+	events <- granted("ticket_1")
+	events <- granted("ticket_2")
+	waitFor(t, 5*time.Second, "first attempts", func() bool { return len(r.requests()) == 2 })
+	waitFor(t, time.Second, "retries scheduled", func() bool {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		return len(d.retries) == 2
+	})
+
+	d.Stop()
+
+	entries := deadLetters(t, d, 2)
+	for _, entry := range entries {
+		if entry.Error != "server shut down before delivery" || entry.Attempts != 1 {
+			t.Errorf("dead letter = %+v", entry)
+		}
+	}
+}
+
+// Run with go test -race: retries firing while Stop runs are either queued
+// before the queue is drained or dead-lettered, never lost
+func TestRetryRacingStop(t *testing.T) {
+	r := newReceiver(t, http.StatusServiceUnavailable)
+	for i := 0; i < 20; i++ {
+		d, _ := newTestDispatcher(t, config.WebhookConfig{Name: "hook", URL: r.server.URL, MaxAttempts: 10})
+		const pending = 50
+		for j := 0; j < pending; j++ {
+			dl := &delivery{id: strconv.Itoa(j), webhook: "hook", eventType: model.LockEventGranted, body: []byte(`{}`), attempt: 1}
+			d.mu.Lock()
+			d.retries[dl] = time.AfterFunc(time.Duration(j%5)*time.Millisecond, func() { d.retry(dl) })
+			d.mu.Unlock()
+		}
+		time.Sleep(2 * time.Millisecond)
+		d.Stop()
+
+		// Stop dead-letters what it took over; retries that beat it are
+		// attempted again and dead-lettered when they fail after Stop
+		waitFor(t, 5*time.Second, "every delivery dead-lettered", func() bool {
+			_, total, err := d.DeadLetters(1)
+			return err == nil && total == pending
+		})
+	}
+}